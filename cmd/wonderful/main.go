@@ -2,19 +2,22 @@ package main
 
 import (
 	"context"
+	"crypto/rand"
 	"encoding/json"
+	"errors"
+	"expvar"
 	"flag"
 	"fmt"
 	"log/slog"
 	"net"
 	"net/http"
+	"os"
 	"os/signal"
 	"syscall"
 	"time"
 
 	"github.com/go-chi/chi/v5"
 	"github.com/go-chi/chi/v5/middleware"
-	omiddleware "github.com/oapi-codegen/nethttp-middleware"
 
 	apiv1 "wonderful/internal/api/v1"
 	openapiv1 "wonderful/internal/api/v1/openapi"
@@ -23,8 +26,8 @@ import (
 	"wonderful/internal/store"
 )
 
-func apiV1Router(root *chi.Mux, su service.UserService) error {
-	wonderfulAPI := apiv1.New(su)
+func apiV1Router(root *chi.Mux, su service.UserService, sp service.PopulateService, si service.IdempotencyService, compressMinSize int) error {
+	wonderfulAPI := apiv1.New(su, sp)
 
 	swagger, err := openapiv1.GetSwagger()
 	if err != nil {
@@ -37,19 +40,40 @@ func apiV1Router(root *chi.Mux, su service.UserService) error {
 
 	r := chi.NewRouter()
 
+	// Compress the responses, also the errors of the middlewares below.
+	compression := apiv1.Compression(compressMinSize)
+	r.Use(compression)
 	// Use our validation middleware to check all requests against the
-	// OpenAPI schema.
-	r.Use(omiddleware.OapiRequestValidator(swagger))
-	r.Use(middleware.AllowContentType("application/json"))          //nolint:goconst //ignore
+	// OpenAPI schema, the invalid ones are rejected as problem details.
+	validator, err := apiv1.RequestValidator(swagger)
+	if err != nil {
+		return fmt.Errorf("error setting up request validation: %w", err)
+	}
+	r.Use(validator)
+	// Reject the requests accepting none of the media types of their responses.
+	negotiation, err := apiv1.Negotiation(swagger)
+	if err != nil {
+		return fmt.Errorf("error setting up content negotiation: %w", err)
+	}
+	r.Use(negotiation)
+	// Retried mutating requests with an Idempotency-Key replay the first response.
+	r.Use(apiv1.Idempotency(si))
+	// the imports are uploaded as NDJSON or CSV.
+	r.Use(apiv1.AllowContentType("application/json", "application/x-ndjson", "text/csv")) //nolint:goconst //ignore
+	// JSON by default, the handlers negotiating the representation override it.
 	r.Use(middleware.SetHeader("Content-Type", "application/json")) //nolint:goconst //ignore
 
-	root.Mount("/api/v1", http.StripPrefix("/api/v1", openapiv1.HandlerFromMux(wonderfulAPI, r)))
+	handler := openapiv1.HandlerWithOptions(wonderfulAPI, openapiv1.ChiServerOptions{
+		BaseRouter:       r,
+		ErrorHandlerFunc: apiv1.SendRequestError,
+	})
+	root.Mount("/api/v1", http.StripPrefix("/api/v1", handler))
 
 	apiJSON, err := json.Marshal(swagger)
 	if err != nil {
 		return fmt.Errorf("error marshaling swagger: %w", err)
 	}
-	root.Get("/api/v1/api.json", func(w http.ResponseWriter, _ *http.Request) {
+	root.With(compression).Get("/api/v1/api.json", func(w http.ResponseWriter, _ *http.Request) {
 		w.Header().Set("Content-Type", "application/json")
 		_, err := w.Write(apiJSON)
 		if err != nil {
@@ -60,6 +84,21 @@ func apiV1Router(root *chi.Mux, su service.UserService) error {
 	return nil
 }
 
+// cursorSecret returns the key signing the pagination cursors. Without the
+// CURSOR_SECRET environment variable a random key is used, so the cursors do
+// not survive a restart and are not shared by replicas.
+func cursorSecret() ([]byte, error) {
+	if secret := os.Getenv("CURSOR_SECRET"); secret != "" {
+		return []byte(secret), nil
+	}
+	slog.Warn("CURSOR_SECRET is not set, pagination cursors will not survive a restart")
+	secret := make([]byte, 32)
+	if _, err := rand.Read(secret); err != nil {
+		return nil, fmt.Errorf("failed to generate cursor secret: %w", err)
+	}
+	return secret, nil
+}
+
 func printRoutes(_ context.Context, r chi.Router) {
 	walkFunc := func(method, route string, _ http.Handler, _ ...func(http.Handler) http.Handler) error {
 		slog.Info("registered route", "method", method, "route", route)
@@ -75,6 +114,14 @@ func main() {
 	ctx := context.Background()
 
 	port := flag.Int("port", 8888, "Port for the HTTP server")
+	populateSource := flag.String("populate-source", service.SourceRandomUser,
+		"Default source of the populated users: randomuser, file or synthetic")
+	populateFile := flag.String("populate-file", "", "User file for the file populate source (JSON or NDJSON)")
+	cacheTTL := flag.Duration("cache-ttl", 5*time.Second, "Time the pages of users are cached for, 0 to disable the cache")
+	cacheSize := flag.Int("cache-size", 1000, "Number of pages of users cached")
+	compressMinSize := flag.Int("compress-min-size", 1024, "Size in bytes from which the responses are compressed")
+	debugAddr := flag.String("debug-addr", "localhost:6060",
+		"Address of the debug server publishing the counters at /debug/vars, empty to disable it")
 	flag.Parse()
 
 	// Set up our data store
@@ -85,9 +132,43 @@ func main() {
 	defer dbServer.Close()
 
 	// we need to create a http client to fetch random users.
-	c := http.Client{Timeout: 10 * time.Second}
+	// It retries the failed attempts, each one bounded by its own timeout.
+	c := service.NewRandomUserClient(http.Client{}, service.RandomUserClientConfig{})
+	secret, err := cursorSecret()
+	if err != nil {
+		slog.Error("error setting up pagination cursors", "error", err)
+		return
+	}
 	s := store.NewPersistentStore(dbServer.Pool())
-	su := service.NewUserService(s, c)
+	var su service.UserService = service.NewUserService(s, secret)
+	// the listings are cached in front of the database, the writes purge them.
+	var onUsersChanged func(context.Context)
+	if *cacheTTL > 0 {
+		cached := service.NewCachedUserService(su, service.NewLRUCache(*cacheSize), *cacheTTL, secret)
+		expvar.Publish("user_list_cache", expvar.Func(func() any { return cached.Stats() }))
+		su, onUsersChanged = cached, cached.Invalidate
+	}
+
+	// populate jobs run in the background and are resumed on restart
+	sources := map[string]service.UserSource{
+		service.SourceRandomUser: service.NewRandomUserSource(c),
+		service.SourceSynthetic:  service.NewSyntheticSource(),
+	}
+	if *populateFile != "" {
+		sources[service.SourceFile] = service.NewFileSource(*populateFile)
+	}
+	sp, err := service.NewPopulateService(s, sources, *populateSource)
+	if err != nil {
+		slog.Error("error setting up populate jobs", "error", err)
+		return
+	}
+	if onUsersChanged != nil {
+		sp.OnUsersChanged(onUsersChanged)
+	}
+	if err := sp.Start(ctx); err != nil {
+		slog.Error("error starting populate jobs", "error", err)
+		return
+	}
 
 	// Set up the root router
 	root := chi.NewRouter()
@@ -96,22 +177,44 @@ func main() {
 	root.Use(middleware.StripSlashes)
 
 	// Set up API v1
-	if err := apiV1Router(root, su); err != nil {
+	if err := apiV1Router(root, su, sp, service.NewIdempotencyService(s), *compressMinSize); err != nil {
 		slog.Error("error setting up api v1 router", "error", err)
 		return
 	}
 
+	// the counters, e.g. the hits and misses of the cache, to read during the
+	// load tests, are served apart from the API as they expose the process.
+	if *debugAddr != "" {
+		go serveDebug(*debugAddr)
+	}
+
 	// Print out the routes if we're in debug mode
 	printRoutes(ctx, root)
 
 	// Start the server
-	if err := serve(ctx, root, *port); err != nil {
+	if err := serve(ctx, root, *port, sp.Shutdown); err != nil {
 		slog.Error("error serving http", "error", err)
 		return
 	}
 }
 
-func serve(ctx context.Context, handler http.Handler, port int) error {
+// serveDebug serves the expvar counters at /debug/vars on addr, a loopback
+// address by default, so that they are not reachable with the API.
+func serveDebug(addr string) {
+	mux := http.NewServeMux()
+	mux.Handle("/debug/vars", expvar.Handler())
+	srv := &http.Server{Handler: mux, Addr: addr, ReadHeaderTimeout: 10 * time.Second}
+	slog.Info("serving debug counters", "addr", addr)
+	if err := srv.ListenAndServe(); err != nil {
+		slog.Error("error serving debug counters", "error", err)
+	}
+}
+
+// serve runs the server until a termination signal is received, then shuts
+// it down gracefully and runs the onShutdown hooks, with a timeout of their
+// own. The hooks always run, even when the server fails to drain its
+// connections in time.
+func serve(ctx context.Context, handler http.Handler, port int, onShutdown ...func(context.Context) error) error {
 	srv := &http.Server{
 		Handler:     handler,
 		Addr:        fmt.Sprintf(":%d", port),
@@ -141,9 +244,26 @@ func serve(ctx context.Context, handler http.Handler, port int) error {
 	}
 
 	slog.Info("shutting down...")
-	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	// ctx is already cancelled by the signal, the timeouts must not inherit it.
+	base := context.WithoutCancel(ctx)
+	shutdownCtx, cancel := context.WithTimeout(base, 10*time.Second)
 	defer cancel()
-	if err := srv.Shutdown(ctx); err != nil {
+	var errs []error
+	if err := srv.Shutdown(shutdownCtx); err != nil {
+		// the connections still open, e.g. long exports or imports, are cut
+		// short, the hooks must run anyway to checkpoint the populate jobs.
+		slog.Error("failed to drain the connections", "error", err)
+		errs = append(errs, fmt.Errorf("failed to shutdown the server: %w", err))
+	}
+	// the hooks have their own deadline, the server may have used up its own.
+	hooksCtx, cancelHooks := context.WithTimeout(base, 10*time.Second)
+	defer cancelHooks()
+	for _, f := range onShutdown {
+		if err := f(hooksCtx); err != nil {
+			errs = append(errs, fmt.Errorf("failed to run a shutdown hook: %w", err))
+		}
+	}
+	if err := errors.Join(errs...); err != nil {
 		return fmt.Errorf("failed to shutdown gracefully: %w", err)
 	}
 	slog.Info("server shutdown gracefully")
@@ -0,0 +1,67 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/stretchr/testify/require"
+
+	openapiv1 "wonderful/internal/api/v1/openapi"
+	"wonderful/internal/entities"
+	"wonderful/internal/service"
+)
+
+// pagedUserService lists a page of a single user with a next page, the other
+// methods are not called.
+type pagedUserService struct {
+	service.UserService
+}
+
+func (pagedUserService) ListUsers(_ context.Context, _ service.ListParams) (*entities.UserPage, error) {
+	return &entities.UserPage{Users: []entities.User{{ID: "1", Name: "Mr. John Doe"}}, HasMore: true, NextCursor: "next"}, nil
+}
+
+func TestAPIV1RouterLinks(t *testing.T) {
+	root := chi.NewRouter()
+	root.Use(middleware.StripSlashes)
+	require.NoError(t, apiV1Router(root, pagedUserService{}, nil, nil, 1024))
+	server := httptest.NewServer(root)
+	defer server.Close()
+
+	get := func(path, accept string) *http.Response {
+		req, err := http.NewRequestWithContext(context.Background(), http.MethodGet, server.URL+path, http.NoBody)
+		require.NoError(t, err)
+		req.Header.Set("Accept", accept)
+		res, err := server.Client().Do(req)
+		require.NoError(t, err)
+		t.Cleanup(func() { res.Body.Close() })
+		return res
+	}
+
+	// the links keep the prefix the API is mounted at
+	res := get("/api/v1/wonderfuls?limit=1", "application/json")
+	require.Equal(t, http.StatusOK, res.StatusCode)
+	require.Equal(t, `</api/v1/wonderfuls?limit=1>; rel="first", </api/v1/wonderfuls?limit=1&starting_after=next>; rel="next"`,
+		res.Header.Get("Link"))
+
+	res = get("/api/v1/wonderfuls?limit=1", "application/vnd.api+json")
+	require.Equal(t, http.StatusOK, res.StatusCode)
+	var doc openapiv1.UserListDocument
+	require.NoError(t, json.NewDecoder(res.Body).Decode(&doc))
+	require.Equal(t, "/api/v1/wonderfuls?limit=1", doc.Links.Self)
+	require.Equal(t, "/api/v1/wonderfuls?limit=1&starting_after=next", *doc.Links.Next)
+	require.Len(t, doc.Data, 1)
+	require.Equal(t, "/api/v1/wonderfuls/1", doc.Data[0].Links.Self)
+
+	// and so does the instance of the problems
+	res = get("/api/v1/wonderfuls?limit=0", "application/json")
+	require.Equal(t, http.StatusBadRequest, res.StatusCode)
+	var problem openapiv1.Problem
+	require.NoError(t, json.NewDecoder(res.Body).Decode(&problem))
+	require.Equal(t, "/api/v1/wonderfuls?limit=0", problem.Instance)
+}
@@ -13,7 +13,7 @@ import (
 
 const (
 	contentTypeKey   = "Content-Type"
-	contentTypeValue = "application/vnd.api+json"
+	contentTypeValue = "application/json"
 )
 
 func parseResponse(req *http.Request, response interface{}) (int, error) {
@@ -69,7 +69,7 @@ func GetWithHeaders(
 }
 
 // Post executes an HTTP POST request to the given URL with given body.
-// The content type is set to application/vnd.api+json. The response body is
+// The content type is set to application/json. The response body is
 // assumed to contain JSON and this is unmarshaled into the provided response
 // object. The HTTP response status code is also returned.
 func Post(ctx context.Context, theURL, body string, response interface{}) (statusCode int, err error) {
@@ -103,6 +103,21 @@ func PostWithHeaders(
 	return parseResponse(req, response)
 }
 
+// Put executes an HTTP PUT request to the given URL with given body.
+func Put(ctx context.Context, theURL, body string, headers map[string]string, response interface{}) (statusCode int, err error) {
+	req, err := http.NewRequestWithContext(
+		ctx, http.MethodPut, theURL, strings.NewReader(body),
+	)
+	if err != nil {
+		return 0, fmt.Errorf("failed to PUT request: %w", err)
+	}
+	req.Header.Set(contentTypeKey, contentTypeValue)
+	for k, v := range headers {
+		req.Header.Set(k, v)
+	}
+	return parseResponse(req, response)
+}
+
 // Patch executes an HTTP PATCH request to the given URL with given body.
 func Patch(ctx context.Context, theURL, body string, headers map[string]string, response interface{}) (statusCode int, err error) {
 	req, err := http.NewRequestWithContext(
@@ -4,7 +4,10 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
+	"io"
+	"log/slog"
 	"net/http"
+	"time"
 
 	"wonderful/internal/api/v1/openapi"
 	"wonderful/internal/service"
@@ -12,96 +15,383 @@ import (
 
 // wonderfulAPI is the implementation of the API.
 type wonderfulAPI struct {
-	userService service.UserService
+	userService     service.UserService
+	populateService service.PopulateService
 }
 
 // New returns a new wonderfulAPI.
-func New(userService service.UserService) *wonderfulAPI {
+func New(userService service.UserService, populateService service.PopulateService) *wonderfulAPI {
 	return &wonderfulAPI{
-		userService: userService,
+		userService:     userService,
+		populateService: populateService,
 	}
 }
 
-// validateQueryParams validates the query parameters.
-// TODO: check why OpenAPI Validator is not working for min/max boundaries.
+// validateQueryParams validates the query parameters the spec cannot
+// describe, returning the errors of all the invalid ones. Their types and
+// bounds are checked by RequestValidator.
 func validateQueryParams(params openapi.GetWonderfulsParams) error {
-	if params.Limit != nil && (*params.Limit < 1 || *params.Limit > 100) {
-		return fmt.Errorf("invalid limit: limit must be between 1 and 100")
-	}
+	var errs []error
 	if params.StartingAfter != nil && params.EndingBefore != nil {
-		return fmt.Errorf("invalid startingAfter and endingBefore: only one of them can be used")
+		errs = append(errs, invalidParam("ending_before", "only one of starting_after and ending_before can be used"))
+	}
+	if err := validateRegistrationRange(params.RegisteredAfter, params.RegisteredBefore); err != nil {
+		errs = append(errs, err)
+	}
+	if params.Sort != nil {
+		filters := fromOpenAPIListFilters(&params)
+		if err := service.CheckSortFilters(*params.Sort, &filters); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	if params.Fields != nil {
+		if _, err := service.ParseFields(*params.Fields); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// validateRegistrationRange validates the registered_after and registered_before filters.
+func validateRegistrationRange(registeredAfter, registeredBefore *time.Time) error {
+	if registeredAfter != nil && registeredBefore != nil && !registeredAfter.Before(*registeredBefore) {
+		return invalidParam("registered_before", "registered_before must be after registered_after")
 	}
 	return nil
 }
 
+// invalidParam returns the error of an invalid query parameter, which reads
+// "invalid <param>: <reason>".
+func invalidParam(param, reason string) error {
+	return &service.FieldError{Err: fmt.Errorf("invalid %s", param), Field: param, Reason: reason}
+}
+
 // GetWonderfuls returns a list of wonderfuls.
 func (c *wonderfulAPI) GetWonderfuls(w http.ResponseWriter, r *http.Request, params openapi.GetWonderfulsParams) {
 	ctx := r.Context()
 
 	if err := validateQueryParams(params); err != nil {
-		sendAPIError(ctx, w, http.StatusBadRequest, err.Error(), err)
+		sendAPIError(w, r, problemInvalidParameter, err.Error(), err)
+		return
+	}
+
+	p, err := service.ConvertParams(params.Limit, params.StartingAfter, params.EndingBefore, params.Sort, params.Fields,
+		params.IncludeTotal, fromOpenAPIListFilters(&params))
+	if err != nil {
+		sendAPIError(w, r, problemInvalidParameter, "Invalid parameters", err)
+		return
+	}
+
+	page, err := c.userService.ListUsers(ctx, *p)
+	if err != nil {
+		if errors.Is(err, service.ErrInvalidCursor) {
+			sendAPIError(w, r, problemInvalidCursor, "Invalid cursor", err)
+			return
+		}
+		sendAPIError(w, r, problemInternal, "Error listing users", err)
+		return
+	}
+
+	w.Header().Set("Link", pageLinks(r, page.NextCursor, page.PrevCursor))
+	mediaType := userMediaType(w, r)
+	if notModified(w, r, listETag(page, mediaType, p.Fields)) {
+		return
+	}
+	if err := writeUserList(w, r, mediaType, page, p.Fields); err != nil {
+		sendAPIError(w, r, problemInternal, "Error listing users", err)
+	}
+}
+
+// ExportWonderfuls streams the wonderfuls matching the filters as NDJSON or CSV.
+// The client going away cancels the request context, which stops the export.
+func (c *wonderfulAPI) ExportWonderfuls(w http.ResponseWriter, r *http.Request, params openapi.ExportWonderfulsParams) {
+	ctx := r.Context()
+
+	if err := validateRegistrationRange(params.RegisteredAfter, params.RegisteredBefore); err != nil {
+		sendAPIError(w, r, problemInvalidParameter, err.Error(), err)
+		return
+	}
+
+	ew := newExportWriter(w, exportFormat(r, params.Format))
+	err := c.userService.ExportUsers(ctx, fromOpenAPIExportFilters(&params), ew.write)
+	if err == nil {
+		err = ew.close()
+	}
+	switch {
+	case err == nil:
+	case ctx.Err() != nil:
+		slog.Info("Export interrupted by the client", "error", err, "users", ew.rows)
+	case !ew.started:
+		sendAPIError(w, r, problemInternal, "Error exporting users", err)
+	default:
+		// the response has started, it can only be cut short.
+		slog.Error("Error exporting users", "error", err, "users", ew.rows)
+	}
+}
+
+// ImportWonderfuls creates the wonderfuls of an NDJSON or CSV body, reporting its invalid lines.
+func (c *wonderfulAPI) ImportWonderfuls(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	if err := http.NewResponseController(w).SetReadDeadline(time.Now().Add(importReadTimeout)); err != nil {
+		slog.Warn("failed to extend the read deadline of an import", "error", err)
+	}
+	next, err := newImportReader(r.Header.Get("Content-Type"), r.Body)
+	if err != nil {
+		if errors.Is(err, errUnsupportedImport) {
+			sendAPIError(w, r, problemUnsupportedMediaType, err.Error(), err)
+			return
+		}
+		sendAPIError(w, r, problemInvalidBody, "Invalid request body", err)
+		return
+	}
+
+	report, err := c.userService.ImportUsers(ctx, next)
+	if err != nil {
+		if errors.Is(err, errInvalidImport) {
+			sendAPIError(w, r, problemInvalidBody, "Invalid request body", err)
+			return
+		}
+		sendAPIError(w, r, problemInternal, "Error importing users", err)
+		return
+	}
+
+	json.NewEncoder(w).Encode(toOpenAPIImportReport(report)) //nolint:errcheck //ignore error
+}
+
+// SearchWonderfuls searches the wonderfuls by name, email and location.
+func (c *wonderfulAPI) SearchWonderfuls(w http.ResponseWriter, r *http.Request, params openapi.SearchWonderfulsParams) {
+	ctx := r.Context()
+
+	page, err := c.userService.SearchUsers(ctx, fromOpenAPISearchParams(&params))
+	if err != nil {
+		if errors.Is(err, service.ErrInvalidCursor) {
+			sendAPIError(w, r, problemInvalidCursor, "Invalid cursor", err)
+			return
+		}
+		sendAPIError(w, r, problemInternal, "Error searching users", err)
+		return
+	}
+
+	w.Header().Set("Link", pageLinks(r, page.NextCursor, ""))
+	json.NewEncoder(w).Encode(toOpenAPISearchResults(page)) //nolint:errcheck //ignore error
+}
+
+// GetWonderful returns a single wonderful by ID.
+func (c *wonderfulAPI) GetWonderful(w http.ResponseWriter, r *http.Request, id string) {
+	ctx := r.Context()
+
+	user, err := c.userService.GetUser(ctx, id)
+	if err != nil {
+		if errors.Is(err, service.ErrUserNotFound) {
+			sendAPIError(w, r, problemUserNotFound, "User not found", err)
+			return
+		}
+		sendAPIError(w, r, problemInternal, "Error getting user", err)
+		return
+	}
+
+	mediaType := userMediaType(w, r)
+	if notModified(w, r, userETag(user, mediaType)) {
+		return
+	}
+	if err := writeUser(w, r, mediaType, http.StatusOK, user); err != nil {
+		sendAPIError(w, r, problemInternal, "Error getting user", err)
+	}
+}
+
+// PostWonderfuls creates a single wonderful.
+func (c *wonderfulAPI) PostWonderfuls(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	var body openapi.PostWonderfulsJSONRequestBody
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		sendAPIError(w, r, problemInvalidBody, "Invalid request body", err)
+		return
+	}
+
+	user, err := c.userService.CreateUser(ctx, fromOpenAPIUserInput(&body))
+	if err != nil {
+		sendAPIError(w, r, problemInternal, "Error creating user", err)
 		return
 	}
 
-	p, err := service.ConvertParams(params.Limit, params.StartingAfter, params.EndingBefore, params.Email)
+	if err := writeUser(w, r, userMediaType(w, r), http.StatusCreated, user); err != nil {
+		sendAPIError(w, r, problemInternal, "Error creating user", err)
+	}
+}
+
+// PutWonderful replaces a wonderful.
+func (c *wonderfulAPI) PutWonderful(w http.ResponseWriter, r *http.Request, id string) {
+	ctx := r.Context()
+
+	var body openapi.PutWonderfulJSONRequestBody
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		sendAPIError(w, r, problemInvalidBody, "Invalid request body", err)
+		return
+	}
+
+	mediaType := userMediaType(w, r)
+	user, err := c.userService.ReplaceUser(ctx, id, fromOpenAPIUserInput(&body), ifMatch(r, mediaType))
 	if err != nil {
-		sendAPIError(ctx, w, http.StatusBadRequest, "Invalid parameters", err)
+		if errors.Is(err, service.ErrUserNotFound) {
+			sendAPIError(w, r, problemUserNotFound, "User not found", err)
+			return
+		}
+		if errors.Is(err, service.ErrPreconditionFailed) {
+			sendAPIError(w, r, problemPreconditionFailed, "User was modified, get it again", err)
+			return
+		}
+		sendAPIError(w, r, problemInternal, "Error updating user", err)
+		return
+	}
+
+	if err := writeUser(w, r, mediaType, http.StatusOK, user); err != nil {
+		sendAPIError(w, r, problemInternal, "Error updating user", err)
+	}
+}
+
+// PatchWonderful updates some fields of a wonderful.
+func (c *wonderfulAPI) PatchWonderful(w http.ResponseWriter, r *http.Request, id string) {
+	ctx := r.Context()
+
+	var body openapi.PatchWonderfulJSONRequestBody
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		sendAPIError(w, r, problemInvalidBody, "Invalid request body", err)
 		return
 	}
 
-	users, err := c.userService.ListUsers(ctx, *p)
+	mediaType := userMediaType(w, r)
+	user, err := c.userService.PatchUser(ctx, id, fromOpenAPIUserPatch(&body), ifMatch(r, mediaType))
 	if err != nil {
-		sendAPIError(ctx, w, http.StatusInternalServerError, "Error listing users", err)
-		return
-	}
-
-	openapiUsers := make([]openapi.User, 0, len(users))
-	for _, user := range users {
-		picLarge := user.Picture["large"]
-		picMedium := user.Picture["medium"]
-		picThumbnail := user.Picture["thumbnail"]
-		cellPhone := user.Cell
-		mainPhone := user.Phone
-		openapiUsers = append(openapiUsers, openapi.User{
-			Email: user.Email,
-			Id:    user.ID,
-			Name:  user.Name,
-			Phone: &struct {
-				Cell *string "json:\"cell,omitempty\""
-				Main *string "json:\"main,omitempty\""
-			}{
-				Cell: &cellPhone,
-				Main: &mainPhone,
-			},
-			Picture: &struct {
-				Large     *string "json:\"large,omitempty\""
-				Medium    *string "json:\"medium,omitempty\""
-				Thumbnail *string "json:\"thumbnail,omitempty\""
-			}{
-				Large:     &picLarge,
-				Medium:    &picMedium,
-				Thumbnail: &picThumbnail,
-			},
-			RegistrationDate: user.Registration,
-		})
-	}
-	json.NewEncoder(w).Encode(openapiUsers) //nolint:errcheck //ignore error
-}
-
-// PostPopulate populates the database with users.
+		if errors.Is(err, service.ErrUserNotFound) {
+			sendAPIError(w, r, problemUserNotFound, "User not found", err)
+			return
+		}
+		if errors.Is(err, service.ErrPreconditionFailed) {
+			sendAPIError(w, r, problemPreconditionFailed, "User was modified, get it again", err)
+			return
+		}
+		sendAPIError(w, r, problemInternal, "Error updating user", err)
+		return
+	}
+
+	if err := writeUser(w, r, mediaType, http.StatusOK, user); err != nil {
+		sendAPIError(w, r, problemInternal, "Error updating user", err)
+	}
+}
+
+// DeleteWonderful deletes a wonderful.
+func (c *wonderfulAPI) DeleteWonderful(w http.ResponseWriter, r *http.Request, id string) {
+	ctx := r.Context()
+
+	// the If-Match tag is the one of the representation a GET would return.
+	if err := c.userService.DeleteUser(ctx, id, ifMatch(r, userMediaType(w, r))); err != nil {
+		if errors.Is(err, service.ErrUserNotFound) {
+			sendAPIError(w, r, problemUserNotFound, "User not found", err)
+			return
+		}
+		if errors.Is(err, service.ErrPreconditionFailed) {
+			sendAPIError(w, r, problemPreconditionFailed, "User was modified, get it again", err)
+			return
+		}
+		sendAPIError(w, r, problemInternal, "Error deleting user", err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// PostWonderfulRestore restores a soft-deleted wonderful.
+func (c *wonderfulAPI) PostWonderfulRestore(w http.ResponseWriter, r *http.Request, id string) {
+	ctx := r.Context()
+
+	user, err := c.userService.RestoreUser(ctx, id)
+	if err != nil {
+		if errors.Is(err, service.ErrUserNotFound) {
+			sendAPIError(w, r, problemUserNotFound, "User not found", err)
+			return
+		}
+		if errors.Is(err, service.ErrUserNotDeleted) {
+			sendAPIError(w, r, problemUserNotDeleted, "User is not deleted", err)
+			return
+		}
+		sendAPIError(w, r, problemInternal, "Error restoring user", err)
+		return
+	}
+
+	if err := writeUser(w, r, userMediaType(w, r), http.StatusOK, user); err != nil {
+		sendAPIError(w, r, problemInternal, "Error restoring user", err)
+	}
+}
+
+// PostAdminPurge permanently removes the wonderfuls soft-deleted before the given cutoff.
+func (c *wonderfulAPI) PostAdminPurge(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	var body openapi.PostAdminPurgeJSONRequestBody
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		sendAPIError(w, r, problemInvalidBody, "Invalid request body", err)
+		return
+	}
+
+	n, err := c.userService.PurgeUsers(ctx, body.DeletedBefore.UTC())
+	if err != nil {
+		sendAPIError(w, r, problemInternal, "Error purging users", err)
+		return
+	}
+
+	json.NewEncoder(w).Encode(openapi.PurgeResult{Purged: n}) //nolint:errcheck //ignore error
+}
+
+// PostPopulate queues a job to populate the database with users.
 func (c *wonderfulAPI) PostPopulate(w http.ResponseWriter, r *http.Request) {
 	ctx := r.Context()
 
-	err := c.userService.Create(ctx)
+	// the body is optional, an empty one means the default options.
+	var body openapi.PostPopulateJSONRequestBody
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil && !errors.Is(err, io.EOF) {
+		sendAPIError(w, r, problemInvalidBody, "Invalid request body", err)
+		return
+	}
+
+	job, err := c.populateService.Enqueue(ctx, fromOpenAPIPopulateRequest(&body))
+	if err != nil {
+		if errors.Is(err, service.ErrInvalidPopulateOptions) {
+			sendAPIError(w, r, problemInvalidParameter, err.Error(), err)
+			return
+		}
+		if errors.Is(err, service.ErrShuttingDown) {
+			sendAPIError(w, r, problemShuttingDown, "Server is shutting down", err)
+			return
+		}
+		// the other RandomUser API failures happen in the job, see its error.
+		if errors.Is(err, service.ErrRandomUserCircuitOpen) {
+			sendAPIError(w, r, problemUpstreamUnavailable, "Random user API is unavailable, try again later", err)
+			return
+		}
+		sendAPIError(w, r, problemInternal, "Error queueing populate job", err)
+		return
+	}
+
+	w.WriteHeader(http.StatusAccepted)
+	json.NewEncoder(w).Encode(toOpenAPIJob(job)) //nolint:errcheck //ignore error
+}
+
+// GetPopulateJob returns the status of a populate job.
+func (c *wonderfulAPI) GetPopulateJob(w http.ResponseWriter, r *http.Request, id string) {
+	ctx := r.Context()
+
+	job, err := c.populateService.GetJob(ctx, id)
 	if err != nil {
-		// let's check if the error is from the RandomUser API
-		if errors.Is(err, service.ErrRandomUserAPI) {
-			sendAPIError(ctx, w, http.StatusInternalServerError, "Error getting data from RandomUser API", err)
+		if errors.Is(err, service.ErrJobNotFound) {
+			sendAPIError(w, r, problemJobNotFound, "Job not found", err)
 			return
 		}
-		sendAPIError(ctx, w, http.StatusInternalServerError, "Error populating users", err)
+		sendAPIError(w, r, problemInternal, "Error getting job", err)
 		return
 	}
 
-	w.WriteHeader(http.StatusCreated)
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(toOpenAPIJob(job)) //nolint:errcheck //ignore error
 }
@@ -1,10 +1,17 @@
 package v1_test
 
 import (
+	"bytes"
 	"context"
+	"encoding/csv"
+	"encoding/json"
+	"io"
 	"net/http"
 	"net/http/httptest"
+	"net/url"
+	"strings"
 	"testing"
+	"time"
 
 	"wonderful/internal/api/testhelpers"
 	api "wonderful/internal/api/v1"
@@ -16,10 +23,11 @@ import (
 
 	"github.com/go-chi/chi/v5"
 	_ "github.com/golang-migrate/migrate/v4/source/file"
-	middleware "github.com/oapi-codegen/nethttp-middleware"
+	"github.com/segmentio/ksuid"
 	"github.com/stretchr/testify/require"
 	"github.com/stretchr/testify/suite"
 	testcontainers "github.com/testcontainers/testcontainers-go/modules/postgres"
+	"github.com/vmihailenco/msgpack/v5"
 )
 
 type APITestIntegrationSuite struct {
@@ -27,6 +35,8 @@ type APITestIntegrationSuite struct {
 	container *testcontainers.PostgresContainer
 	s         *db.Storage
 	server    *httptest.Server
+	// stopJobs shuts down the populate service
+	stopJobs func(context.Context) error
 }
 
 // In order for 'go test' to run this suite, we need to create
@@ -44,116 +54,932 @@ func (ts *APITestIntegrationSuite) SetupSuite() {
 	require.NoError(ts.T(), err)
 
 	s := store.NewPersistentStore(ts.s.Pool())
-	c := http.Client{}
-	su := service.NewUserService(s, c)
+	c := service.NewRandomUserClient(http.Client{}, service.RandomUserClientConfig{})
+	su := service.NewUserService(s, []byte("test"))
+	// populate without network access, RandomUser is only used when requested.
+	sources := map[string]service.UserSource{
+		service.SourceRandomUser: service.NewRandomUserSource(c),
+		service.SourceSynthetic:  service.NewSyntheticSource(),
+	}
+	sp, err := service.NewPopulateService(s, sources, service.SourceSynthetic)
+	require.NoError(ts.T(), err)
+	require.NoError(ts.T(), sp.Start(ctx))
+	ts.stopJobs = sp.Shutdown
 
 	// set up our API
-	wonderfulAPI := api.New(su)
+	wonderfulAPI := api.New(su, sp)
 	r := chi.NewRouter()
 	swagger, err := openapi.GetSwagger()
 	require.NoError(ts.T(), err)
-	r.Use(middleware.OapiRequestValidator(swagger))
-	openapi.HandlerFromMux(wonderfulAPI, r)
+	r.Use(api.Compression(1024))
+	validator, err := api.RequestValidator(swagger)
+	require.NoError(ts.T(), err)
+	r.Use(validator)
+	negotiation, err := api.Negotiation(swagger)
+	require.NoError(ts.T(), err)
+	r.Use(negotiation)
+	r.Use(api.Idempotency(service.NewIdempotencyService(s)))
+	r.Use(api.AllowContentType("application/json", "application/x-ndjson", "text/csv"))
+	openapi.HandlerWithOptions(wonderfulAPI, openapi.ChiServerOptions{BaseRouter: r, ErrorHandlerFunc: api.SendRequestError})
 	ts.server = httptest.NewServer(r)
 }
 
 func (ts *APITestIntegrationSuite) TearDownSuite() {
 	ctx := context.Background()
+	require.NoError(ts.T(), ts.stopJobs(ctx))
 	err := test.TeardownDB(ctx, ts.container)
 	require.NoError(ts.T(), err)
 	ts.s.Close()
 }
 
+// waitForJob polls a populate job until it is done.
+func (ts *APITestIntegrationSuite) waitForJob(ctx context.Context, id string) openapi.Job {
+	var job openapi.Job
+	ts.Require().Eventually(func() bool {
+		statusCode, err := testhelpers.Get(ctx, ts.server.URL+"/populate/jobs/"+id, &job)
+		ts.Require().NoError(err)
+		ts.Require().Equal(http.StatusOK, statusCode)
+		return job.State == openapi.JobStateDone || job.State == openapi.JobStateFailed
+	}, 30*time.Second, 100*time.Millisecond)
+	ts.Require().Equal(openapi.JobStateDone, job.State, "job error: %v", job.Error)
+	return job
+}
+
+func (ts *APITestIntegrationSuite) TestConditionalRequests() {
+	ctx := context.Background()
+	var created openapi.User
+	statusCode, err := testhelpers.Post(ctx, ts.server.URL+"/wonderfuls", `{"name": "Mr. John Doe", "email": "john@mail.com"}`, &created)
+	ts.Require().NoError(err)
+	ts.Require().Equal(http.StatusCreated, statusCode)
+	do := func(method, path string, headers map[string]string, body string) *http.Response {
+		req, reqErr := http.NewRequestWithContext(ctx, method, ts.server.URL+path, strings.NewReader(body))
+		ts.Require().NoError(reqErr)
+		req.Header.Set("Content-Type", "application/json")
+		for k, v := range headers {
+			req.Header.Set(k, v)
+		}
+		res, reqErr := http.DefaultClient.Do(req)
+		ts.Require().NoError(reqErr)
+		_, reqErr = io.Copy(io.Discard, res.Body)
+		ts.Require().NoError(reqErr)
+		ts.Require().NoError(res.Body.Close())
+		return res
+	}
+
+	// a user is not sent again while it is unchanged
+	res := do(http.MethodGet, "/wonderfuls/"+created.Id, nil, "")
+	ts.Require().Equal(http.StatusOK, res.StatusCode)
+	etag := res.Header.Get("ETag")
+	ts.Require().NotEmpty(etag)
+	res = do(http.MethodGet, "/wonderfuls/"+created.Id, map[string]string{"If-None-Match": `"other", ` + etag}, "")
+	ts.Require().Equal(http.StatusNotModified, res.StatusCode)
+	ts.Require().Equal(etag, res.Header.Get("ETag"))
+	// the other representations have other tags
+	res = do(http.MethodGet, "/wonderfuls/"+created.Id, map[string]string{"If-None-Match": etag, "Accept": "text/csv"}, "")
+	ts.Require().Equal(http.StatusOK, res.StatusCode)
+	ts.Require().NotEqual(etag, res.Header.Get("ETag"))
+
+	// neither is a page of users
+	res = do(http.MethodGet, "/wonderfuls", nil, "")
+	ts.Require().Equal(http.StatusOK, res.StatusCode)
+	listETag := res.Header.Get("ETag")
+	ts.Require().NotEmpty(listETag)
+	ts.Require().NotEqual(etag, listETag)
+	res = do(http.MethodGet, "/wonderfuls", map[string]string{"If-None-Match": "W/" + listETag}, "")
+	ts.Require().Equal(http.StatusNotModified, res.StatusCode)
+	res = do(http.MethodGet, "/wonderfuls?include_total=true", map[string]string{"If-None-Match": listETag}, "")
+	ts.Require().Equal(http.StatusOK, res.StatusCode)
+	// the sparse pages are tagged with the versions of their users too
+	res = do(http.MethodGet, "/wonderfuls?fields=id,name", nil, "")
+	ts.Require().Equal(http.StatusOK, res.StatusCode)
+	sparseETag := res.Header.Get("ETag")
+	ts.Require().NotEmpty(sparseETag)
+
+	// the user is only changed with its current tag
+	res = do(http.MethodPatch, "/wonderfuls/"+created.Id, map[string]string{"If-Match": etag}, `{"name": "Mr. John Smith"}`)
+	ts.Require().Equal(http.StatusOK, res.StatusCode)
+	patchedETag := res.Header.Get("ETag")
+	ts.Require().NotEqual(etag, patchedETag)
+	replacement := `{"name": "Mr. John Brown", "email": "brown@mail.com"}`
+	res = do(http.MethodPut, "/wonderfuls/"+created.Id, map[string]string{"If-Match": etag}, replacement)
+	ts.Require().Equal(http.StatusPreconditionFailed, res.StatusCode)
+	// the weak tags never match
+	res = do(http.MethodPut, "/wonderfuls/"+created.Id, map[string]string{"If-Match": "W/" + patchedETag}, replacement)
+	ts.Require().Equal(http.StatusPreconditionFailed, res.StatusCode)
+	res = do(http.MethodDelete, "/wonderfuls/"+created.Id, map[string]string{"If-Match": etag}, "")
+	ts.Require().Equal(http.StatusPreconditionFailed, res.StatusCode)
+	var user openapi.User
+	statusCode, err = testhelpers.Get(ctx, ts.server.URL+"/wonderfuls/"+created.Id, &user)
+	ts.Require().NoError(err)
+	ts.Require().Equal(http.StatusOK, statusCode)
+	ts.Require().Equal("Mr. John Smith", user.Name)
+
+	// the page changed with its user
+	res = do(http.MethodGet, "/wonderfuls", map[string]string{"If-None-Match": listETag}, "")
+	ts.Require().Equal(http.StatusOK, res.StatusCode)
+	res = do(http.MethodGet, "/wonderfuls?fields=id,name", map[string]string{"If-None-Match": sparseETag}, "")
+	ts.Require().Equal(http.StatusOK, res.StatusCode)
+	ts.Require().NotEqual(sparseETag, res.Header.Get("ETag"))
+
+	res = do(http.MethodDelete, "/wonderfuls/"+created.Id, map[string]string{"If-Match": "*"}, "")
+	ts.Require().Equal(http.StatusNoContent, res.StatusCode)
+
+	// clean up
+	_, err = ts.s.Pool().Exec(ctx, "DELETE FROM users")
+	ts.Require().NoError(err)
+}
+
+func (ts *APITestIntegrationSuite) TestContentNegotiation() {
+	ctx := context.Background()
+	headers := map[string]string{"Content-Type": "application/json"}
+	var created openapi.User
+	for _, name := range []string{"Alice", "Bob", "Carol"} {
+		body := `{"name": "` + name + `", "email": "` + strings.ToLower(name) + `@mail.com"}`
+		statusCode, err := testhelpers.PostWithHeaders(ctx, ts.server.URL+"/wonderfuls", headers, body, &created)
+		ts.Require().NoError(err)
+		ts.Require().Equal(http.StatusCreated, statusCode)
+	}
+	get := func(path, accept string) (*http.Response, []byte) {
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, ts.server.URL+path, http.NoBody)
+		ts.Require().NoError(err)
+		req.Header.Set("Accept", accept)
+		res, err := http.DefaultClient.Do(req)
+		ts.Require().NoError(err)
+		defer res.Body.Close()
+		body, err := io.ReadAll(res.Body)
+		ts.Require().NoError(err)
+		return res, body
+	}
+
+	// JSON:API documents, with the sparse fieldsets in the attributes
+	res, body := get("/wonderfuls?sort=name&limit=2&include_total=true&fields=name", "application/vnd.api+json")
+	ts.Require().Equal(http.StatusOK, res.StatusCode)
+	ts.Require().Equal("application/vnd.api+json", res.Header.Get("Content-Type"))
+	ts.Require().Equal("Accept", res.Header.Get("Vary"))
+	var doc openapi.UserListDocument
+	ts.Require().NoError(json.Unmarshal(body, &doc))
+	ts.Require().Len(doc.Data, 2)
+	ts.Require().Equal(openapi.UserResourceTypeUsers, doc.Data[0].Type)
+	ts.Require().Equal(map[string]any{"name": "Alice"}, doc.Data[0].Attributes)
+	ts.Require().Equal("/wonderfuls/"+doc.Data[0].Id, doc.Data[0].Links.Self)
+	ts.Require().True(doc.Meta.HasMore)
+	ts.Require().Equal(int64(3), *doc.Meta.TotalCount)
+	ts.Require().Equal("/wonderfuls?sort=name&limit=2&include_total=true&fields=name", doc.Links.Self)
+	ts.Require().NotNil(doc.Links.Next)
+	ts.Require().Nil(doc.Links.Prev)
+	res, body = get("/wonderfuls/"+created.Id, "application/vnd.api+json")
+	ts.Require().Equal(http.StatusOK, res.StatusCode)
+	var userDoc openapi.UserDocument
+	ts.Require().NoError(json.Unmarshal(body, &userDoc))
+	ts.Require().Equal(created.Id, userDoc.Data.Id)
+	ts.Require().Equal("Carol", userDoc.Data.Attributes["name"])
+	ts.Require().NotContains(userDoc.Data.Attributes, "id")
+	ts.Require().Equal("/wonderfuls/"+created.Id, userDoc.Links.Self)
+
+	// CSV, with the columns of the export
+	res, body = get("/wonderfuls?sort=name", "text/csv")
+	ts.Require().Equal(http.StatusOK, res.StatusCode)
+	ts.Require().Equal("text/csv; charset=utf-8", res.Header.Get("Content-Type"))
+	records, err := csv.NewReader(bytes.NewReader(body)).ReadAll()
+	ts.Require().NoError(err)
+	ts.Require().Len(records, 4)
+	ts.Require().Equal([]string{"id", "name", "email"}, records[0][:3])
+	ts.Require().Equal("Alice", records[1][1])
+	// only the columns of the fields, and the ID
+	res, body = get("/wonderfuls?sort=name&fields=name,phone", "text/csv")
+	ts.Require().Equal(http.StatusOK, res.StatusCode)
+	records, err = csv.NewReader(bytes.NewReader(body)).ReadAll()
+	ts.Require().NoError(err)
+	ts.Require().Len(records, 4)
+	ts.Require().Equal([]string{"id", "name", "phone", "cell"}, records[0])
+	ts.Require().Equal("Alice", records[1][1])
+
+	// MessagePack, with the keys of the JSON and integers kept as integers
+	res, body = get("/wonderfuls?sort=name&include_total=true", "application/x-msgpack")
+	ts.Require().Equal(http.StatusOK, res.StatusCode)
+	ts.Require().Equal("application/msgpack", res.Header.Get("Content-Type"))
+	var list map[string]any
+	ts.Require().NoError(msgpack.Unmarshal(body, &list))
+	ts.Require().Len(list["data"], 3)
+	ts.Require().Equal("Alice", list["data"].([]any)[0].(map[string]any)["name"])
+	ts.Require().EqualValues(3, list["total_count"])
+	ts.Require().Equal(false, list["has_more"])
+
+	// the quality values are honoured, JSON is the default
+	for _, accept := range []string{"text/csv;q=0.5, application/json", "*/*", "application/*", "text/html, application/json;q=0.1"} {
+		res, body = get("/wonderfuls", accept)
+		ts.Require().Equal(http.StatusOK, res.StatusCode, accept)
+		ts.Require().Equal("application/json", res.Header.Get("Content-Type"), accept)
+		var users openapi.UserList
+		ts.Require().NoError(json.Unmarshal(body, &users), accept)
+		ts.Require().Len(users.Data, 3)
+	}
+
+	// the unsupported media types are not acceptable
+	for _, req := range []struct{ path, accept string }{
+		{"/wonderfuls", "application/xml"},
+		{"/wonderfuls/" + created.Id, "text/html, application/json;q=0"},
+		{"/wonderfuls/search?q=alice", "text/csv"},
+		{"/wonderfuls/export", "application/json"},
+	} {
+		res, body = get(req.path, req.accept)
+		ts.Require().Equal(http.StatusNotAcceptable, res.StatusCode, req.accept)
+		var errorResponse openapi.Problem
+		ts.Require().NoError(json.Unmarshal(body, &errorResponse))
+		ts.Require().Equal("application/problem+json", res.Header.Get("Content-Type"))
+		ts.Require().Equal(openapi.ProblemErrorCodeNotAcceptable, errorResponse.ErrorCode)
+		ts.Require().Equal(http.StatusNotAcceptable, errorResponse.Status)
+	}
+
+	// clean up
+	_, err = ts.s.Pool().Exec(ctx, "DELETE FROM users")
+	ts.Require().NoError(err)
+}
+
+func (ts *APITestIntegrationSuite) TestIdempotency() {
+	ctx := context.Background()
+	headers := map[string]string{"Content-Type": "application/json", api.IdempotencyKeyHeader: ksuid.New().String()}
+	body := `{"name": "Mr. Idem Potent", "email": "idem@mail.com", "phone": {"main": "123-456-7890"}}`
+
+	// the retry replays the first response instead of creating another user
+	var first, retry openapi.User
+	statusCode, err := testhelpers.PostWithHeaders(ctx, ts.server.URL+"/wonderfuls", headers, body, &first)
+	ts.Require().NoError(err)
+	ts.Require().Equal(http.StatusCreated, statusCode)
+	statusCode, err = testhelpers.PostWithHeaders(ctx, ts.server.URL+"/wonderfuls", headers, body, &retry)
+	ts.Require().NoError(err)
+	ts.Require().Equal(http.StatusCreated, statusCode)
+	ts.Require().Equal(first, retry)
+
+	var users openapi.UserList
+	statusCode, err = testhelpers.Get(ctx, ts.server.URL+"/wonderfuls?email=idem@mail.com", &users)
+	ts.Require().NoError(err)
+	ts.Require().Equal(http.StatusOK, statusCode)
+	ts.Require().Len(users.Data, 1)
+
+	// the same key with another body is rejected
+	var errorResponse openapi.Problem
+	other := `{"name": "Mrs. Other", "email": "other@mail.com", "phone": {"main": "123-456-7890"}}`
+	statusCode, err = testhelpers.PostWithHeaders(ctx, ts.server.URL+"/wonderfuls", headers, other, &errorResponse)
+	ts.Require().NoError(err)
+	ts.Require().Equal(http.StatusUnprocessableEntity, statusCode)
+
+	// clean up
+	_, err = ts.s.Pool().Exec(ctx, "DELETE FROM users")
+	ts.Require().NoError(err)
+}
+
+func (ts *APITestIntegrationSuite) TestImport() {
+	ctx := context.Background()
+
+	// the invalid lines are reported, the others imported
+	ndjson := `{"name": "Mr. John Doe", "email": "john@mail.com", "external_id": "john"}
+
+{"name": "No Email"}
+{"name": 42, "email": "jane@mail.com"}
+not json
+{"name": "Mrs. Jane Doe", "email": "jane@mail.com", "phone": {"main": "123-456-7890"}, "picture": {"thumbnail": "http://xpto.com/jane.jpg"}}
+`
+	headers := map[string]string{"Content-Type": "application/x-ndjson"}
+	var report openapi.ImportReport
+	statusCode, err := testhelpers.PostWithHeaders(ctx, ts.server.URL+"/wonderfuls/import", headers, ndjson, &report)
+	ts.Require().NoError(err)
+	ts.Require().Equal(http.StatusOK, statusCode)
+	ts.Require().Equal(2, report.Inserted)
+	ts.Require().Equal(3, report.Failed)
+	ts.Require().Len(report.Errors, 3)
+	ts.Require().Equal(3, report.Errors[0].Line)
+	ts.Require().Equal("email", *report.Errors[0].Field)
+	ts.Require().Equal("is required", report.Errors[0].Reason)
+	ts.Require().Equal(4, report.Errors[1].Line)
+	ts.Require().Equal("name", *report.Errors[1].Field)
+	ts.Require().Equal(5, report.Errors[2].Line)
+	ts.Require().Nil(report.Errors[2].Field)
+
+	// CSV, the unknown columns are ignored and the external IDs update the users
+	csv := "id,external_id,name,email,phone,picture_thumbnail,registration_date\n" +
+		"x,john,Mr. John Doe Jr.,john@mail.com,,,2020-01-01T00:00:00Z\n" +
+		"x,,\"Smith, John\",smith@mail.com,123,http://xpto.com/smith.jpg,\n" +
+		"x,,Mr. Late,late@mail.com,,,yesterday\n" +
+		"x,too,few\n"
+	headers["Content-Type"] = "text/csv"
+	statusCode, err = testhelpers.PostWithHeaders(ctx, ts.server.URL+"/wonderfuls/import", headers, csv, &report)
+	ts.Require().NoError(err)
+	ts.Require().Equal(http.StatusOK, statusCode)
+	ts.Require().Equal(1, report.Inserted)
+	ts.Require().Equal(1, report.Updated)
+	ts.Require().Equal(2, report.Failed)
+	ts.Require().Equal(4, report.Errors[0].Line)
+	ts.Require().Equal("registration_date", *report.Errors[0].Field)
+	ts.Require().Equal(5, report.Errors[1].Line)
+
+	var users openapi.UserList
+	statusCode, err = testhelpers.Get(ctx, ts.server.URL+"/wonderfuls?sort=name", &users)
+	ts.Require().NoError(err)
+	ts.Require().Equal(http.StatusOK, statusCode)
+	ts.Require().Len(users.Data, 3)
+	ts.Require().Equal("Mr. John Doe Jr.", users.Data[0].Name)
+	ts.Require().Equal("Mrs. Jane Doe", users.Data[1].Name)
+	ts.Require().Equal("http://xpto.com/jane.jpg", *users.Data[1].Picture.Thumbnail)
+	ts.Require().Equal("Smith, John", users.Data[2].Name)
+	ts.Require().Equal("123", *users.Data[2].Phone.Main)
+
+	// the bodies that can not be read are rejected
+	var errorResponse openapi.Problem
+	statusCode, err = testhelpers.PostWithHeaders(ctx, ts.server.URL+"/wonderfuls/import", headers, "id,email\nx,a@mail.com\n", &errorResponse)
+	ts.Require().NoError(err)
+	ts.Require().Equal(http.StatusBadRequest, statusCode)
+	headers["Content-Type"] = "application/xml"
+	statusCode, err = testhelpers.PostWithHeaders(ctx, ts.server.URL+"/wonderfuls/import", headers, "<users/>", &errorResponse)
+	ts.Require().NoError(err)
+	ts.Require().Equal(http.StatusUnsupportedMediaType, statusCode)
+
+	// the streamed bodies can not be fingerprinted, the imports are not idempotent
+	headers = map[string]string{"Content-Type": "application/x-ndjson", api.IdempotencyKeyHeader: ksuid.New().String()}
+	statusCode, err = testhelpers.PostWithHeaders(ctx, ts.server.URL+"/wonderfuls/import", headers, ndjson, &errorResponse)
+	ts.Require().NoError(err)
+	ts.Require().Equal(http.StatusBadRequest, statusCode)
+	ts.Require().Equal(openapi.ProblemErrorCodeInvalidIdempotencyKey, errorResponse.ErrorCode)
+
+	// clean up
+	_, err = ts.s.Pool().Exec(ctx, "DELETE FROM users")
+	ts.Require().NoError(err)
+}
+
+func (ts *APITestIntegrationSuite) TestExportImport() {
+	ctx := context.Background()
+	var job openapi.Job
+	statusCode, err := testhelpers.Post(ctx, ts.server.URL+"/populate", `{"count": 20}`, &job)
+	ts.Require().NoError(err)
+	ts.Require().Equal(http.StatusAccepted, statusCode)
+	job = ts.waitForJob(ctx, job.Id)
+	ts.Require().Equal(int32(20), job.Inserted)
+
+	count := func() int {
+		var users openapi.UserList
+		statusCode, err := testhelpers.Get(ctx, ts.server.URL+"/wonderfuls?limit=1&include_total=true", &users)
+		ts.Require().NoError(err)
+		ts.Require().Equal(http.StatusOK, statusCode)
+		return int(*users.TotalCount)
+	}
+
+	// importing an export back updates the users it came from, unchanged
+	for _, tc := range []struct{ format, contentType string }{
+		{"ndjson", "application/x-ndjson"},
+		{"csv", "text/csv"},
+	} {
+		res, err := http.Get(ts.server.URL + "/wonderfuls/export?format=" + tc.format) //nolint:noctx //test
+		ts.Require().NoError(err)
+		exported, err := io.ReadAll(res.Body)
+		res.Body.Close()
+		ts.Require().NoError(err)
+		ts.Require().Equal(http.StatusOK, res.StatusCode)
+
+		var report openapi.ImportReport
+		headers := map[string]string{"Content-Type": tc.contentType}
+		statusCode, err = testhelpers.PostWithHeaders(ctx, ts.server.URL+"/wonderfuls/import", headers, string(exported), &report)
+		ts.Require().NoError(err)
+		ts.Require().Equal(http.StatusOK, statusCode)
+		ts.Require().Zero(report.Failed, tc.format)
+		ts.Require().Zero(report.Inserted, tc.format)
+		ts.Require().Zero(report.Updated, tc.format)
+		ts.Require().Equal(20, report.Skipped, tc.format)
+		ts.Require().Equal(20, count(), tc.format)
+	}
+
+	// the fields the import does not read are kept when a user changes
+	var users openapi.UserList
+	statusCode, err = testhelpers.Get(ctx, ts.server.URL+"/wonderfuls?limit=1", &users)
+	ts.Require().NoError(err)
+	ts.Require().Equal(http.StatusOK, statusCode)
+	user := users.Data[0]
+	ts.Require().NotNil(user.Gender)
+	res, err := http.Get(ts.server.URL + "/wonderfuls/export?email=" + url.QueryEscape(user.Email)) //nolint:noctx //test
+	ts.Require().NoError(err)
+	exported, err := io.ReadAll(res.Body)
+	res.Body.Close()
+	ts.Require().NoError(err)
+	var report openapi.ImportReport
+	headers := map[string]string{"Content-Type": "application/x-ndjson"}
+	renamed := strings.Replace(string(exported), `"name":"`+user.Name+`"`, `"name":"Renamed"`, 1)
+	statusCode, err = testhelpers.PostWithHeaders(ctx, ts.server.URL+"/wonderfuls/import", headers, renamed, &report)
+	ts.Require().NoError(err)
+	ts.Require().Equal(http.StatusOK, statusCode)
+	ts.Require().Equal(1, report.Updated)
+	var updated openapi.User
+	statusCode, err = testhelpers.Get(ctx, ts.server.URL+"/wonderfuls/"+user.Id, &updated)
+	ts.Require().NoError(err)
+	ts.Require().Equal(http.StatusOK, statusCode)
+	ts.Require().Equal("Renamed", updated.Name)
+	ts.Require().Equal(user.Gender, updated.Gender)
+	ts.Require().Equal(user.Nat, updated.Nat)
+	ts.Require().Equal(20, count())
+
+	// clean up
+	_, err = ts.s.Pool().Exec(ctx, "DELETE FROM users")
+	ts.Require().NoError(err)
+}
+
+func (ts *APITestIntegrationSuite) TestUserCRUD() {
+	ctx := context.Background()
+	headers := map[string]string{"Content-Type": "application/json"}
+
+	// create
+	var created openapi.User
+	body := `{"name": "Mr. John Doe", "email": "john@mail.com", "phone": {"main": "123-456-7890"},
+		"registration_date": "2021-01-01T00:00:00Z"}`
+	statusCode, err := testhelpers.PostWithHeaders(ctx, ts.server.URL+"/wonderfuls", headers, body, &created)
+	ts.Require().NoError(err)
+	ts.Require().Equal(http.StatusCreated, statusCode)
+	ts.Require().NotEmpty(created.Id)
+	ts.Require().Equal("Mr. John Doe", created.Name)
+	ts.Require().Nil(created.UpdatedAt)
+
+	// the registration bounds are compared in UTC whatever their offset
+	for _, tc := range []struct {
+		query string
+		found int
+	}{
+		{"registered_after=2021-01-01T01:59:59%2B02:00", 1},
+		{"registered_after=2021-01-01T02:00:00%2B02:00", 0},
+		{"registered_before=2021-01-01T02:00:01%2B02:00", 1},
+		{"registered_before=2021-01-01T02:00:00%2B02:00", 0},
+	} {
+		var filtered openapi.UserList
+		statusCode, err = testhelpers.Get(ctx, ts.server.URL+"/wonderfuls?email=john@mail.com&"+tc.query, &filtered)
+		ts.Require().NoError(err)
+		ts.Require().Equal(http.StatusOK, statusCode)
+		ts.Require().Len(filtered.Data, tc.found, tc.query)
+		res, err := http.Get(ts.server.URL + "/wonderfuls/export?email=john@mail.com&" + tc.query) //nolint:noctx //test
+		ts.Require().NoError(err)
+		exported, err := io.ReadAll(res.Body)
+		res.Body.Close()
+		ts.Require().NoError(err)
+		ts.Require().Equal(http.StatusOK, res.StatusCode)
+		ts.Require().Equal(tc.found, strings.Count(string(exported), created.Id), tc.query)
+	}
+
+	// invalid bodies are rejected by the OpenAPI validator
+	res, err := http.Post(ts.server.URL+"/wonderfuls", "application/json", strings.NewReader(`{"name": "No Email"}`)) //nolint:noctx //test
+	ts.Require().NoError(err)
+	res.Body.Close()
+	ts.Require().Equal(http.StatusBadRequest, res.StatusCode)
+	var errorResponse openapi.Problem
+
+	// replace
+	var replaced openapi.User
+	body = `{"name": "Mr. John Smith", "email": "smith@mail.com"}`
+	statusCode, err = testhelpers.Put(ctx, ts.server.URL+"/wonderfuls/"+created.Id, body, headers, &replaced)
+	ts.Require().NoError(err)
+	ts.Require().Equal(http.StatusOK, statusCode)
+	ts.Require().Equal(created.Id, replaced.Id)
+	ts.Require().Equal("Mr. John Smith", replaced.Name)
+	ts.Require().Equal("smith@mail.com", replaced.Email)
+	ts.Require().Equal(created.RegistrationDate, replaced.RegistrationDate)
+	ts.Require().NotNil(replaced.UpdatedAt)
+
+	// patch
+	var patched openapi.User
+	statusCode, err = testhelpers.Patch(ctx, ts.server.URL+"/wonderfuls/"+created.Id, `{"phone": {"cell": "555-0100"}}`, headers, &patched)
+	ts.Require().NoError(err)
+	ts.Require().Equal(http.StatusOK, statusCode)
+	ts.Require().Equal("Mr. John Smith", patched.Name)
+	ts.Require().Equal("555-0100", *patched.Phone.Cell)
+
+	// delete
+	var responseEmpty struct{}
+	statusCode, err = testhelpers.Delete(ctx, ts.server.URL+"/wonderfuls/"+created.Id, &responseEmpty)
+	ts.Require().NoError(err)
+	ts.Require().Equal(http.StatusNoContent, statusCode)
+	statusCode, err = testhelpers.Delete(ctx, ts.server.URL+"/wonderfuls/"+created.Id, &errorResponse)
+	ts.Require().NoError(err)
+	ts.Require().Equal(http.StatusNotFound, statusCode)
+	statusCode, err = testhelpers.Patch(ctx, ts.server.URL+"/wonderfuls/"+created.Id, `{"name": "Nobody"}`, headers, &errorResponse)
+	ts.Require().NoError(err)
+	ts.Require().Equal(http.StatusNotFound, statusCode)
+
+	// soft-deleted users are hidden unless explicitly requested
+	var users openapi.UserList
+	statusCode, err = testhelpers.Get(ctx, ts.server.URL+"/wonderfuls", &users)
+	ts.Require().NoError(err)
+	ts.Require().Equal(http.StatusOK, statusCode)
+	ts.Require().Len(users.Data, 0)
+	statusCode, err = testhelpers.Get(ctx, ts.server.URL+"/wonderfuls?include_deleted=true", &users)
+	ts.Require().NoError(err)
+	ts.Require().Equal(http.StatusOK, statusCode)
+	ts.Require().Len(users.Data, 1)
+	ts.Require().NotNil(users.Data[0].DeletedAt)
+
+	// restore
+	var restored openapi.User
+	statusCode, err = testhelpers.PostWithHeaders(ctx, ts.server.URL+"/wonderfuls/"+created.Id+"/restore", headers, "", &restored)
+	ts.Require().NoError(err)
+	ts.Require().Equal(http.StatusOK, statusCode)
+	ts.Require().Nil(restored.DeletedAt)
+	// the users that are not deleted are left as they are
+	statusCode, err = testhelpers.PostWithHeaders(ctx, ts.server.URL+"/wonderfuls/"+created.Id+"/restore", headers, "", &errorResponse)
+	ts.Require().NoError(err)
+	ts.Require().Equal(http.StatusConflict, statusCode)
+	ts.Require().Equal(openapi.ProblemErrorCodeUserNotDeleted, errorResponse.ErrorCode)
+
+	// purge only removes users deleted before the cutoff
+	statusCode, err = testhelpers.Delete(ctx, ts.server.URL+"/wonderfuls/"+created.Id, &responseEmpty)
+	ts.Require().NoError(err)
+	ts.Require().Equal(http.StatusNoContent, statusCode)
+	var purged openapi.PurgeResult
+	statusCode, err = testhelpers.PostWithHeaders(ctx, ts.server.URL+"/admin/purge", headers, `{"deleted_before": "2000-01-01T00:00:00Z"}`, &purged)
+	ts.Require().NoError(err)
+	ts.Require().Equal(http.StatusOK, statusCode)
+	ts.Require().Equal(int64(0), purged.Purged)
+	cutoff := time.Now().Add(time.Hour).UTC().Format(time.RFC3339)
+	statusCode, err = testhelpers.PostWithHeaders(ctx, ts.server.URL+"/admin/purge", headers, `{"deleted_before": "`+cutoff+`"}`, &purged)
+	ts.Require().NoError(err)
+	ts.Require().Equal(http.StatusOK, statusCode)
+	ts.Require().Equal(int64(1), purged.Purged)
+	statusCode, err = testhelpers.PostWithHeaders(ctx, ts.server.URL+"/wonderfuls/"+created.Id+"/restore", headers, "", &errorResponse)
+	ts.Require().NoError(err)
+	ts.Require().Equal(http.StatusNotFound, statusCode)
+}
+
 func (ts *APITestIntegrationSuite) TestUsers() {
 	ctx := context.Background()
-	var response []openapi.User
+	var response openapi.UserList
 
 	statusCode, err := testhelpers.Get(ctx, ts.server.URL+"/wonderfuls", &response)
 	ts.Require().NoError(err)
 	ts.Require().Equal(http.StatusOK, statusCode)
-	ts.Require().Len(response, 0)
+	ts.Require().Len(response.Data, 0)
 
 	// Populate the database
-	var responseEmpty struct{}
-	statusCode, err = testhelpers.Post(ctx, ts.server.URL+"/populate", "", &responseEmpty)
+	var job openapi.Job
+	statusCode, err = testhelpers.Post(ctx, ts.server.URL+"/populate", "", &job)
+	ts.Require().NoError(err)
+	ts.Require().Equal(http.StatusAccepted, statusCode)
+	ts.Require().Equal(openapi.JobStateQueued, job.State)
+
+	// Wait for the job to finish
+	job = ts.waitForJob(ctx, job.Id)
+	ts.Require().Equal(job.Fetched, job.Inserted)
+	ts.Require().NotNil(job.FinishedAt)
+	ts.Require().Equal(5000, *job.Options.Count)
+	ts.Require().Equal(openapi.PopulateRequestSourceSynthetic, *job.Options.Source)
+
+	// Populating the same users again does not duplicate them
+	statusCode, err = testhelpers.Post(ctx, ts.server.URL+"/populate", "", &job)
 	ts.Require().NoError(err)
-	ts.Require().Equal(201, statusCode)
+	ts.Require().Equal(http.StatusAccepted, statusCode)
+	job = ts.waitForJob(ctx, job.Id)
+	ts.Require().Zero(job.Inserted)
+	ts.Require().Zero(job.Updated)
+	ts.Require().Equal(job.Fetched, job.Skipped)
+
+	// Unknown job
+	var errorResponse openapi.Problem
+	statusCode, err = testhelpers.Get(ctx, ts.server.URL+"/populate/jobs/"+ksuid.New().String(), &errorResponse)
+	ts.Require().NoError(err)
+	ts.Require().Equal(http.StatusNotFound, statusCode)
+
+	// Invalid populate options
+	headers := map[string]string{"Content-Type": "application/json"}
+	for _, body := range []string{`{"count": 0}`, `{"count": 50001}`, `{"gender": "other"}`, `{"nat": ["XX"]}`, `{"source": "file"}`} {
+		errorResponse = openapi.Problem{}
+		statusCode, err = testhelpers.PostWithHeaders(ctx, ts.server.URL+"/populate", headers, body, &errorResponse)
+		ts.Require().NoError(err)
+		ts.Require().Equal(http.StatusBadRequest, statusCode, body)
+		ts.Require().NotNil(errorResponse.Errors, body)
+	}
 
 	// Get default number of users
 	statusCode, err = testhelpers.Get(ctx, ts.server.URL+"/wonderfuls", &response)
 	ts.Require().NoError(err)
 	ts.Require().Equal(http.StatusOK, statusCode)
-	ts.Require().Len(response, 10)
+	ts.Require().Len(response.Data, 10)
 
 	// Get 50 users
 	statusCode, err = testhelpers.Get(ctx, ts.server.URL+"/wonderfuls?limit=50", &response)
 	ts.Require().NoError(err)
 	ts.Require().Equal(http.StatusOK, statusCode)
-	ts.Require().Len(response, 50)
-	ts.Require().Greater(response[0].RegistrationDate, response[49].RegistrationDate)
+	ts.Require().Len(response.Data, 50)
+	ts.Require().Greater(response.Data[0].RegistrationDate, response.Data[49].RegistrationDate)
 
-	// invalid limit
-	var errorResponse openapi.Error
+	// the envelope and the Link header point to the next page
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, ts.server.URL+"/wonderfuls?limit=50&include_total=true", http.NoBody)
+	ts.Require().NoError(err)
+	resp, err := http.DefaultClient.Do(req)
+	ts.Require().NoError(err)
+	defer resp.Body.Close()
+	var page openapi.UserList
+	ts.Require().NoError(json.NewDecoder(resp.Body).Decode(&page))
+	ts.Require().Equal(response.Data, page.Data)
+	ts.Require().True(page.HasMore)
+	ts.Require().NotEmpty(*page.NextCursor)
+	ts.Require().Nil(page.PrevCursor)
+	ts.Require().Equal(int64(5000), *page.TotalCount)
+	ts.Require().Equal(`</wonderfuls?include_total=true&limit=50>; rel="first", `+
+		`</wonderfuls?include_total=true&limit=50&starting_after=`+*page.NextCursor+`>; rel="next"`, resp.Header.Get("Link"))
+
+	// invalid limit, the bounds of the spec are enforced by the validator
 	statusCode, err = testhelpers.Get(ctx, ts.server.URL+"/wonderfuls?limit=0", &errorResponse)
 	ts.Require().NoError(err)
 	ts.Require().Equal(http.StatusBadRequest, statusCode)
-	ts.Require().Equal("invalid limit: limit must be between 1 and 100", errorResponse.Message)
+	ts.Require().Equal(openapi.ProblemErrorCodeInvalidParameter, errorResponse.ErrorCode)
+	ts.Require().Equal(&[]openapi.FieldError{{Field: "limit", Reason: "number must be at least 1"}}, errorResponse.Errors)
 	statusCode, err = testhelpers.Get(ctx, ts.server.URL+"/wonderfuls?limit=150", &errorResponse)
 	ts.Require().NoError(err)
 	ts.Require().Equal(http.StatusBadRequest, statusCode)
-	ts.Require().Equal("invalid limit: limit must be between 1 and 100", errorResponse.Message)
+	ts.Require().Equal(&[]openapi.FieldError{{Field: "limit", Reason: "number must be at most 100"}}, errorResponse.Errors)
+
+	// the errors are problem details, listing every invalid parameter
+	var problem openapi.Problem
+	statusCode, err = testhelpers.Get(ctx, ts.server.URL+"/wonderfuls?starting_after=a&ending_before=b&sort=phone&fields=id,password", &problem)
+	ts.Require().NoError(err)
+	ts.Require().Equal(http.StatusBadRequest, statusCode)
+	ts.Require().Equal(openapi.Problem{
+		Type:   "urn:wonderful:problem:invalid_parameter",
+		Title:  "Invalid parameter",
+		Status: http.StatusBadRequest,
+		Detail: "invalid ending_before: only one of starting_after and ending_before can be used\n" +
+			"invalid sort: unknown field \"phone\"\ninvalid fields: unknown field \"password\"",
+		Instance:  "/wonderfuls?starting_after=a&ending_before=b&sort=phone&fields=id,password",
+		ErrorCode: openapi.ProblemErrorCodeInvalidParameter,
+		Errors: &[]openapi.FieldError{
+			{Field: "ending_before", Reason: "only one of starting_after and ending_before can be used"},
+			{Field: "sort", Reason: `unknown field "phone"`},
+			{Field: "fields", Reason: `unknown field "password"`},
+		},
+	}, problem)
+
+	// sort, the pages follow the order of the database collation
+	var byName100, byName, byName2ndPage openapi.UserList
+	statusCode, err = testhelpers.Get(ctx, ts.server.URL+"/wonderfuls?limit=100&sort=name,-registration", &byName100)
+	ts.Require().NoError(err)
+	ts.Require().Equal(http.StatusOK, statusCode)
+	ts.Require().Len(byName100.Data, 100)
+	statusCode, err = testhelpers.Get(ctx, ts.server.URL+"/wonderfuls?limit=50&sort=name,-registration", &byName)
+	ts.Require().NoError(err)
+	ts.Require().Equal(http.StatusOK, statusCode)
+	ts.Require().Equal(byName100.Data[:50], byName.Data)
+	statusCode, err = testhelpers.Get(ctx, ts.server.URL+"/wonderfuls?limit=50&sort=name,-registration&starting_after="+*byName.NextCursor, &byName2ndPage)
+	ts.Require().NoError(err)
+	ts.Require().Equal(http.StatusOK, statusCode)
+	ts.Require().Equal(byName100.Data[50:], byName2ndPage.Data)
+	// the cursor of another order is rejected
+	statusCode, err = testhelpers.Get(ctx, ts.server.URL+"/wonderfuls?limit=50&starting_after="+*byName.NextCursor, &errorResponse)
+	ts.Require().NoError(err)
+	ts.Require().Equal(http.StatusBadRequest, statusCode)
+	ts.Require().Equal("Invalid cursor", errorResponse.Detail)
+	statusCode, err = testhelpers.Get(ctx, ts.server.URL+"/wonderfuls?sort=phone", &errorResponse)
+	ts.Require().NoError(err)
+	ts.Require().Equal(http.StatusBadRequest, statusCode)
+	ts.Require().Equal(`invalid sort: unknown field "phone"`, errorResponse.Detail)
+	statusCode, err = testhelpers.Get(ctx, ts.server.URL+"/wonderfuls?sort=name,-name", &errorResponse)
+	ts.Require().NoError(err)
+	ts.Require().Equal(http.StatusBadRequest, statusCode)
+	ts.Require().Equal(`invalid sort: repeated field "name"`, errorResponse.Detail)
+
+	// filters
+	var filtered, filtered2ndPage openapi.UserList
+	statusCode, err = testhelpers.Get(ctx, ts.server.URL+"/wonderfuls?limit=5&gender=female&nat=US", &filtered)
+	ts.Require().NoError(err)
+	ts.Require().Equal(http.StatusOK, statusCode)
+	ts.Require().Len(filtered.Data, 5)
+	statusCode, err = testhelpers.Get(ctx, ts.server.URL+"/wonderfuls?limit=5&gender=female&nat=US&starting_after="+*filtered.NextCursor, &filtered2ndPage)
+	ts.Require().NoError(err)
+	ts.Require().Equal(http.StatusOK, statusCode)
+	for _, u := range append(filtered.Data, filtered2ndPage.Data...) {
+		ts.Require().Equal("female", *u.Gender)
+		ts.Require().Equal("US", *u.Nat)
+	}
+	// the cursor of other filters is rejected
+	statusCode, err = testhelpers.Get(ctx, ts.server.URL+"/wonderfuls?limit=5&gender=female&starting_after="+*filtered.NextCursor, &errorResponse)
+	ts.Require().NoError(err)
+	ts.Require().Equal(http.StatusBadRequest, statusCode)
+	ts.Require().Equal("Invalid cursor", errorResponse.Detail)
+	registeredAfter := response.Data[9].RegistrationDate.Format(time.RFC3339Nano)
+	statusCode, err = testhelpers.Get(ctx, ts.server.URL+"/wonderfuls?limit=100&registered_after="+registeredAfter, &filtered)
+	ts.Require().NoError(err)
+	ts.Require().Equal(http.StatusOK, statusCode)
+	for _, u := range filtered.Data {
+		ts.Require().True(u.RegistrationDate.After(response.Data[9].RegistrationDate))
+	}
+	statusCode, err = testhelpers.Get(ctx, ts.server.URL+"/wonderfuls?registered_after=2020-01-02T00:00:00Z&registered_before=2020-01-01T00:00:00Z", &errorResponse)
+	ts.Require().NoError(err)
+	ts.Require().Equal(http.StatusBadRequest, statusCode)
+	ts.Require().Equal("invalid registered_before: registered_before must be after registered_after", errorResponse.Detail)
+
+	// sparse fieldsets, the id is always returned
+	var sparse struct {
+		Data       []map[string]any `json:"data"`
+		NextCursor string           `json:"next_cursor"`
+	}
+	statusCode, err = testhelpers.Get(ctx, ts.server.URL+"/wonderfuls?limit=5&fields=name,picture.thumbnail", &sparse)
+	ts.Require().NoError(err)
+	ts.Require().Equal(http.StatusOK, statusCode)
+	ts.Require().Len(sparse.Data, 5)
+	for i, u := range sparse.Data {
+		ts.Require().Equal(map[string]any{
+			"id":      response.Data[i].Id,
+			"name":    response.Data[i].Name,
+			"picture": map[string]any{"thumbnail": *response.Data[i].Picture.Thumbnail},
+		}, u)
+	}
+	statusCode, err = testhelpers.Get(ctx, ts.server.URL+"/wonderfuls?limit=5&fields=email&starting_after="+sparse.NextCursor, &sparse)
+	ts.Require().NoError(err)
+	ts.Require().Equal(http.StatusOK, statusCode)
+	ts.Require().Len(sparse.Data, 5)
+	for i, u := range sparse.Data {
+		ts.Require().Equal(map[string]any{"id": response.Data[i+5].Id, "email": response.Data[i+5].Email}, u)
+	}
+	statusCode, err = testhelpers.Get(ctx, ts.server.URL+"/wonderfuls?fields=id,password", &errorResponse)
+	ts.Require().NoError(err)
+	ts.Require().Equal(http.StatusBadRequest, statusCode)
+	ts.Require().Equal(`invalid fields: unknown field "password"`, errorResponse.Detail)
+
+	// export every user as NDJSON, or as CSV with the Accept header
+	export := func(query, accept string) (*http.Response, []string) {
+		exportReq, reqErr := http.NewRequestWithContext(ctx, http.MethodGet, ts.server.URL+"/wonderfuls/export"+query, http.NoBody)
+		ts.Require().NoError(reqErr)
+		if accept != "" {
+			exportReq.Header.Set("Accept", accept)
+		}
+		exportResp, reqErr := http.DefaultClient.Do(exportReq)
+		ts.Require().NoError(reqErr)
+		defer exportResp.Body.Close()
+		body, reqErr := io.ReadAll(exportResp.Body)
+		ts.Require().NoError(reqErr)
+		return exportResp, strings.Split(strings.TrimSuffix(string(body), "\n"), "\n")
+	}
+	resp, lines := export("", "")
+	ts.Require().Equal(http.StatusOK, resp.StatusCode)
+	ts.Require().Equal("application/x-ndjson", resp.Header.Get("Content-Type"))
+	ts.Require().Len(lines, 5000)
+	var exported openapi.User
+	ts.Require().NoError(json.Unmarshal([]byte(lines[0]), &exported))
+	ts.Require().Equal(response.Data[0], exported)
+	// with the external ID of the populated users
+	var externalID struct {
+		ExternalID string `json:"external_id"`
+	}
+	ts.Require().NoError(json.Unmarshal([]byte(lines[0]), &externalID))
+	ts.Require().NotEmpty(externalID.ExternalID)
+	resp, lines = export("?gender=female&nat=US", "text/csv")
+	ts.Require().Equal(http.StatusOK, resp.StatusCode)
+	ts.Require().Equal("text/csv; charset=utf-8", resp.Header.Get("Content-Type"))
+	ts.Require().True(strings.HasPrefix(lines[0], "id,external_id,name,email,"))
+	var females openapi.UserList
+	statusCode, err = testhelpers.Get(ctx, ts.server.URL+"/wonderfuls?gender=female&nat=US&include_total=true", &females)
+	ts.Require().NoError(err)
+	ts.Require().Equal(http.StatusOK, statusCode)
+	ts.Require().Len(lines, int(*females.TotalCount)+1)
+	ts.Require().True(strings.HasPrefix(lines[1], females.Data[0].Id+","))
+	// the Accept header is negotiated with its qualities
+	resp, _ = export("?email=nobody@nowhere", "text/csv;q=0, application/x-ndjson")
+	ts.Require().Equal("application/x-ndjson", resp.Header.Get("Content-Type"))
+	resp, _ = export("?email=nobody@nowhere", "application/x-ndjson;q=0.5, text/csv")
+	ts.Require().Equal("text/csv; charset=utf-8", resp.Header.Get("Content-Type"))
+	// the format parameter overrides the Accept header
+	resp, lines = export("?format=ndjson&email=nobody@nowhere", "text/csv")
+	ts.Require().Equal("application/x-ndjson", resp.Header.Get("Content-Type"))
+	ts.Require().Equal([]string{""}, lines)
+	resp, _ = export("?registered_after=2020-01-02T00:00:00Z&registered_before=2020-01-01T00:00:00Z", "")
+	ts.Require().Equal(http.StatusBadRequest, resp.StatusCode)
+
+	// the email filter ignores the case
+	var byEmail openapi.UserList
+	statusCode, err = testhelpers.Get(ctx, ts.server.URL+"/wonderfuls?email="+url.QueryEscape(strings.ToUpper(response.Data[0].Email)), &byEmail)
+	ts.Require().NoError(err)
+	ts.Require().Equal(http.StatusOK, statusCode)
+	ts.Require().Equal([]openapi.User{response.Data[0]}, byEmail.Data)
+
+	// search, ranked by relevance
+	lastName := strings.ToUpper(*response.Data[0].LastName)
+	var searched, searched2ndPage openapi.UserList
+	statusCode, err = testhelpers.Get(ctx, ts.server.URL+"/wonderfuls?limit=5&q="+url.QueryEscape(lastName), &searched)
+	ts.Require().NoError(err)
+	ts.Require().Equal(http.StatusOK, statusCode)
+	ts.Require().NotEmpty(searched.Data)
+	ts.Require().Contains(strings.ToUpper(searched.Data[0].Name), lastName)
+	if searched.HasMore {
+		statusCode, err = testhelpers.Get(ctx, ts.server.URL+"/wonderfuls?limit=5&q="+url.QueryEscape(lastName)+"&starting_after="+*searched.NextCursor, &searched2ndPage)
+		ts.Require().NoError(err)
+		ts.Require().Equal(http.StatusOK, statusCode)
+		for _, u := range searched.Data {
+			require.NotContains(ts.T(), searched2ndPage.Data, u)
+		}
+	}
+	statusCode, err = testhelpers.Get(ctx, ts.server.URL+"/wonderfuls?sort=-relevance", &errorResponse)
+	ts.Require().NoError(err)
+	ts.Require().Equal(http.StatusBadRequest, statusCode)
+	ts.Require().Equal("invalid sort: relevance can only be sorted by with q", errorResponse.Detail)
+
+	// full-text search, with the matches highlighted
+	var results, results2ndPage openapi.SearchResults
+	statusCode, err = testhelpers.Get(ctx, ts.server.URL+"/wonderfuls/search?limit=2&q="+url.QueryEscape(lastName), &results)
+	ts.Require().NoError(err)
+	ts.Require().Equal(http.StatusOK, statusCode)
+	ts.Require().NotEmpty(results.Data)
+	ts.Require().Positive(results.Data[0].Rank)
+	ts.Require().Contains(strings.ToUpper(results.Data[0].Highlights.Name), "<MARK>"+lastName+"</MARK>")
+	if results.HasMore {
+		statusCode, err = testhelpers.Get(ctx, ts.server.URL+"/wonderfuls/search?limit=2&q="+url.QueryEscape(lastName)+"&starting_after="+*results.NextCursor, &results2ndPage)
+		ts.Require().NoError(err)
+		ts.Require().Equal(http.StatusOK, statusCode)
+		ts.Require().NotEmpty(results2ndPage.Data)
+		for _, r := range results.Data {
+			require.NotContains(ts.T(), results2ndPage.Data, r)
+		}
+	}
+	// the cursors of a listing or of another search are rejected
+	cursors := []string{*byName.NextCursor}
+	if results.NextCursor != nil {
+		cursors = append(cursors, *results.NextCursor)
+	}
+	for _, cursor := range cursors {
+		statusCode, err = testhelpers.Get(ctx, ts.server.URL+"/wonderfuls/search?q=xyz&starting_after="+cursor, &errorResponse)
+		ts.Require().NoError(err)
+		ts.Require().Equal(http.StatusBadRequest, statusCode)
+		ts.Require().Equal("Invalid cursor", errorResponse.Detail)
+	}
+	statusCode, err = testhelpers.Get(ctx, ts.server.URL+"/wonderfuls/search", &errorResponse)
+	ts.Require().NoError(err)
+	ts.Require().Equal(http.StatusBadRequest, statusCode)
 
 	// starting_after and ending_before
 	statusCode, err = testhelpers.Get(ctx, ts.server.URL+"/wonderfuls?starting_after=1&ending_before=2", &errorResponse)
 	ts.Require().NoError(err)
 	ts.Require().Equal(http.StatusBadRequest, statusCode)
-	ts.Require().Equal("invalid startingAfter and endingBefore: only one of them can be used", errorResponse.Message)
+	ts.Require().Equal("invalid ending_before: only one of starting_after and ending_before can be used", errorResponse.Detail)
 
 	// starting_after
-	var response2ndPage []openapi.User
-	statusCode, err = testhelpers.Get(ctx, ts.server.URL+"/wonderfuls?limit=50&starting_after="+response[49].Id, &response2ndPage)
+	var response2ndPage openapi.UserList
+	statusCode, err = testhelpers.Get(ctx, ts.server.URL+"/wonderfuls?limit=50&starting_after="+*page.NextCursor, &response2ndPage)
 	ts.Require().NoError(err)
 	ts.Require().Equal(http.StatusOK, statusCode)
-	ts.Require().Len(response2ndPage, 50)
-	ts.Require().Greater(response2ndPage[0].RegistrationDate, response2ndPage[49].RegistrationDate)
-	for _, u := range response {
-		require.NotContains(ts.T(), response2ndPage, u)
+	ts.Require().Len(response2ndPage.Data, 50)
+	ts.Require().Greater(response2ndPage.Data[0].RegistrationDate, response2ndPage.Data[49].RegistrationDate)
+	for _, u := range response.Data {
+		require.NotContains(ts.T(), response2ndPage.Data, u)
 	}
 
 	// ending_before
-	var response1stPage []openapi.User
-	statusCode, err = testhelpers.Get(ctx, ts.server.URL+"/wonderfuls?limit=50&ending_before="+response2ndPage[0].Id, &response1stPage)
+	var response1stPage openapi.UserList
+	statusCode, err = testhelpers.Get(ctx, ts.server.URL+"/wonderfuls?limit=50&ending_before="+*response2ndPage.PrevCursor, &response1stPage)
 	ts.Require().NoError(err)
 	ts.Require().Equal(http.StatusOK, statusCode)
-	ts.Require().Len(response1stPage, 50)
-	for _, u := range response2ndPage {
-		require.NotContains(ts.T(), response1stPage, u)
+	ts.Require().Len(response1stPage.Data, 50)
+	for _, u := range response2ndPage.Data {
+		require.NotContains(ts.T(), response1stPage.Data, u)
 	}
-	for i, u := range response {
-		require.Equal(ts.T(), u, response1stPage[i])
+	for i, u := range response.Data {
+		require.Equal(ts.T(), u, response1stPage.Data[i])
 	}
 
+	// cursors are opaque and signed
+	statusCode, err = testhelpers.Get(ctx, ts.server.URL+"/wonderfuls?starting_after="+response.Data[49].Id, &errorResponse)
+	ts.Require().NoError(err)
+	ts.Require().Equal(http.StatusBadRequest, statusCode)
+	ts.Require().Equal("Invalid cursor", errorResponse.Detail)
+	statusCode, err = testhelpers.Get(ctx, ts.server.URL+"/wonderfuls?ending_before="+*page.NextCursor, &errorResponse)
+	ts.Require().NoError(err)
+	ts.Require().Equal(http.StatusBadRequest, statusCode)
+	ts.Require().Equal("Invalid cursor", errorResponse.Detail)
+
+	// get a single user by ID
+	var user openapi.User
+	statusCode, err = testhelpers.Get(ctx, ts.server.URL+"/wonderfuls/"+response.Data[0].Id, &user)
+	ts.Require().NoError(err)
+	ts.Require().Equal(http.StatusOK, statusCode)
+	ts.Require().Equal(response.Data[0], user)
+
+	// unknown and malformed IDs
+	statusCode, err = testhelpers.Get(ctx, ts.server.URL+"/wonderfuls/"+ksuid.New().String(), &errorResponse)
+	ts.Require().NoError(err)
+	ts.Require().Equal(http.StatusNotFound, statusCode)
+	statusCode, err = testhelpers.Get(ctx, ts.server.URL+"/wonderfuls/notaksuid", &errorResponse)
+	ts.Require().NoError(err)
+	ts.Require().Equal(http.StatusNotFound, statusCode)
+
 	// email
-	statusCode, err = testhelpers.Get(ctx, ts.server.URL+"/wonderfuls?email="+response[0].Email, &response)
+	statusCode, err = testhelpers.Get(ctx, ts.server.URL+"/wonderfuls?email="+response.Data[0].Email, &response)
 	ts.Require().NoError(err)
 	ts.Require().Equal(http.StatusOK, statusCode)
-	ts.Require().Len(response, 1)
+	ts.Require().Len(response.Data, 1)
 
 	// email not found
 	statusCode, err = testhelpers.Get(ctx, ts.server.URL+"/wonderfuls?email=notfound", &response)
 	ts.Require().NoError(err)
 	ts.Require().Equal(http.StatusOK, statusCode)
-	ts.Require().Len(response, 0)
+	ts.Require().Len(response.Data, 0)
 
 	// partial email
-	statusCode, err = testhelpers.Get(ctx, ts.server.URL+"/wonderfuls?email="+response2ndPage[0].Email[:5], &response)
+	statusCode, err = testhelpers.Get(ctx, ts.server.URL+"/wonderfuls?email="+response2ndPage.Data[0].Email[:5], &response)
 	ts.Require().NoError(err)
 	ts.Require().Equal(http.StatusOK, statusCode)
-	ts.Require().Greater(len(response), 0)
-	statusCode, err = testhelpers.Get(ctx, ts.server.URL+"/wonderfuls?email="+response2ndPage[0].Email[5:], &response)
+	ts.Require().Greater(len(response.Data), 0)
+	statusCode, err = testhelpers.Get(ctx, ts.server.URL+"/wonderfuls?email="+response2ndPage.Data[0].Email[5:], &response)
 	ts.Require().NoError(err)
 	ts.Require().Equal(http.StatusOK, statusCode)
-	ts.Require().Greater(len(response), 0)
+	ts.Require().Greater(len(response.Data), 0)
 
 	// SQL injection and make sure the database is not affected
 	// '; DROP TABLE users; --
@@ -161,9 +987,9 @@ func (ts *APITestIntegrationSuite) TestUsers() {
 	statusCode, err = testhelpers.Get(ctx, ts.server.URL+"/wonderfuls?email="+s, &response)
 	ts.Require().NoError(err)
 	ts.Require().Equal(http.StatusOK, statusCode)
-	ts.Require().Len(response, 0)
+	ts.Require().Len(response.Data, 0)
 	statusCode, err = testhelpers.Get(ctx, ts.server.URL+"/wonderfuls", &response)
 	ts.Require().NoError(err)
 	ts.Require().Equal(http.StatusOK, statusCode)
-	ts.Require().Len(response, 10)
+	ts.Require().Len(response.Data, 10)
 }
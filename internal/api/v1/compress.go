@@ -0,0 +1,300 @@
+package v1
+
+import (
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/andybalholm/brotli"
+	"github.com/klauspost/compress/gzip"
+	"github.com/klauspost/compress/zstd"
+)
+
+// The content codings of the compressed responses.
+const (
+	encodingZstd   = "zstd"
+	encodingBrotli = "br"
+	encodingGzip   = "gzip"
+)
+
+// contentEncodings are the supported content codings, preferred in this order on a tie.
+var contentEncodings = []string{encodingZstd, encodingBrotli, encodingGzip}
+
+// encodingAliases are the other names of the content codings (RFC 9110, section 8.4.1.3).
+var encodingAliases = map[string]string{
+	"x-gzip": encodingGzip,
+}
+
+// encoder is a writer compressing with a content coding.
+type encoder interface {
+	io.WriteCloser
+	Flush() error
+	Reset(w io.Writer)
+}
+
+// encoderPools hold the encoders of the finished responses, which are
+// expensive to create, by content coding.
+var encoderPools = map[string]*sync.Pool{
+	encodingZstd:   {},
+	encodingBrotli: {},
+	encodingGzip:   {},
+}
+
+// getEncoder returns an encoder of the content coding writing to w.
+func getEncoder(encoding string, w io.Writer) (encoder, error) {
+	if e, ok := encoderPools[encoding].Get().(encoder); ok {
+		e.Reset(w)
+		return e, nil
+	}
+	switch encoding {
+	case encodingZstd:
+		e, err := zstd.NewWriter(w, zstd.WithEncoderConcurrency(1))
+		if err != nil {
+			return nil, fmt.Errorf("failed to create the zstd encoder: %w", err)
+		}
+		return e, nil
+	case encodingBrotli:
+		return brotli.NewWriterLevel(w, brotli.DefaultCompression), nil
+	case encodingGzip:
+		e, err := gzip.NewWriterLevel(w, gzip.DefaultCompression)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create the gzip encoder: %w", err)
+		}
+		return e, nil
+	}
+	return nil, fmt.Errorf("unsupported content coding %q", encoding)
+}
+
+// negotiateEncoding returns the supported content coding the Accept-Encoding
+// header prefers, the first one on a tie, or an empty string when none is
+// acceptable (RFC 9110, section 12.5.3). The invalid codings are ignored.
+func negotiateEncoding(acceptEncoding string) string {
+	qualities := make(map[string]float64)
+	wildcard := 0.0
+	for _, part := range strings.Split(acceptEncoding, ",") {
+		params := strings.Split(part, ";")
+		coding := strings.ToLower(strings.TrimSpace(params[0]))
+		if alias, ok := encodingAliases[coding]; ok {
+			coding = alias
+		}
+		q, ok := encodingQuality(params[1:])
+		if !ok {
+			continue
+		}
+		if coding == "*" {
+			wildcard = q
+			continue
+		}
+		qualities[coding] = q
+	}
+	best, bestQ := "", 0.0
+	for _, encoding := range contentEncodings {
+		q, ok := qualities[encoding]
+		if !ok {
+			q = wildcard
+		}
+		if q > bestQ {
+			best, bestQ = encoding, q
+		}
+	}
+	return best
+}
+
+// encodingQuality returns the quality of the parameters of a content coding, 1 without one.
+func encodingQuality(params []string) (float64, bool) {
+	for _, param := range params {
+		name, value, _ := strings.Cut(param, "=")
+		if !strings.EqualFold(strings.TrimSpace(name), "q") {
+			continue
+		}
+		q, err := strconv.ParseFloat(strings.TrimSpace(value), 64)
+		if err != nil || q < 0 || q > 1 {
+			return 0, false
+		}
+		return q, true
+	}
+	return 1, true
+}
+
+// Compression returns a middleware compressing the responses with the content
+// coding the Accept-Encoding header of the request prefers among zstd, br and
+// gzip. The responses smaller than minSize are sent as they are, unless they
+// are flushed before: a flushed response is streamed, its size is unknown.
+// Every response varies with Accept-Encoding. The entity tags of the
+// responses are suffixed with the negotiated content coding, as the
+// compressed representations are not the same as the others, and the suffix
+// is removed from the If-None-Match and If-Match headers of the requests.
+func Compression(minSize int) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			encoding := negotiateEncoding(r.Header.Get("Accept-Encoding"))
+			for _, name := range []string{"If-None-Match", "If-Match"} {
+				if header := r.Header.Get(name); header != "" && encoding != "" {
+					r.Header.Set(name, strings.ReplaceAll(header, "-"+encoding+`"`, `"`))
+				}
+			}
+			cw := &compressWriter{
+				ResponseWriter: w,
+				header:         make(http.Header),
+				encoding:       encoding,
+				minSize:        minSize,
+			}
+			next.ServeHTTP(cw, r)
+			cw.close()
+		})
+	}
+}
+
+// compressWriter compresses a response once minSize bytes of its body are
+// written or it is flushed, unless no content coding is acceptable. The
+// handler writes its own headers, which are copied to the response when it
+// starts: those of the handler stay the ones of the uncompressed response,
+// e.g. for the idempotent replays.
+type compressWriter struct {
+	http.ResponseWriter
+	header http.Header
+	// encoding is the negotiated content coding, empty when none is acceptable.
+	encoding string
+	minSize  int
+
+	status  int
+	buf     []byte
+	started bool
+	// enc is the encoder of the body, nil when it is not compressed.
+	enc encoder
+}
+
+func (cw *compressWriter) Header() http.Header {
+	return cw.header
+}
+
+func (cw *compressWriter) WriteHeader(status int) {
+	if cw.started || cw.status != 0 {
+		return
+	}
+	if status < http.StatusOK {
+		// the informational responses are not the response.
+		copyHeader(cw.ResponseWriter.Header(), cw.header)
+		cw.ResponseWriter.WriteHeader(status)
+		return
+	}
+	cw.status = status
+	if status == http.StatusNoContent || status == http.StatusNotModified {
+		cw.start(false) //nolint:errcheck //no body is written
+	}
+}
+
+func (cw *compressWriter) Write(p []byte) (int, error) {
+	if !cw.started {
+		if cw.status == 0 {
+			cw.status = http.StatusOK
+		}
+		cw.buf = append(cw.buf, p...)
+		if len(cw.buf) < cw.minSize {
+			return len(p), nil
+		}
+		if err := cw.start(true); err != nil {
+			return 0, err
+		}
+		return len(p), nil
+	}
+	if cw.enc != nil {
+		return cw.enc.Write(p) //nolint:wrapcheck //wrapped by the caller
+	}
+	return cw.ResponseWriter.Write(p) //nolint:wrapcheck //wrapped by the caller
+}
+
+// Flush sends the body written so far, compressed, see FlushError.
+func (cw *compressWriter) Flush() {
+	cw.FlushError() //nolint:errcheck //ignore error, http.Flusher has none
+}
+
+// FlushError sends the body written so far, compressed: the encoder writes
+// out the compressed blocks, so that the client can decompress them now.
+func (cw *compressWriter) FlushError() error {
+	if !cw.started {
+		if cw.status == 0 {
+			cw.status = http.StatusOK
+		}
+		if err := cw.start(true); err != nil {
+			return err
+		}
+	}
+	if cw.enc != nil {
+		if err := cw.enc.Flush(); err != nil {
+			return fmt.Errorf("failed to flush the compressed response: %w", err)
+		}
+	}
+	if err := http.NewResponseController(cw.ResponseWriter).Flush(); err != nil {
+		return fmt.Errorf("failed to flush the response: %w", err)
+	}
+	return nil
+}
+
+// Unwrap returns the ResponseWriter, for http.ResponseController.
+func (cw *compressWriter) Unwrap() http.ResponseWriter {
+	return cw.ResponseWriter
+}
+
+// start writes the headers of the response, compressed when compress is set
+// and the handler has not encoded the body itself, and the body buffered so far.
+func (cw *compressWriter) start(compress bool) error {
+	cw.started = true
+	h := cw.ResponseWriter.Header()
+	copyHeader(h, cw.header)
+	h.Add("Vary", "Accept-Encoding")
+	if cw.encoding == "" {
+		compress = false
+	} else if tag := h.Get("ETag"); strings.HasSuffix(tag, `"`) {
+		h.Set("ETag", strings.TrimSuffix(tag, `"`)+"-"+cw.encoding+`"`)
+	}
+	if compress && h.Get("Content-Encoding") == "" {
+		enc, err := getEncoder(cw.encoding, cw.ResponseWriter)
+		if err != nil {
+			slog.Error("failed to compress the response", "encoding", cw.encoding, "error", err)
+		} else {
+			cw.enc = enc
+			h.Set("Content-Encoding", cw.encoding)
+			h.Del("Content-Length")
+		}
+	}
+	cw.ResponseWriter.WriteHeader(cw.status)
+	if len(cw.buf) == 0 {
+		return nil
+	}
+	buf := cw.buf
+	cw.buf = nil
+	if cw.enc != nil {
+		_, err := cw.enc.Write(buf)
+		return err //nolint:wrapcheck //wrapped by the caller
+	}
+	_, err := cw.ResponseWriter.Write(buf)
+	return err //nolint:wrapcheck //wrapped by the caller
+}
+
+// close ends the response, starting it if the handler did not, and returns the encoder to its pool.
+func (cw *compressWriter) close() {
+	if !cw.started {
+		if cw.status == 0 {
+			cw.status = http.StatusOK
+		}
+		cw.start(false) //nolint:errcheck //ignore error
+	}
+	if cw.enc != nil {
+		cw.enc.Close() //nolint:errcheck //ignore error
+		cw.enc.Reset(nil)
+		encoderPools[cw.encoding].Put(cw.enc)
+		cw.enc = nil
+	}
+}
+
+// copyHeader adds the values of src to dst, replacing the ones of the same keys.
+func copyHeader(dst, src http.Header) {
+	for k, v := range src {
+		dst[k] = v
+	}
+}
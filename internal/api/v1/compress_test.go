@@ -0,0 +1,121 @@
+package v1_test
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	api "wonderful/internal/api/v1"
+
+	"github.com/andybalholm/brotli"
+	"github.com/klauspost/compress/gzip"
+	"github.com/klauspost/compress/zstd"
+	"github.com/stretchr/testify/require"
+)
+
+// compressedGet gets a path of the server with the Accept-Encoding header,
+// the client does not decompress the response.
+func compressedGet(t *testing.T, server *httptest.Server, path, acceptEncoding string, headers ...string) *http.Response {
+	t.Helper()
+	req, err := http.NewRequestWithContext(context.Background(), http.MethodGet, server.URL+path, http.NoBody)
+	require.NoError(t, err)
+	req.Header.Set("Accept-Encoding", acceptEncoding)
+	for i := 0; i+1 < len(headers); i += 2 {
+		req.Header.Set(headers[i], headers[i+1])
+	}
+	res, err := server.Client().Do(req)
+	require.NoError(t, err)
+	t.Cleanup(func() { res.Body.Close() })
+	return res
+}
+
+func TestCompression(t *testing.T) {
+	large := strings.Repeat(`{"name": "Mr. John Doe"}`, 100)
+	r := http.NewServeMux()
+	r.HandleFunc("/large", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("ETag", `"v1"`)
+		if r.Header.Get("If-None-Match") == `"v1"` {
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		w.Header().Set("Vary", "Accept")
+		w.Write([]byte(large)) //nolint:errcheck //ignore error
+	})
+	r.HandleFunc("/small", func(w http.ResponseWriter, _ *http.Request) {
+		w.Write([]byte(`{"name": "Mr. John Doe"}`)) //nolint:errcheck //ignore error
+	})
+	server := httptest.NewServer(api.Compression(1024)(r))
+	defer server.Close()
+	server.Client().Transport.(*http.Transport).DisableCompression = true //nolint:forcetypeassert //httptest transport
+
+	decoders := map[string]func(io.Reader) (io.Reader, error){
+		"zstd": func(r io.Reader) (io.Reader, error) { return zstd.NewReader(r) },
+		"br":   func(r io.Reader) (io.Reader, error) { return brotli.NewReader(r), nil },
+		"gzip": func(r io.Reader) (io.Reader, error) { return gzip.NewReader(r) },
+	}
+	for acceptEncoding, encoding := range map[string]string{
+		"gzip, deflate, br, zstd":    "zstd",
+		"gzip;q=0.5, br;q=0.8":       "br",
+		"x-gzip":                     "gzip",
+		"*;q=0.1, zstd;q=0, br; q=0": "gzip",
+	} {
+		res := compressedGet(t, server, "/large", acceptEncoding)
+		require.Equal(t, http.StatusOK, res.StatusCode)
+		require.Equal(t, encoding, res.Header.Get("Content-Encoding"), acceptEncoding)
+		require.Equal(t, []string{"Accept", "Accept-Encoding"}, res.Header.Values("Vary"))
+		require.Equal(t, `"v1-`+encoding+`"`, res.Header.Get("ETag"))
+		body, err := decoders[encoding](res.Body)
+		require.NoError(t, err)
+		b, err := io.ReadAll(body)
+		require.NoError(t, err)
+		require.Equal(t, large, string(b))
+	}
+
+	// the tag of the compressed representation is the one of the others for the handler
+	res := compressedGet(t, server, "/large", "gzip", "If-None-Match", `"v1-gzip"`)
+	require.Equal(t, http.StatusNotModified, res.StatusCode)
+	require.Equal(t, `"v1-gzip"`, res.Header.Get("ETag"))
+	res = compressedGet(t, server, "/large", "gzip", "If-None-Match", `"v1-zstd"`)
+	require.Equal(t, http.StatusOK, res.StatusCode)
+
+	// the small responses and the ones accepting no coding are not compressed
+	for path, acceptEncoding := range map[string]string{"/small": "gzip", "/large": "deflate, gzip;q=0"} {
+		res = compressedGet(t, server, path, acceptEncoding)
+		require.Equal(t, http.StatusOK, res.StatusCode)
+		require.Empty(t, res.Header.Get("Content-Encoding"))
+		require.Contains(t, res.Header.Values("Vary"), "Accept-Encoding")
+		b, err := io.ReadAll(res.Body)
+		require.NoError(t, err)
+		require.Contains(t, string(b), "Mr. John Doe")
+	}
+}
+
+func TestCompressionFlush(t *testing.T) {
+	sent := make(chan struct{})
+	r := http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.Write([]byte("{\"name\": \"Mr. John Doe\"}\n")) //nolint:errcheck //ignore error
+		require.NoError(t, http.NewResponseController(w).Flush())
+		// the handler waits until the client read the flushed line
+		<-sent
+		w.Write([]byte("{\"name\": \"Mrs. Jane Doe\"}\n")) //nolint:errcheck //ignore error
+	})
+	server := httptest.NewServer(api.Compression(1024)(r))
+	defer server.Close()
+	server.Client().Transport.(*http.Transport).DisableCompression = true //nolint:forcetypeassert //httptest transport
+
+	res := compressedGet(t, server, "/", "gzip")
+	require.Equal(t, "gzip", res.Header.Get("Content-Encoding"))
+	body, err := gzip.NewReader(res.Body)
+	require.NoError(t, err)
+	line := make([]byte, len("{\"name\": \"Mr. John Doe\"}\n"))
+	_, err = io.ReadFull(body, line)
+	require.NoError(t, err)
+	require.Equal(t, "{\"name\": \"Mr. John Doe\"}\n", string(line))
+	close(sent)
+	rest, err := io.ReadAll(body)
+	require.NoError(t, err)
+	require.Equal(t, "{\"name\": \"Mrs. Jane Doe\"}\n", string(rest))
+}
@@ -0,0 +1,312 @@
+package v1
+
+import (
+	"time"
+
+	"wonderful/internal/api/v1/openapi"
+	"wonderful/internal/entities"
+	"wonderful/internal/service"
+)
+
+// toOpenAPIUser converts an entities user to the API representation.
+func toOpenAPIUser(user *entities.User) openapi.User {
+	picLarge := user.Picture["large"]
+	picMedium := user.Picture["medium"]
+	picThumbnail := user.Picture["thumbnail"]
+	cellPhone := user.Cell
+	mainPhone := user.Phone
+	return openapi.User{
+		Email: user.Email,
+		Id:    user.ID,
+		Name:  user.Name,
+		Phone: &openapi.Phone{
+			Cell: &cellPhone,
+			Main: &mainPhone,
+		},
+		Picture: &openapi.Picture{
+			Large:     &picLarge,
+			Medium:    &picMedium,
+			Thumbnail: &picThumbnail,
+		},
+		RegistrationDate: user.Registration,
+		CreatedAt:        user.CreatedAt,
+		UpdatedAt:        user.UpdatedAt,
+		DeletedAt:        user.DeletedAt,
+		Gender:           optional(user.Gender),
+		Title:            optional(user.Title),
+		FirstName:        optional(user.FirstName),
+		LastName:         optional(user.LastName),
+		DateOfBirth:      user.DateOfBirth,
+		Nat:              optional(user.Nat),
+		NationalId:       toOpenAPINationalID(user.NationalID),
+		Location:         toOpenAPILocation(user.Location),
+	}
+}
+
+// toOpenAPINationalID converts a national ID to the API representation.
+func toOpenAPINationalID(id *entities.NationalID) *openapi.NationalID {
+	if id == nil {
+		return nil
+	}
+	return &openapi.NationalID{Name: id.Name, Value: id.Value}
+}
+
+// toOpenAPILocation converts a location to the API representation.
+func toOpenAPILocation(l *entities.Location) *openapi.Location {
+	if l == nil {
+		return nil
+	}
+	return &openapi.Location{
+		StreetNumber: optional(l.StreetNumber),
+		StreetName:   optional(l.StreetName),
+		City:         optional(l.City),
+		State:        optional(l.State),
+		Country:      optional(l.Country),
+		Postcode:     optional(l.Postcode),
+		Latitude:     l.Latitude,
+		Longitude:    l.Longitude,
+	}
+}
+
+// toOpenAPIUserList converts a page of users to the API representation.
+func toOpenAPIUserList(page *entities.UserPage) openapi.UserList {
+	data := make([]openapi.User, 0, len(page.Users))
+	for i := range page.Users {
+		data = append(data, toOpenAPIUser(&page.Users[i]))
+	}
+	return openapi.UserList{
+		Data:       data,
+		HasMore:    page.HasMore,
+		NextCursor: optional(page.NextCursor),
+		PrevCursor: optional(page.PrevCursor),
+		TotalCount: page.TotalCount,
+	}
+}
+
+// toOpenAPISearchResults converts a page of search results to the API representation.
+func toOpenAPISearchResults(page *entities.SearchPage) openapi.SearchResults {
+	data := make([]openapi.SearchResult, 0, len(page.Results))
+	for i := range page.Results {
+		res := &page.Results[i]
+		data = append(data, openapi.SearchResult{
+			User: toOpenAPIUser(&res.User),
+			Rank: res.Rank,
+			Highlights: openapi.SearchHighlights{
+				Name:     res.NameHeadline,
+				Email:    res.EmailHeadline,
+				Location: optional(res.LocationHeadline),
+			},
+		})
+	}
+	return openapi.SearchResults{
+		Data:       data,
+		HasMore:    page.HasMore,
+		NextCursor: optional(page.NextCursor),
+	}
+}
+
+// toOpenAPIImportReport converts the outcome of an import to the API representation.
+func toOpenAPIImportReport(report *entities.ImportReport) openapi.ImportReport {
+	errs := make([]openapi.ImportError, 0, len(report.Errors))
+	for _, e := range report.Errors {
+		errs = append(errs, openapi.ImportError{Line: e.Line, Field: optional(e.Field), Reason: e.Reason})
+	}
+	return openapi.ImportReport{
+		Inserted: report.Inserted,
+		Updated:  report.Updated,
+		Skipped:  report.Skipped,
+		Failed:   report.Failed,
+		Errors:   errs,
+	}
+}
+
+// fromOpenAPIUserInput converts a create or replace request body to an entities user.
+func fromOpenAPIUserInput(in *openapi.UserInput) entities.User {
+	u := entities.User{
+		Name:    in.Name,
+		Email:   in.Email,
+		Picture: fromOpenAPIPicture(in.Picture),
+	}
+	if in.Phone != nil {
+		u.Phone = deref(in.Phone.Main)
+		u.Cell = deref(in.Phone.Cell)
+	}
+	if in.RegistrationDate != nil {
+		u.Registration = in.RegistrationDate.UTC()
+	}
+	return u
+}
+
+// fromOpenAPIUserPatch converts a partial update request body to an entities patch.
+func fromOpenAPIUserPatch(in *openapi.UserPatch) entities.UserPatch {
+	p := entities.UserPatch{
+		Name:    in.Name,
+		Email:   in.Email,
+		Picture: fromOpenAPIPicture(in.Picture),
+	}
+	if in.Phone != nil {
+		p.Phone = in.Phone.Main
+		p.Cell = in.Phone.Cell
+	}
+	if in.RegistrationDate != nil {
+		registration := in.RegistrationDate.UTC()
+		p.Registration = &registration
+	}
+	return p
+}
+
+// fromOpenAPIPicture converts the picture object to a map holding only the given sizes.
+func fromOpenAPIPicture(in *openapi.Picture) map[string]string {
+	if in == nil {
+		return nil
+	}
+	picture := make(map[string]string, 3)
+	if in.Large != nil {
+		picture["large"] = *in.Large
+	}
+	if in.Medium != nil {
+		picture["medium"] = *in.Medium
+	}
+	if in.Thumbnail != nil {
+		picture["thumbnail"] = *in.Thumbnail
+	}
+	return picture
+}
+
+func deref[T any](p *T) T {
+	var zero T
+	if p == nil {
+		return zero
+	}
+	return *p
+}
+
+// optional returns a pointer to v, or nil if v is the zero value.
+func optional[T comparable](v T) *T {
+	var zero T
+	if v == zero {
+		return nil
+	}
+	return &v
+}
+
+// utc returns t in UTC, or nil if t is nil. The timestamps are stored without
+// their offset, the bounds compared to them must be in UTC.
+func utc(t *time.Time) *time.Time {
+	if t == nil {
+		return nil
+	}
+	u := t.UTC()
+	return &u
+}
+
+// toOpenAPIJob converts an entities job to the API representation.
+func toOpenAPIJob(job *entities.Job) openapi.Job {
+	j := openapi.Job{
+		Id:         job.ID,
+		State:      openapi.JobState(job.State),
+		Fetched:    int32(job.Fetched),
+		Inserted:   int32(job.Inserted),
+		Updated:    int32(job.Updated),
+		Skipped:    int32(job.Skipped),
+		CreatedAt:  job.CreatedAt,
+		UpdatedAt:  job.UpdatedAt,
+		FinishedAt: job.FinishedAt,
+		Options:    toOpenAPIPopulateRequest(job.Options),
+	}
+	if job.Error != "" {
+		j.Error = &job.Error
+	}
+	return j
+}
+
+// fromOpenAPIListFilters converts the filters of a user listing to the service representation.
+func fromOpenAPIListFilters(params *openapi.GetWonderfulsParams) service.ListFilters {
+	return service.ListFilters{
+		Email:            params.Email,
+		Name:             params.Name,
+		Query:            params.Q,
+		Phone:            params.Phone,
+		Cell:             params.Cell,
+		Nat:              optional(string(deref(params.Nat))),
+		Gender:           optional(string(deref(params.Gender))),
+		RegisteredAfter:  utc(params.RegisteredAfter),
+		RegisteredBefore: utc(params.RegisteredBefore),
+		IncludeDeleted:   deref(params.IncludeDeleted),
+	}
+}
+
+// fromOpenAPIExportFilters converts the query parameters of an export to the service filters.
+func fromOpenAPIExportFilters(params *openapi.ExportWonderfulsParams) service.ListFilters {
+	return service.ListFilters{
+		Email:            params.Email,
+		Name:             params.Name,
+		Query:            params.Q,
+		Phone:            params.Phone,
+		Cell:             params.Cell,
+		Nat:              optional(string(deref(params.Nat))),
+		Gender:           optional(string(deref(params.Gender))),
+		RegisteredAfter:  utc(params.RegisteredAfter),
+		RegisteredBefore: utc(params.RegisteredBefore),
+		IncludeDeleted:   deref(params.IncludeDeleted),
+	}
+}
+
+// fromOpenAPISearchParams converts the query parameters of a search to the service parameters.
+func fromOpenAPISearchParams(params *openapi.SearchWonderfulsParams) service.SearchParams {
+	return service.SearchParams{
+		Query:          params.Q,
+		StartingAfter:  deref(params.StartingAfter),
+		Limit:          deref(params.Limit),
+		IncludeDeleted: deref(params.IncludeDeleted),
+	}
+}
+
+// toOpenAPIPopulateRequest converts the options of a populate job to the API representation.
+func toOpenAPIPopulateRequest(opts entities.PopulateOptions) openapi.PopulateRequest {
+	var out openapi.PopulateRequest
+	if opts.Count != 0 {
+		count := opts.Count
+		out.Count = &count
+	}
+	if opts.Seed != "" {
+		seed := opts.Seed
+		out.Seed = &seed
+	}
+	if len(opts.Nat) > 0 {
+		nat := make([]openapi.Nationality, 0, len(opts.Nat))
+		for _, n := range opts.Nat {
+			nat = append(nat, openapi.Nationality(n))
+		}
+		out.Nat = &nat
+	}
+	if opts.Gender != "" {
+		gender := openapi.PopulateRequestGender(opts.Gender)
+		out.Gender = &gender
+	}
+	if opts.Source != "" {
+		source := openapi.PopulateRequestSource(opts.Source)
+		out.Source = &source
+	}
+	return out
+}
+
+// fromOpenAPIPopulateRequest converts a populate request body to entities options.
+func fromOpenAPIPopulateRequest(in *openapi.PopulateRequest) entities.PopulateOptions {
+	opts := entities.PopulateOptions{
+		Count: deref(in.Count),
+		Seed:  deref(in.Seed),
+	}
+	if in.Nat != nil {
+		for _, n := range *in.Nat {
+			opts.Nat = append(opts.Nat, string(n))
+		}
+	}
+	if in.Gender != nil {
+		opts.Gender = string(*in.Gender)
+	}
+	if in.Source != nil {
+		opts.Source = string(*in.Source)
+	}
+	return opts
+}
@@ -1,22 +1,95 @@
 package v1
 
 import (
-	"context"
 	"encoding/json"
+	"errors"
 	"log/slog"
 	"net/http"
 
+	"github.com/getkin/kin-openapi/openapi3"
+
 	"wonderful/internal/api/v1/openapi"
+	"wonderful/internal/service"
+)
+
+// mediaTypeProblem is the media type of the errors (RFC 9457).
+const mediaTypeProblem = "application/problem+json"
+
+// problemTypePrefix prefixes the error code of a problem in its type URI.
+const problemTypePrefix = "urn:wonderful:problem:"
+
+// problem is a kind of error of the API: its stable error code, and the title
+// and status of its responses.
+type problem struct {
+	code   openapi.ProblemErrorCode
+	title  string
+	status int
+}
+
+// The catalogue of the errors of the API, see the Problem schema of the spec.
+var (
+	problemInvalidParameter         = problem{openapi.ProblemErrorCodeInvalidParameter, "Invalid parameter", http.StatusBadRequest}
+	problemInvalidCursor            = problem{openapi.ProblemErrorCodeInvalidCursor, "Invalid cursor", http.StatusBadRequest}
+	problemInvalidBody              = problem{openapi.ProblemErrorCodeInvalidBody, "Invalid request body", http.StatusBadRequest}
+	problemInvalidIdempotencyKey    = problem{openapi.ProblemErrorCodeInvalidIdempotencyKey, "Invalid idempotency key", http.StatusBadRequest}
+	problemIdempotencyKeyReused     = problem{openapi.ProblemErrorCodeIdempotencyKeyReused, "Idempotency key reused", http.StatusUnprocessableEntity}
+	problemIdempotencyKeyInProgress = problem{openapi.ProblemErrorCodeIdempotencyKeyInProgress, "Idempotency key in progress", http.StatusConflict}
+	problemUserNotFound             = problem{openapi.ProblemErrorCodeUserNotFound, "User not found", http.StatusNotFound}
+	problemUserNotDeleted           = problem{openapi.ProblemErrorCodeUserNotDeleted, "User is not deleted", http.StatusConflict}
+	problemJobNotFound              = problem{openapi.ProblemErrorCodeJobNotFound, "Job not found", http.StatusNotFound}
+	problemNotFound                 = problem{openapi.ProblemErrorCodeNotFound, "Not found", http.StatusNotFound}
+	problemMethodNotAllowed         = problem{openapi.ProblemErrorCodeMethodNotAllowed, "Method not allowed", http.StatusMethodNotAllowed}
+	problemPreconditionFailed       = problem{openapi.ProblemErrorCodePreconditionFailed, "Precondition failed", http.StatusPreconditionFailed}
+	problemNotAcceptable            = problem{openapi.ProblemErrorCodeNotAcceptable, "Not acceptable", http.StatusNotAcceptable}
+	problemUnsupportedMediaType     = problem{openapi.ProblemErrorCodeUnsupportedMediaType, "Unsupported media type", http.StatusUnsupportedMediaType}
+	problemShuttingDown             = problem{openapi.ProblemErrorCodeShuttingDown, "Server is shutting down", http.StatusServiceUnavailable}
+	problemUpstreamUnavailable      = problem{openapi.ProblemErrorCodeUpstreamUnavailable, "Random user API is unavailable", http.StatusServiceUnavailable}
+	problemInternal                 = problem{openapi.ProblemErrorCodeInternalError, "Internal server error", http.StatusInternalServerError}
 )
 
-// This function wraps sending of an error in the Error format, and
-// handling the failure to marshal that.
-func sendAPIError(_ context.Context, w http.ResponseWriter, code int, message string, err error) {
-	slog.Error(message, "error", err)
-	apiErr := openapi.Error{
-		Code:    int32(code),
-		Message: message,
+// This function wraps sending of an error as problem details, with the detail
+// of this occurrence, the fields and parameters the error is about, see
+// service.FieldError, and handling the failure to marshal that.
+func sendAPIError(w http.ResponseWriter, r *http.Request, p problem, detail string, err error) {
+	slog.Error(detail, "error_code", p.code, "error", err)
+	apiErr := openapi.Problem{
+		Type:      problemTypePrefix + string(p.code),
+		Title:     p.title,
+		Status:    p.status,
+		Detail:    detail,
+		Instance:  requestURI(r),
+		ErrorCode: p.code,
+		Errors:    fieldErrors(err),
 	}
-	w.WriteHeader(code)
+	w.Header().Set("Content-Type", mediaTypeProblem)
+	w.WriteHeader(p.status)
 	json.NewEncoder(w).Encode(apiErr) //nolint:errcheck //ignore error
 }
+
+// fieldErrors returns the fields and parameters of the service.FieldError
+// wrapped by err, or by the errors it joins, and of the errors of the
+// validation of the request, see requestFieldErrors, or nil when there are none.
+func fieldErrors(err error) *[]openapi.FieldError {
+	errs := []error{err}
+	var joined interface{ Unwrap() []error }
+	if errors.As(err, &joined) {
+		errs = joined.Unwrap()
+	}
+	// the validator returns all the errors of a request unwrapped.
+	if multi, ok := err.(openapi3.MultiError); ok { //nolint:errorlint //not wrapped
+		errs = multi
+	}
+	var fields []openapi.FieldError
+	for _, e := range errs {
+		var fe *service.FieldError
+		if errors.As(e, &fe) {
+			fields = append(fields, openapi.FieldError{Field: fe.Field, Reason: fe.Reason})
+			continue
+		}
+		fields = append(fields, requestFieldErrors(e)...)
+	}
+	if len(fields) == 0 {
+		return nil
+	}
+	return &fields
+}
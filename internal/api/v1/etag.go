@@ -0,0 +1,115 @@
+package v1
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"hash"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"wonderful/internal/entities"
+	"wonderful/internal/service"
+)
+
+// userETag returns the strong entity tag of a representation of a user. It is
+// a digest of the ID of the user and of the times it was created, updated and
+// deleted, one of which changes with every write, and of the media type, as
+// the representations of a user have different tags.
+func userETag(u *entities.User, mediaType string) string {
+	h := sha256.New()
+	writeETagPart(h, mediaType)
+	writeUserVersion(h, u)
+	return etag(h)
+}
+
+// listETag returns the strong entity tag of a representation of a page of
+// users, a digest of the versions of its users, see userETag, and of the
+// other parts of the page.
+func listETag(page *entities.UserPage, mediaType string, fields []string) string {
+	h := sha256.New()
+	writeETagPart(h, mediaType)
+	writeETagPart(h, strings.Join(fields, ","))
+	for i := range page.Users {
+		writeUserVersion(h, &page.Users[i])
+	}
+	writeETagPart(h, strconv.FormatBool(page.HasMore))
+	writeETagPart(h, page.NextCursor)
+	writeETagPart(h, page.PrevCursor)
+	if page.TotalCount != nil {
+		writeETagPart(h, strconv.FormatInt(*page.TotalCount, 10))
+	}
+	return etag(h)
+}
+
+func writeUserVersion(h hash.Hash, u *entities.User) {
+	formatTime := func(t *time.Time) string {
+		if t == nil {
+			return ""
+		}
+		return t.UTC().Format(time.RFC3339Nano)
+	}
+	writeETagPart(h, u.ID)
+	writeETagPart(h, formatTime(&u.CreatedAt))
+	writeETagPart(h, formatTime(u.UpdatedAt))
+	writeETagPart(h, formatTime(u.DeletedAt))
+}
+
+// writeETagPart writes a part of an entity tag, terminated so that the parts
+// can not run into each other.
+func writeETagPart(h hash.Hash, part string) {
+	h.Write([]byte(part)) //nolint:errcheck //a hash never fails to write
+	h.Write([]byte{0})    //nolint:errcheck //a hash never fails to write
+}
+
+func etag(h hash.Hash) string {
+	return `"` + hex.EncodeToString(h.Sum(nil)[:16]) + `"`
+}
+
+// notModified sets the ETag header of a response and answers 304 Not Modified
+// when the If-None-Match header of the request holds the tag. It returns
+// whether the response is done.
+func notModified(w http.ResponseWriter, r *http.Request, tag string) bool {
+	w.Header().Set("ETag", tag)
+	ifNoneMatch := r.Header.Get("If-None-Match")
+	if ifNoneMatch == "" || !matchETag(ifNoneMatch, tag, false) {
+		return false
+	}
+	w.WriteHeader(http.StatusNotModified)
+	return true
+}
+
+// ifMatch returns the precondition of the If-Match header of a request on the
+// representation of a user of the media type, or nil without the header.
+func ifMatch(r *http.Request, mediaType string) service.Precondition {
+	header := r.Header.Get("If-Match")
+	if header == "" {
+		return nil
+	}
+	return func(current *entities.User) bool {
+		return matchETag(header, userETag(current, mediaType), true)
+	}
+}
+
+// matchETag returns whether the list of entity tags of an If-Match or
+// If-None-Match header holds the tag, or is "*". The strong comparison, used
+// by If-Match, never matches the weak tags (RFC 9110, section 8.8.3.2).
+func matchETag(header, tag string, strong bool) bool {
+	if strings.TrimSpace(header) == "*" {
+		return true
+	}
+	for _, candidate := range strings.Split(header, ",") {
+		candidate = strings.TrimSpace(candidate)
+		if weak, ok := strings.CutPrefix(candidate, "W/"); ok {
+			if strong {
+				continue
+			}
+			candidate = weak
+		}
+		if candidate == tag {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,157 @@
+package v1
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"slices"
+	"strconv"
+	"time"
+
+	"wonderful/internal/api/v1/openapi"
+	"wonderful/internal/entities"
+)
+
+// exportFlushRows is the number of users written between two flushes of an export.
+const exportFlushRows = 100
+
+// csvHeader are the columns of the users as CSV, the nested fields are flattened.
+var csvHeader = []string{
+	"id", "name", "email", "phone", "cell", "picture_large", "picture_medium", "picture_thumbnail",
+	"registration_date", "created_at", "updated_at", "deleted_at",
+	"gender", "title", "first_name", "last_name", "date_of_birth", "nat", "national_id_name", "national_id_value",
+	"street_number", "street_name", "city", "state", "country", "postcode", "latitude", "longitude",
+}
+
+// exportHeader are the columns of a CSV export: the ones of csvHeader with the
+// external ID after the ID, which the import matches the users by.
+var exportHeader = slices.Insert(slices.Clone(csvHeader), 1, "external_id")
+
+// exportedUser is an NDJSON line of an export, a user with its external ID.
+type exportedUser struct {
+	openapi.User
+	ExternalID string `json:"external_id,omitempty"`
+}
+
+// exportFormat returns the format of an export: the format parameter, or else
+// the one the Accept header prefers, NDJSON on a tie.
+func exportFormat(r *http.Request, format *openapi.ExportWonderfulsParamsFormat) openapi.ExportWonderfulsParamsFormat {
+	if format != nil {
+		return *format
+	}
+	if negotiate(r, "application/x-ndjson", "text/csv") == "text/csv" {
+		return openapi.ExportWonderfulsParamsFormatCsv
+	}
+	return openapi.ExportWonderfulsParamsFormatNdjson
+}
+
+// exportWriter writes the users of an export as they are read. The response
+// starts with the first user, or when the export is closed if there is none,
+// so that the errors before it can still be sent as an API error.
+type exportWriter struct {
+	w       http.ResponseWriter
+	rc      *http.ResponseController
+	format  openapi.ExportWonderfulsParamsFormat
+	csv     *csv.Writer
+	json    *json.Encoder
+	started bool
+	rows    int
+}
+
+func newExportWriter(w http.ResponseWriter, format openapi.ExportWonderfulsParamsFormat) *exportWriter {
+	return &exportWriter{
+		w:      w,
+		rc:     http.NewResponseController(w),
+		format: format,
+		csv:    csv.NewWriter(w),
+		json:   json.NewEncoder(w),
+	}
+}
+
+// start writes the headers of the response, and the header row of a CSV export.
+func (e *exportWriter) start() error {
+	e.started = true
+	if e.format == openapi.ExportWonderfulsParamsFormatCsv {
+		e.w.Header().Set("Content-Type", "text/csv; charset=utf-8")
+		e.w.Header().Set("Content-Disposition", `attachment; filename="wonderfuls.csv"`)
+		e.w.WriteHeader(http.StatusOK)
+		return e.csv.Write(exportHeader) //nolint:wrapcheck //wrapped by the caller
+	}
+	e.w.Header().Set("Content-Type", "application/x-ndjson")
+	e.w.Header().Set("Content-Disposition", `attachment; filename="wonderfuls.ndjson"`)
+	e.w.WriteHeader(http.StatusOK)
+	return nil
+}
+
+// write writes a user, flushing the response every exportFlushRows users.
+func (e *exportWriter) write(u *entities.User) error {
+	if !e.started {
+		if err := e.start(); err != nil {
+			return fmt.Errorf("failed to write the export: %w", err)
+		}
+	}
+	var err error
+	if e.format == openapi.ExportWonderfulsParamsFormatCsv {
+		err = e.csv.Write(slices.Insert(csvRecord(u), 1, u.ExternalID))
+	} else {
+		err = e.json.Encode(exportedUser{User: toOpenAPIUser(u), ExternalID: u.ExternalID})
+	}
+	if err != nil {
+		return fmt.Errorf("failed to write the export: %w", err)
+	}
+	e.rows++
+	if e.rows%exportFlushRows == 0 {
+		return e.flush()
+	}
+	return nil
+}
+
+// flush sends the users written so far to the client.
+func (e *exportWriter) flush() error {
+	e.csv.Flush()
+	if err := e.csv.Error(); err != nil {
+		return fmt.Errorf("failed to write the export: %w", err)
+	}
+	if err := e.rc.Flush(); err != nil {
+		return fmt.Errorf("failed to flush the export: %w", err)
+	}
+	return nil
+}
+
+// close ends the export, starting the response if no user was written.
+func (e *exportWriter) close() error {
+	if !e.started {
+		if err := e.start(); err != nil {
+			return fmt.Errorf("failed to write the export: %w", err)
+		}
+	}
+	return e.flush()
+}
+
+// csvRecord returns the row of a user as CSV, see csvHeader.
+func csvRecord(u *entities.User) []string {
+	formatTime := func(t *time.Time) string {
+		if t == nil {
+			return ""
+		}
+		return t.Format(time.RFC3339Nano)
+	}
+	record := []string{
+		u.ID, u.Name, u.Email, u.Phone, u.Cell, u.Picture["large"], u.Picture["medium"], u.Picture["thumbnail"],
+		formatTime(&u.Registration), formatTime(&u.CreatedAt), formatTime(u.UpdatedAt), formatTime(u.DeletedAt),
+		u.Gender, u.Title, u.FirstName, u.LastName, formatTime(u.DateOfBirth), u.Nat,
+	}
+	if u.NationalID != nil {
+		record = append(record, u.NationalID.Name, u.NationalID.Value)
+	} else {
+		record = append(record, "", "")
+	}
+	if l := u.Location; l != nil {
+		record = append(record, strconv.Itoa(l.StreetNumber), l.StreetName, l.City, l.State, l.Country, l.Postcode,
+			strconv.FormatFloat(l.Latitude, 'f', -1, 64), strconv.FormatFloat(l.Longitude, 'f', -1, 64))
+	} else {
+		record = append(record, "", "", "", "", "", "", "", "")
+	}
+	return record
+}
@@ -0,0 +1,83 @@
+package v1
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"wonderful/internal/api/v1/openapi"
+	"wonderful/internal/entities"
+)
+
+// sparseUserList is a page of users restricted to some of their fields. The
+// users are only read from the database with these fields, see
+// service.ParseFields, and the other fields are left out here. Its Data
+// shadows the one of the UserList.
+type sparseUserList struct {
+	openapi.UserList
+	Data []map[string]any `json:"data"`
+}
+
+// toSparseUserList converts a page of users to the API representation with only the given fields.
+func toSparseUserList(page *entities.UserPage, fields []string) (sparseUserList, error) {
+	list := sparseUserList{UserList: toOpenAPIUserList(page)}
+	list.Data = make([]map[string]any, 0, len(list.UserList.Data))
+	for i := range list.UserList.Data {
+		u, err := sparseUser(&list.UserList.Data[i], fields)
+		if err != nil {
+			return list, err
+		}
+		list.Data = append(list.Data, u)
+	}
+	return list, nil
+}
+
+// sparseUser returns the given fields of a user, and its ID. A field of a
+// nested object, e.g. "picture.thumbnail", only keeps that part of it.
+func sparseUser(user *openapi.User, fields []string) (map[string]any, error) {
+	var all map[string]json.RawMessage
+	if err := marshalTo(user, &all); err != nil {
+		return nil, err
+	}
+	sparse := map[string]any{"id": user.Id}
+	for _, f := range fields {
+		name, part, nested := strings.Cut(f, ".")
+		value, ok := all[name]
+		if !ok {
+			continue
+		}
+		if !nested {
+			sparse[name] = value
+			continue
+		}
+		parts, ok := sparse[name].(map[string]json.RawMessage)
+		if !ok {
+			if _, whole := sparse[name]; whole {
+				// the whole object is already returned
+				continue
+			}
+			parts = map[string]json.RawMessage{}
+			sparse[name] = parts
+		}
+		var object map[string]json.RawMessage
+		if err := json.Unmarshal(value, &object); err != nil {
+			return nil, fmt.Errorf("failed to read the %s field: %w", name, err)
+		}
+		if v, ok := object[part]; ok {
+			parts[part] = v
+		}
+	}
+	return sparse, nil
+}
+
+// marshalTo converts v to out through its JSON representation.
+func marshalTo(v, out any) error {
+	b, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Errorf("failed to marshal: %w", err)
+	}
+	if err := json.Unmarshal(b, out); err != nil {
+		return fmt.Errorf("failed to unmarshal: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,151 @@
+package v1
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"io"
+	"log/slog"
+	"net/http"
+	"strings"
+
+	"wonderful/internal/entities"
+	"wonderful/internal/service"
+
+	"github.com/go-chi/chi/v5/middleware"
+)
+
+const (
+	// IdempotencyKeyHeader is the request header holding the idempotency key.
+	IdempotencyKeyHeader = "Idempotency-Key"
+	// idempotentReplayedHeader marks the responses replayed from a previous request.
+	idempotentReplayedHeader = "Idempotent-Replayed"
+	// maxIdempotencyKeyLength is the length of the idempotency_keys.key column.
+	maxIdempotencyKeyLength = 255
+)
+
+// streamedBodyPaths are the paths of the requests whose body is read as it is
+// uploaded. Their body would have to be read whole to fingerprint the request
+// before it runs, so they can not be made idempotent.
+var streamedBodyPaths = map[string]bool{
+	"/wonderfuls/import": true,
+}
+
+// Idempotency returns a middleware that makes POST, PUT, PATCH and DELETE
+// requests with an Idempotency-Key header safe to retry. The first response
+// for a key is recorded and replayed for the repeated requests. Reusing a key
+// for a different request is rejected with 422, and repeating it while the
+// first request still runs with 409. Server errors and panics are not
+// recorded, so that the request can be retried. The requests with a streamed
+// body are rejected with 400, see streamedBodyPaths.
+func Idempotency(idempotencyService service.IdempotencyService) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			key := r.Header.Get(IdempotencyKeyHeader)
+			if key == "" || !isMutating(r.Method) {
+				next.ServeHTTP(w, r)
+				return
+			}
+			ctx := r.Context()
+			if len(key) > maxIdempotencyKeyLength {
+				sendAPIError(w, r, problemInvalidIdempotencyKey, "Idempotency key is too long", errors.New("idempotency key is too long"))
+				return
+			}
+			if streamedBodyPaths[strings.TrimSuffix(r.URL.Path, "/")] {
+				sendAPIError(w, r, problemInvalidIdempotencyKey, "Idempotency keys are not supported by this endpoint, its body is streamed",
+					errors.New("idempotency key on a streamed body"))
+				return
+			}
+
+			body, err := io.ReadAll(r.Body)
+			if err != nil {
+				sendAPIError(w, r, problemInvalidBody, "Invalid request body", err)
+				return
+			}
+			r.Body = io.NopCloser(bytes.NewReader(body))
+
+			recorded, err := idempotencyService.Begin(ctx, key, requestFingerprint(r, body))
+			if err != nil {
+				switch {
+				case errors.Is(err, service.ErrIdempotencyKeyMismatch):
+					sendAPIError(w, r, problemIdempotencyKeyReused, "Idempotency key was used for a different request", err)
+				case errors.Is(err, service.ErrIdempotencyKeyInProgress):
+					sendAPIError(w, r, problemIdempotencyKeyInProgress, "Request with the same idempotency key is in progress", err)
+				default:
+					sendAPIError(w, r, problemInternal, "Error checking idempotency key", err)
+				}
+				return
+			}
+			if recorded != nil {
+				replay(w, recorded)
+				return
+			}
+
+			// the key must be released or recorded even if the client went away,
+			// it is released unless the response is recorded, also on a panic.
+			ctx = context.WithoutCancel(ctx)
+			completed := false
+			defer func() {
+				if completed {
+					return
+				}
+				if err := idempotencyService.Abandon(ctx, key); err != nil {
+					slog.Error("failed to abandon idempotency key", "key", key, "error", err)
+				}
+			}()
+
+			var buf bytes.Buffer
+			ww := middleware.NewWrapResponseWriter(w, r.ProtoMajor)
+			ww.Tee(&buf)
+			next.ServeHTTP(ww, r)
+
+			if ww.Status() >= http.StatusInternalServerError {
+				return
+			}
+			status := ww.Status()
+			if status == 0 {
+				// nothing was written, the server replied with an empty 200.
+				status = http.StatusOK
+			}
+			resp := entities.RecordedResponse{
+				StatusCode: status,
+				Header:     ww.Header().Clone(),
+				Body:       buf.Bytes(),
+			}
+			if err := idempotencyService.Complete(ctx, key, resp); err != nil {
+				slog.Error("failed to record idempotent response", "key", key, "error", err)
+				return
+			}
+			completed = true
+		})
+	}
+}
+
+// isMutating reports whether requests with the method change state.
+func isMutating(method string) bool {
+	switch method {
+	case http.MethodPost, http.MethodPut, http.MethodPatch, http.MethodDelete:
+		return true
+	}
+	return false
+}
+
+// requestFingerprint identifies a request by its method, URL and body.
+func requestFingerprint(r *http.Request, body []byte) string {
+	h := sha256.New()
+	h.Write([]byte(r.Method + " " + r.URL.RequestURI() + "\n")) //nolint:errcheck //hash writes never fail
+	h.Write(body)                                               //nolint:errcheck //hash writes never fail
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// replay writes a recorded response.
+func replay(w http.ResponseWriter, recorded *entities.RecordedResponse) {
+	for k, v := range recorded.Header {
+		w.Header()[k] = v
+	}
+	w.Header().Set(idempotentReplayedHeader, "true")
+	w.WriteHeader(recorded.StatusCode)
+	w.Write(recorded.Body) //nolint:errcheck //ignore error
+}
@@ -0,0 +1,67 @@
+package v1_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	api "wonderful/internal/api/v1"
+	"wonderful/internal/entities"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/stretchr/testify/require"
+)
+
+// keyService holds the idempotency keys in progress, the responses are not
+// recorded.
+type keyService struct {
+	begun, abandoned []string
+}
+
+func (s *keyService) Begin(_ context.Context, key, _ string) (*entities.RecordedResponse, error) {
+	s.begun = append(s.begun, key)
+	return nil, nil
+}
+
+func (s *keyService) Complete(_ context.Context, _ string, _ entities.RecordedResponse) error {
+	return nil
+}
+
+func (s *keyService) Abandon(_ context.Context, key string) error {
+	s.abandoned = append(s.abandoned, key)
+	return nil
+}
+
+func TestIdempotencyPanic(t *testing.T) {
+	keys := &keyService{}
+	r := chi.NewRouter()
+	r.Use(middleware.Recoverer)
+	r.Use(api.Idempotency(keys))
+	r.Post("/panic", func(http.ResponseWriter, *http.Request) { panic("boom") })
+	r.Post("/ok", func(w http.ResponseWriter, _ *http.Request) { w.WriteHeader(http.StatusCreated) })
+	server := httptest.NewServer(r)
+	defer server.Close()
+
+	post := func(path, key string) int {
+		req, err := http.NewRequestWithContext(context.Background(), http.MethodPost, server.URL+path, strings.NewReader("{}"))
+		require.NoError(t, err)
+		req.Header.Set(api.IdempotencyKeyHeader, key)
+		res, err := server.Client().Do(req)
+		require.NoError(t, err)
+		res.Body.Close()
+		return res.StatusCode
+	}
+
+	// the key of a request that panicked is released for its retry
+	require.Equal(t, http.StatusInternalServerError, post("/panic", "a"))
+	require.Equal(t, []string{"a"}, keys.begun)
+	require.Equal(t, []string{"a"}, keys.abandoned)
+
+	// the key of a recorded response is kept
+	require.Equal(t, http.StatusCreated, post("/ok", "b"))
+	require.Equal(t, []string{"a", "b"}, keys.begun)
+	require.Equal(t, []string{"a"}, keys.abandoned)
+}
@@ -0,0 +1,165 @@
+package v1
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"time"
+
+	"wonderful/internal/api/v1/openapi"
+	"wonderful/internal/entities"
+	"wonderful/internal/service"
+)
+
+const (
+	// importMaxLine is the size of the longest NDJSON line of an import.
+	importMaxLine = 1 << 20
+	// importReadTimeout is the time an import body can take to upload, which
+	// replaces the read timeout of the server for the imports.
+	importReadTimeout = 10 * time.Minute
+)
+
+// errUnsupportedImport is an error when an import body is neither NDJSON nor CSV.
+var errUnsupportedImport = errors.New("unsupported import content type, use application/x-ndjson or text/csv")
+
+// errInvalidImport is an error when an import body can not be read any further.
+var errInvalidImport = errors.New("invalid import body")
+
+// newImportReader returns the function reading the users of an import body of the content type.
+func newImportReader(contentType string, body io.Reader) (func() (*service.ImportRecord, error), error) {
+	mediaType, _, _ := mime.ParseMediaType(contentType)
+	switch mediaType {
+	case "application/x-ndjson":
+		s := bufio.NewScanner(body)
+		s.Buffer(make([]byte, 0, bufio.MaxScanTokenSize), importMaxLine)
+		return (&ndjsonImport{scanner: s}).next, nil
+	case "text/csv":
+		r, err := newCSVImport(body)
+		if err != nil {
+			return nil, err
+		}
+		return r.next, nil
+	default:
+		return nil, errUnsupportedImport
+	}
+}
+
+// importInput is an NDJSON line of an import.
+type importInput struct {
+	openapi.UserInput
+	ExternalID string `json:"external_id"`
+}
+
+// ndjsonImport reads the users of an NDJSON import, one per line. The blank lines are skipped.
+type ndjsonImport struct {
+	scanner *bufio.Scanner
+	line    int
+}
+
+func (n *ndjsonImport) next() (*service.ImportRecord, error) {
+	for n.scanner.Scan() {
+		n.line++
+		b := bytes.TrimSpace(n.scanner.Bytes())
+		if len(b) == 0 {
+			continue
+		}
+		var in importInput
+		if err := json.Unmarshal(b, &in); err != nil {
+			var typeErr *json.UnmarshalTypeError
+			field := ""
+			if errors.As(err, &typeErr) {
+				field = typeErr.Field
+			}
+			return &service.ImportRecord{Line: n.line, Err: &entities.ImportError{Line: n.line, Field: field, Reason: err.Error()}}, nil
+		}
+		u := fromOpenAPIUserInput(&in.UserInput)
+		u.ExternalID = in.ExternalID
+		return &service.ImportRecord{Line: n.line, User: u}, nil
+	}
+	if err := n.scanner.Err(); err != nil {
+		return nil, fmt.Errorf("%w: failed to read line %d: %w", errInvalidImport, n.line+1, err)
+	}
+	return nil, io.EOF
+}
+
+// csvImport reads the users of a CSV import, one per row after the header row.
+type csvImport struct {
+	reader  *csv.Reader
+	columns map[string]int
+	width   int
+}
+
+func newCSVImport(body io.Reader) (*csvImport, error) {
+	r := csv.NewReader(body)
+	r.FieldsPerRecord = -1 // the rows are checked against the header below
+	r.ReuseRecord = true
+	header, err := r.Read()
+	if err != nil {
+		return nil, fmt.Errorf("%w: failed to read the header row: %w", errInvalidImport, err)
+	}
+	columns := make(map[string]int, len(header))
+	for i, name := range header {
+		columns[name] = i
+	}
+	for _, required := range []string{"name", "email"} {
+		if _, ok := columns[required]; !ok {
+			return nil, fmt.Errorf("%w: missing %s column in the header row", errInvalidImport, required)
+		}
+	}
+	return &csvImport{reader: r, columns: columns, width: len(header)}, nil
+}
+
+func (c *csvImport) next() (*service.ImportRecord, error) {
+	record, err := c.reader.Read()
+	if errors.Is(err, io.EOF) {
+		return nil, io.EOF
+	}
+	var parseErr *csv.ParseError
+	if errors.As(err, &parseErr) {
+		return &service.ImportRecord{Line: parseErr.StartLine, Err: &entities.ImportError{Line: parseErr.StartLine, Reason: parseErr.Err.Error()}}, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%w: failed to read a row: %w", errInvalidImport, err)
+	}
+	line, _ := c.reader.FieldPos(0)
+	invalid := func(field, reason string) (*service.ImportRecord, error) {
+		return &service.ImportRecord{Line: line, Err: &entities.ImportError{Line: line, Field: field, Reason: reason}}, nil
+	}
+	if len(record) != c.width {
+		return invalid("", fmt.Sprintf("has %d columns instead of %d", len(record), c.width))
+	}
+	value := func(column string) string {
+		if i, ok := c.columns[column]; ok {
+			return record[i]
+		}
+		return ""
+	}
+	u := entities.User{
+		ExternalID: value("external_id"),
+		Name:       value("name"),
+		Email:      value("email"),
+		Phone:      value("phone"),
+		Cell:       value("cell"),
+	}
+	for _, size := range []string{"large", "medium", "thumbnail"} {
+		if url := value("picture_" + size); url != "" {
+			if u.Picture == nil {
+				u.Picture = make(map[string]string, 3)
+			}
+			u.Picture[size] = url
+		}
+	}
+	if v := value("registration_date"); v != "" {
+		registration, timeErr := time.Parse(time.RFC3339Nano, v)
+		if timeErr != nil {
+			return invalid("registration_date", "is not an RFC 3339 date-time")
+		}
+		u.Registration = registration.UTC()
+	}
+	return &service.ImportRecord{Line: line, User: u}, nil
+}
@@ -0,0 +1,143 @@
+package v1
+
+import (
+	"fmt"
+	"mime"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/getkin/kin-openapi/openapi3"
+	"github.com/getkin/kin-openapi/routers/gorillamux"
+)
+
+// The media types of the responses.
+const (
+	mediaTypeJSON    = "application/json"
+	mediaTypeJSONAPI = "application/vnd.api+json"
+	mediaTypeCSV     = "text/csv"
+	mediaTypeMsgPack = "application/msgpack"
+)
+
+// userMediaTypes are the representations of the users, JSON by default.
+var userMediaTypes = []string{mediaTypeJSON, mediaTypeJSONAPI, mediaTypeCSV, mediaTypeMsgPack}
+
+// mediaTypeAliases are the other names of the media types, not registered ones.
+var mediaTypeAliases = map[string]string{
+	"application/x-msgpack": mediaTypeMsgPack,
+}
+
+// mediaRange is a media range of an Accept header, e.g. "text/*;q=0.5".
+type mediaRange struct {
+	mediaType string
+	q         float64
+}
+
+// matches returns how specifically the range matches the media type: 3 for
+// the same type, 2 for type/* and 1 for */*, or 0 when it does not match.
+func (m mediaRange) matches(mediaType string) int {
+	switch {
+	case m.mediaType == mediaType:
+		return 3
+	case m.mediaType == "*/*":
+		return 1
+	case strings.HasSuffix(m.mediaType, "/*") && strings.HasPrefix(mediaType, strings.TrimSuffix(m.mediaType, "*")):
+		return 2
+	default:
+		return 0
+	}
+}
+
+// parseAccept returns the media ranges of an Accept header. The invalid ones are ignored.
+func parseAccept(accept string) []mediaRange {
+	var ranges []mediaRange
+	for _, part := range strings.Split(accept, ",") {
+		mediaType, params, err := mime.ParseMediaType(part)
+		if err != nil {
+			continue
+		}
+		if alias, ok := mediaTypeAliases[mediaType]; ok {
+			mediaType = alias
+		}
+		q := 1.0
+		if v, ok := params["q"]; ok {
+			if q, err = strconv.ParseFloat(v, 64); err != nil || q < 0 || q > 1 {
+				continue
+			}
+		}
+		ranges = append(ranges, mediaRange{mediaType: mediaType, q: q})
+	}
+	return ranges
+}
+
+// negotiate returns the offered media type the Accept header of the request
+// prefers, the first one on a tie or without the header, or an empty string
+// when none is acceptable. The quality of an offer is the one of the most
+// specific range matching it (RFC 9110, section 12.5.1).
+func negotiate(r *http.Request, offers ...string) string {
+	accept := r.Header.Get("Accept")
+	if accept == "" {
+		return offers[0]
+	}
+	ranges := parseAccept(accept)
+	best, bestQ := "", 0.0
+	for _, offer := range offers {
+		specificity, q := 0, 0.0
+		for _, m := range ranges {
+			if s := m.matches(offer); s > specificity {
+				specificity, q = s, m.q
+			}
+		}
+		if q > bestQ {
+			best, bestQ = offer, q
+		}
+	}
+	return best
+}
+
+// Negotiation rejects with 406 Not Acceptable the requests accepting none of
+// the media types of the successful responses of their operation in the spec.
+// The handlers negotiate the representation among them.
+func Negotiation(swagger *openapi3.T) (func(http.Handler) http.Handler, error) {
+	router, err := gorillamux.NewRouter(swagger)
+	if err != nil {
+		return nil, fmt.Errorf("failed to route the spec: %w", err)
+	}
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.Header.Get("Accept") == "" {
+				next.ServeHTTP(w, r)
+				return
+			}
+			route, _, err := router.FindRoute(r)
+			if err != nil {
+				// the unknown routes are left to the router.
+				next.ServeHTTP(w, r)
+				return
+			}
+			offers := responseMediaTypes(route.Operation)
+			if len(offers) > 0 && negotiate(r, offers...) == "" {
+				notAcceptable := fmt.Errorf("none of %s is acceptable", strings.Join(offers, ", "))
+				sendAPIError(w, r, problemNotAcceptable, "Not acceptable: "+notAcceptable.Error(), notAcceptable)
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}, nil
+}
+
+// responseMediaTypes returns the media types of the successful responses of an operation, sorted.
+func responseMediaTypes(op *openapi3.Operation) []string {
+	var mediaTypes []string
+	for status, resp := range op.Responses.Map() {
+		if !strings.HasPrefix(status, "2") || resp.Value == nil {
+			continue
+		}
+		for mediaType := range resp.Value.Content {
+			mediaTypes = append(mediaTypes, mediaType)
+		}
+	}
+	slices.Sort(mediaTypes)
+	return mediaTypes
+}
@@ -13,30 +13,129 @@ import (
 
 // ServerInterface represents all server handlers.
 type ServerInterface interface {
+	// Purge soft-deleted users
+	// (POST /admin/purge)
+	PostAdminPurge(w http.ResponseWriter, r *http.Request)
 	// Populate database with random users
 	// (POST /populate)
 	PostPopulate(w http.ResponseWriter, r *http.Request)
+	// Get a populate job
+	// (GET /populate/jobs/{id})
+	GetPopulateJob(w http.ResponseWriter, r *http.Request, id string)
 	// Get list of users
 	// (GET /wonderfuls)
 	GetWonderfuls(w http.ResponseWriter, r *http.Request, params GetWonderfulsParams)
+	// Create a user
+	// (POST /wonderfuls)
+	PostWonderfuls(w http.ResponseWriter, r *http.Request)
+	// Export users
+	// (GET /wonderfuls/export)
+	ExportWonderfuls(w http.ResponseWriter, r *http.Request, params ExportWonderfulsParams)
+	// Import users
+	// (POST /wonderfuls/import)
+	ImportWonderfuls(w http.ResponseWriter, r *http.Request)
+	// Search users
+	// (GET /wonderfuls/search)
+	SearchWonderfuls(w http.ResponseWriter, r *http.Request, params SearchWonderfulsParams)
+	// Delete a user
+	// (DELETE /wonderfuls/{id})
+	DeleteWonderful(w http.ResponseWriter, r *http.Request, id string)
+	// Get a user by ID
+	// (GET /wonderfuls/{id})
+	GetWonderful(w http.ResponseWriter, r *http.Request, id string)
+	// Update a user
+	// (PATCH /wonderfuls/{id})
+	PatchWonderful(w http.ResponseWriter, r *http.Request, id string)
+	// Replace a user
+	// (PUT /wonderfuls/{id})
+	PutWonderful(w http.ResponseWriter, r *http.Request, id string)
+	// Restore a user
+	// (POST /wonderfuls/{id}/restore)
+	PostWonderfulRestore(w http.ResponseWriter, r *http.Request, id string)
 }
 
 // Unimplemented server implementation that returns http.StatusNotImplemented for each endpoint.
 
 type Unimplemented struct{}
 
+// Purge soft-deleted users
+// (POST /admin/purge)
+func (_ Unimplemented) PostAdminPurge(w http.ResponseWriter, r *http.Request) {
+	w.WriteHeader(http.StatusNotImplemented)
+}
+
 // Populate database with random users
 // (POST /populate)
 func (_ Unimplemented) PostPopulate(w http.ResponseWriter, r *http.Request) {
 	w.WriteHeader(http.StatusNotImplemented)
 }
 
+// Get a populate job
+// (GET /populate/jobs/{id})
+func (_ Unimplemented) GetPopulateJob(w http.ResponseWriter, r *http.Request, id string) {
+	w.WriteHeader(http.StatusNotImplemented)
+}
+
 // Get list of users
 // (GET /wonderfuls)
 func (_ Unimplemented) GetWonderfuls(w http.ResponseWriter, r *http.Request, params GetWonderfulsParams) {
 	w.WriteHeader(http.StatusNotImplemented)
 }
 
+// Create a user
+// (POST /wonderfuls)
+func (_ Unimplemented) PostWonderfuls(w http.ResponseWriter, r *http.Request) {
+	w.WriteHeader(http.StatusNotImplemented)
+}
+
+// Export users
+// (GET /wonderfuls/export)
+func (_ Unimplemented) ExportWonderfuls(w http.ResponseWriter, r *http.Request, params ExportWonderfulsParams) {
+	w.WriteHeader(http.StatusNotImplemented)
+}
+
+// Import users
+// (POST /wonderfuls/import)
+func (_ Unimplemented) ImportWonderfuls(w http.ResponseWriter, r *http.Request) {
+	w.WriteHeader(http.StatusNotImplemented)
+}
+
+// Search users
+// (GET /wonderfuls/search)
+func (_ Unimplemented) SearchWonderfuls(w http.ResponseWriter, r *http.Request, params SearchWonderfulsParams) {
+	w.WriteHeader(http.StatusNotImplemented)
+}
+
+// Delete a user
+// (DELETE /wonderfuls/{id})
+func (_ Unimplemented) DeleteWonderful(w http.ResponseWriter, r *http.Request, id string) {
+	w.WriteHeader(http.StatusNotImplemented)
+}
+
+// Get a user by ID
+// (GET /wonderfuls/{id})
+func (_ Unimplemented) GetWonderful(w http.ResponseWriter, r *http.Request, id string) {
+	w.WriteHeader(http.StatusNotImplemented)
+}
+
+// Update a user
+// (PATCH /wonderfuls/{id})
+func (_ Unimplemented) PatchWonderful(w http.ResponseWriter, r *http.Request, id string) {
+	w.WriteHeader(http.StatusNotImplemented)
+}
+
+// Replace a user
+// (PUT /wonderfuls/{id})
+func (_ Unimplemented) PutWonderful(w http.ResponseWriter, r *http.Request, id string) {
+	w.WriteHeader(http.StatusNotImplemented)
+}
+
+// Restore a user
+// (POST /wonderfuls/{id}/restore)
+func (_ Unimplemented) PostWonderfulRestore(w http.ResponseWriter, r *http.Request, id string) {
+	w.WriteHeader(http.StatusNotImplemented)
+}
+
 // ServerInterfaceWrapper converts contexts to parameters.
 type ServerInterfaceWrapper struct {
 	Handler            ServerInterface
@@ -46,6 +145,21 @@ type ServerInterfaceWrapper struct {
 
 type MiddlewareFunc func(http.Handler) http.Handler
 
+// PostAdminPurge operation middleware
+func (siw *ServerInterfaceWrapper) PostAdminPurge(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	handler := http.Handler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		siw.Handler.PostAdminPurge(w, r)
+	}))
+
+	for _, middleware := range siw.HandlerMiddlewares {
+		handler = middleware(handler)
+	}
+
+	handler.ServeHTTP(w, r.WithContext(ctx))
+}
+
 // PostPopulate operation middleware
 func (siw *ServerInterfaceWrapper) PostPopulate(w http.ResponseWriter, r *http.Request) {
 	ctx := r.Context()
@@ -61,6 +175,32 @@ func (siw *ServerInterfaceWrapper) PostPopulate(w http.ResponseWriter, r *http.R
 	handler.ServeHTTP(w, r.WithContext(ctx))
 }
 
+// GetPopulateJob operation middleware
+func (siw *ServerInterfaceWrapper) GetPopulateJob(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	var err error
+
+	// ------------- Path parameter "id" -------------
+	var id string
+
+	err = runtime.BindStyledParameterWithOptions("simple", "id", chi.URLParam(r, "id"), &id, runtime.BindStyledParameterOptions{ParamLocation: runtime.ParamLocationPath, Explode: false, Required: true})
+	if err != nil {
+		siw.ErrorHandlerFunc(w, r, &InvalidParamFormatError{ParamName: "id", Err: err})
+		return
+	}
+
+	handler := http.Handler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		siw.Handler.GetPopulateJob(w, r, id)
+	}))
+
+	for _, middleware := range siw.HandlerMiddlewares {
+		handler = middleware(handler)
+	}
+
+	handler.ServeHTTP(w, r.WithContext(ctx))
+}
+
 // GetWonderfuls operation middleware
 func (siw *ServerInterfaceWrapper) GetWonderfuls(w http.ResponseWriter, r *http.Request) {
 	ctx := r.Context()
@@ -102,6 +242,102 @@ func (siw *ServerInterfaceWrapper) GetWonderfuls(w http.ResponseWriter, r *http.
 		return
 	}
 
+	// ------------- Optional query parameter "name" -------------
+
+	err = runtime.BindQueryParameter("form", true, false, "name", r.URL.Query(), &params.Name)
+	if err != nil {
+		siw.ErrorHandlerFunc(w, r, &InvalidParamFormatError{ParamName: "name", Err: err})
+		return
+	}
+
+	// ------------- Optional query parameter "q" -------------
+
+	err = runtime.BindQueryParameter("form", true, false, "q", r.URL.Query(), &params.Q)
+	if err != nil {
+		siw.ErrorHandlerFunc(w, r, &InvalidParamFormatError{ParamName: "q", Err: err})
+		return
+	}
+
+	// ------------- Optional query parameter "phone" -------------
+
+	err = runtime.BindQueryParameter("form", true, false, "phone", r.URL.Query(), &params.Phone)
+	if err != nil {
+		siw.ErrorHandlerFunc(w, r, &InvalidParamFormatError{ParamName: "phone", Err: err})
+		return
+	}
+
+	// ------------- Optional query parameter "cell" -------------
+
+	err = runtime.BindQueryParameter("form", true, false, "cell", r.URL.Query(), &params.Cell)
+	if err != nil {
+		siw.ErrorHandlerFunc(w, r, &InvalidParamFormatError{ParamName: "cell", Err: err})
+		return
+	}
+
+	// ------------- Optional query parameter "nat" -------------
+
+	err = runtime.BindQueryParameter("form", true, false, "nat", r.URL.Query(), &params.Nat)
+	if err != nil {
+		siw.ErrorHandlerFunc(w, r, &InvalidParamFormatError{ParamName: "nat", Err: err})
+		return
+	}
+
+	// ------------- Optional query parameter "gender" -------------
+
+	err = runtime.BindQueryParameter("form", true, false, "gender", r.URL.Query(), &params.Gender)
+	if err != nil {
+		siw.ErrorHandlerFunc(w, r, &InvalidParamFormatError{ParamName: "gender", Err: err})
+		return
+	}
+
+	// ------------- Optional query parameter "registered_after" -------------
+
+	err = runtime.BindQueryParameter("form", true, false, "registered_after", r.URL.Query(), &params.RegisteredAfter)
+	if err != nil {
+		siw.ErrorHandlerFunc(w, r, &InvalidParamFormatError{ParamName: "registered_after", Err: err})
+		return
+	}
+
+	// ------------- Optional query parameter "registered_before" -------------
+
+	err = runtime.BindQueryParameter("form", true, false, "registered_before", r.URL.Query(), &params.RegisteredBefore)
+	if err != nil {
+		siw.ErrorHandlerFunc(w, r, &InvalidParamFormatError{ParamName: "registered_before", Err: err})
+		return
+	}
+
+	// ------------- Optional query parameter "include_deleted" -------------
+
+	err = runtime.BindQueryParameter("form", true, false, "include_deleted", r.URL.Query(), &params.IncludeDeleted)
+	if err != nil {
+		siw.ErrorHandlerFunc(w, r, &InvalidParamFormatError{ParamName: "include_deleted", Err: err})
+		return
+	}
+
+	// ------------- Optional query parameter "include_total" -------------
+
+	err = runtime.BindQueryParameter("form", true, false, "include_total", r.URL.Query(), &params.IncludeTotal)
+	if err != nil {
+		siw.ErrorHandlerFunc(w, r, &InvalidParamFormatError{ParamName: "include_total", Err: err})
+		return
+	}
+
+	// ------------- Optional query parameter "sort" -------------
+
+	err = runtime.BindQueryParameter("form", true, false, "sort", r.URL.Query(), &params.Sort)
+	if err != nil {
+		siw.ErrorHandlerFunc(w, r, &InvalidParamFormatError{ParamName: "sort", Err: err})
+		return
+	}
+
+	// ------------- Optional query parameter "fields" -------------
+
+	err = runtime.BindQueryParameter("form", true, false, "fields", r.URL.Query(), &params.Fields)
+	if err != nil {
+		siw.ErrorHandlerFunc(w, r, &InvalidParamFormatError{ParamName: "fields", Err: err})
+		return
+	}
+
 	handler := http.Handler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		siw.Handler.GetWonderfuls(w, r, params)
 	}))
@@ -113,6 +349,333 @@ func (siw *ServerInterfaceWrapper) GetWonderfuls(w http.ResponseWriter, r *http.
 	handler.ServeHTTP(w, r.WithContext(ctx))
 }
 
+// PostWonderfuls operation middleware
+func (siw *ServerInterfaceWrapper) PostWonderfuls(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	handler := http.Handler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		siw.Handler.PostWonderfuls(w, r)
+	}))
+
+	for _, middleware := range siw.HandlerMiddlewares {
+		handler = middleware(handler)
+	}
+
+	handler.ServeHTTP(w, r.WithContext(ctx))
+}
+
+// ExportWonderfuls operation middleware
+func (siw *ServerInterfaceWrapper) ExportWonderfuls(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	var err error
+
+	// Parameter object where we will unmarshal all parameters from the context
+	var params ExportWonderfulsParams
+
+	// ------------- Optional query parameter "format" -------------
+
+	err = runtime.BindQueryParameter("form", true, false, "format", r.URL.Query(), &params.Format)
+	if err != nil {
+		siw.ErrorHandlerFunc(w, r, &InvalidParamFormatError{ParamName: "format", Err: err})
+		return
+	}
+
+	// ------------- Optional query parameter "email" -------------
+
+	err = runtime.BindQueryParameter("form", true, false, "email", r.URL.Query(), &params.Email)
+	if err != nil {
+		siw.ErrorHandlerFunc(w, r, &InvalidParamFormatError{ParamName: "email", Err: err})
+		return
+	}
+
+	// ------------- Optional query parameter "name" -------------
+
+	err = runtime.BindQueryParameter("form", true, false, "name", r.URL.Query(), &params.Name)
+	if err != nil {
+		siw.ErrorHandlerFunc(w, r, &InvalidParamFormatError{ParamName: "name", Err: err})
+		return
+	}
+
+	// ------------- Optional query parameter "q" -------------
+
+	err = runtime.BindQueryParameter("form", true, false, "q", r.URL.Query(), &params.Q)
+	if err != nil {
+		siw.ErrorHandlerFunc(w, r, &InvalidParamFormatError{ParamName: "q", Err: err})
+		return
+	}
+
+	// ------------- Optional query parameter "phone" -------------
+
+	err = runtime.BindQueryParameter("form", true, false, "phone", r.URL.Query(), &params.Phone)
+	if err != nil {
+		siw.ErrorHandlerFunc(w, r, &InvalidParamFormatError{ParamName: "phone", Err: err})
+		return
+	}
+
+	// ------------- Optional query parameter "cell" -------------
+
+	err = runtime.BindQueryParameter("form", true, false, "cell", r.URL.Query(), &params.Cell)
+	if err != nil {
+		siw.ErrorHandlerFunc(w, r, &InvalidParamFormatError{ParamName: "cell", Err: err})
+		return
+	}
+
+	// ------------- Optional query parameter "nat" -------------
+
+	err = runtime.BindQueryParameter("form", true, false, "nat", r.URL.Query(), &params.Nat)
+	if err != nil {
+		siw.ErrorHandlerFunc(w, r, &InvalidParamFormatError{ParamName: "nat", Err: err})
+		return
+	}
+
+	// ------------- Optional query parameter "gender" -------------
+
+	err = runtime.BindQueryParameter("form", true, false, "gender", r.URL.Query(), &params.Gender)
+	if err != nil {
+		siw.ErrorHandlerFunc(w, r, &InvalidParamFormatError{ParamName: "gender", Err: err})
+		return
+	}
+
+	// ------------- Optional query parameter "registered_after" -------------
+
+	err = runtime.BindQueryParameter("form", true, false, "registered_after", r.URL.Query(), &params.RegisteredAfter)
+	if err != nil {
+		siw.ErrorHandlerFunc(w, r, &InvalidParamFormatError{ParamName: "registered_after", Err: err})
+		return
+	}
+
+	// ------------- Optional query parameter "registered_before" -------------
+
+	err = runtime.BindQueryParameter("form", true, false, "registered_before", r.URL.Query(), &params.RegisteredBefore)
+	if err != nil {
+		siw.ErrorHandlerFunc(w, r, &InvalidParamFormatError{ParamName: "registered_before", Err: err})
+		return
+	}
+
+	// ------------- Optional query parameter "include_deleted" -------------
+
+	err = runtime.BindQueryParameter("form", true, false, "include_deleted", r.URL.Query(), &params.IncludeDeleted)
+	if err != nil {
+		siw.ErrorHandlerFunc(w, r, &InvalidParamFormatError{ParamName: "include_deleted", Err: err})
+		return
+	}
+
+	handler := http.Handler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		siw.Handler.ExportWonderfuls(w, r, params)
+	}))
+
+	for _, middleware := range siw.HandlerMiddlewares {
+		handler = middleware(handler)
+	}
+
+	handler.ServeHTTP(w, r.WithContext(ctx))
+}
+
+// ImportWonderfuls operation middleware
+func (siw *ServerInterfaceWrapper) ImportWonderfuls(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	handler := http.Handler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		siw.Handler.ImportWonderfuls(w, r)
+	}))
+
+	for _, middleware := range siw.HandlerMiddlewares {
+		handler = middleware(handler)
+	}
+
+	handler.ServeHTTP(w, r.WithContext(ctx))
+}
+
+// SearchWonderfuls operation middleware
+func (siw *ServerInterfaceWrapper) SearchWonderfuls(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	var err error
+
+	// Parameter object where we will unmarshal all parameters from the context
+	var params SearchWonderfulsParams
+
+	// ------------- Required query parameter "q" -------------
+
+	if paramValue := r.URL.Query().Get("q"); paramValue != "" {
+
+	} else {
+		siw.ErrorHandlerFunc(w, r, &RequiredParamError{ParamName: "q"})
+		return
+	}
+
+	err = runtime.BindQueryParameter("form", true, true, "q", r.URL.Query(), &params.Q)
+	if err != nil {
+		siw.ErrorHandlerFunc(w, r, &InvalidParamFormatError{ParamName: "q", Err: err})
+		return
+	}
+
+	// ------------- Optional query parameter "limit" -------------
+
+	err = runtime.BindQueryParameter("form", true, false, "limit", r.URL.Query(), &params.Limit)
+	if err != nil {
+		siw.ErrorHandlerFunc(w, r, &InvalidParamFormatError{ParamName: "limit", Err: err})
+		return
+	}
+
+	// ------------- Optional query parameter "starting_after" -------------
+
+	err = runtime.BindQueryParameter("form", true, false, "starting_after", r.URL.Query(), &params.StartingAfter)
+	if err != nil {
+		siw.ErrorHandlerFunc(w, r, &InvalidParamFormatError{ParamName: "starting_after", Err: err})
+		return
+	}
+
+	// ------------- Optional query parameter "include_deleted" -------------
+
+	err = runtime.BindQueryParameter("form", true, false, "include_deleted", r.URL.Query(), &params.IncludeDeleted)
+	if err != nil {
+		siw.ErrorHandlerFunc(w, r, &InvalidParamFormatError{ParamName: "include_deleted", Err: err})
+		return
+	}
+
+	handler := http.Handler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		siw.Handler.SearchWonderfuls(w, r, params)
+	}))
+
+	for _, middleware := range siw.HandlerMiddlewares {
+		handler = middleware(handler)
+	}
+
+	handler.ServeHTTP(w, r.WithContext(ctx))
+}
+
+// DeleteWonderful operation middleware
+func (siw *ServerInterfaceWrapper) DeleteWonderful(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	var err error
+
+	// ------------- Path parameter "id" -------------
+	var id string
+
+	err = runtime.BindStyledParameterWithOptions("simple", "id", chi.URLParam(r, "id"), &id, runtime.BindStyledParameterOptions{ParamLocation: runtime.ParamLocationPath, Explode: false, Required: true})
+	if err != nil {
+		siw.ErrorHandlerFunc(w, r, &InvalidParamFormatError{ParamName: "id", Err: err})
+		return
+	}
+
+	handler := http.Handler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		siw.Handler.DeleteWonderful(w, r, id)
+	}))
+
+	for _, middleware := range siw.HandlerMiddlewares {
+		handler = middleware(handler)
+	}
+
+	handler.ServeHTTP(w, r.WithContext(ctx))
+}
+
+// GetWonderful operation middleware
+func (siw *ServerInterfaceWrapper) GetWonderful(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	var err error
+
+	// ------------- Path parameter "id" -------------
+	var id string
+
+	err = runtime.BindStyledParameterWithOptions("simple", "id", chi.URLParam(r, "id"), &id, runtime.BindStyledParameterOptions{ParamLocation: runtime.ParamLocationPath, Explode: false, Required: true})
+	if err != nil {
+		siw.ErrorHandlerFunc(w, r, &InvalidParamFormatError{ParamName: "id", Err: err})
+		return
+	}
+
+	handler := http.Handler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		siw.Handler.GetWonderful(w, r, id)
+	}))
+
+	for _, middleware := range siw.HandlerMiddlewares {
+		handler = middleware(handler)
+	}
+
+	handler.ServeHTTP(w, r.WithContext(ctx))
+}
+
+// PatchWonderful operation middleware
+func (siw *ServerInterfaceWrapper) PatchWonderful(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	var err error
+
+	// ------------- Path parameter "id" -------------
+	var id string
+
+	err = runtime.BindStyledParameterWithOptions("simple", "id", chi.URLParam(r, "id"), &id, runtime.BindStyledParameterOptions{ParamLocation: runtime.ParamLocationPath, Explode: false, Required: true})
+	if err != nil {
+		siw.ErrorHandlerFunc(w, r, &InvalidParamFormatError{ParamName: "id", Err: err})
+		return
+	}
+
+	handler := http.Handler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		siw.Handler.PatchWonderful(w, r, id)
+	}))
+
+	for _, middleware := range siw.HandlerMiddlewares {
+		handler = middleware(handler)
+	}
+
+	handler.ServeHTTP(w, r.WithContext(ctx))
+}
+
+// PutWonderful operation middleware
+func (siw *ServerInterfaceWrapper) PutWonderful(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	var err error
+
+	// ------------- Path parameter "id" -------------
+	var id string
+
+	err = runtime.BindStyledParameterWithOptions("simple", "id", chi.URLParam(r, "id"), &id, runtime.BindStyledParameterOptions{ParamLocation: runtime.ParamLocationPath, Explode: false, Required: true})
+	if err != nil {
+		siw.ErrorHandlerFunc(w, r, &InvalidParamFormatError{ParamName: "id", Err: err})
+		return
+	}
+
+	handler := http.Handler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		siw.Handler.PutWonderful(w, r, id)
+	}))
+
+	for _, middleware := range siw.HandlerMiddlewares {
+		handler = middleware(handler)
+	}
+
+	handler.ServeHTTP(w, r.WithContext(ctx))
+}
+
+// PostWonderfulRestore operation middleware
+func (siw *ServerInterfaceWrapper) PostWonderfulRestore(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	var err error
+
+	// ------------- Path parameter "id" -------------
+	var id string
+
+	err = runtime.BindStyledParameterWithOptions("simple", "id", chi.URLParam(r, "id"), &id, runtime.BindStyledParameterOptions{ParamLocation: runtime.ParamLocationPath, Explode: false, Required: true})
+	if err != nil {
+		siw.ErrorHandlerFunc(w, r, &InvalidParamFormatError{ParamName: "id", Err: err})
+		return
+	}
+
+	handler := http.Handler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		siw.Handler.PostWonderfulRestore(w, r, id)
+	}))
+
+	for _, middleware := range siw.HandlerMiddlewares {
+		handler = middleware(handler)
+	}
+
+	handler.ServeHTTP(w, r.WithContext(ctx))
+}
+
 type UnescapedCookieParamError struct {
 	ParamName string
 	Err       error
@@ -226,12 +789,45 @@ func HandlerWithOptions(si ServerInterface, options ChiServerOptions) http.Handl
 		ErrorHandlerFunc:   options.ErrorHandlerFunc,
 	}
 
+	r.Group(func(r chi.Router) {
+		r.Post(options.BaseURL+"/admin/purge", wrapper.PostAdminPurge)
+	})
 	r.Group(func(r chi.Router) {
 		r.Post(options.BaseURL+"/populate", wrapper.PostPopulate)
 	})
+	r.Group(func(r chi.Router) {
+		r.Get(options.BaseURL+"/populate/jobs/{id}", wrapper.GetPopulateJob)
+	})
 	r.Group(func(r chi.Router) {
 		r.Get(options.BaseURL+"/wonderfuls", wrapper.GetWonderfuls)
 	})
+	r.Group(func(r chi.Router) {
+		r.Post(options.BaseURL+"/wonderfuls", wrapper.PostWonderfuls)
+	})
+	r.Group(func(r chi.Router) {
+		r.Get(options.BaseURL+"/wonderfuls/export", wrapper.ExportWonderfuls)
+	})
+	r.Group(func(r chi.Router) {
+		r.Post(options.BaseURL+"/wonderfuls/import", wrapper.ImportWonderfuls)
+	})
+	r.Group(func(r chi.Router) {
+		r.Get(options.BaseURL+"/wonderfuls/search", wrapper.SearchWonderfuls)
+	})
+	r.Group(func(r chi.Router) {
+		r.Delete(options.BaseURL+"/wonderfuls/{id}", wrapper.DeleteWonderful)
+	})
+	r.Group(func(r chi.Router) {
+		r.Get(options.BaseURL+"/wonderfuls/{id}", wrapper.GetWonderful)
+	})
+	r.Group(func(r chi.Router) {
+		r.Patch(options.BaseURL+"/wonderfuls/{id}", wrapper.PatchWonderful)
+	})
+	r.Group(func(r chi.Router) {
+		r.Put(options.BaseURL+"/wonderfuls/{id}", wrapper.PutWonderful)
+	})
+	r.Group(func(r chi.Router) {
+		r.Post(options.BaseURL+"/wonderfuls/{id}/restore", wrapper.PostWonderfulRestore)
+	})
 
 	return r
 }
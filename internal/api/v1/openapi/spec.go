@@ -18,19 +18,107 @@ import (
 // Base64 encoded, gzipped, json marshaled Swagger object
 var swaggerSpec = []string{
 
-	"H4sIAAAAAAAC/8xVTYvcRhD9K00lEBu0MxqbXHQzJDELDlkcgg9mMT3qkqaM+sPVJa+HRf89dEuakWe0",
-	"mL35tD1dpfdeVfWrfYTa2+AdOolQPUKsD2h1Pv7J7DkdAvuALIT5uvYG01+DsWYKQt5BNSarHCug8Wy1",
-	"QAXk5PUrKECOAcef2CLDUIDFGHX7JNAcPn0ahcm1MAwFMH7pidFA9REmwjn9fijgv4grqtFq6tLhAq8A",
-	"MqvXTltcDYSDd7jSFuzW8a0mtxIYTqX5/WesJUNTLT2vgHea23U1Fg31djUkh97u3XrZa+yMLUVhnQbx",
-	"yWjJhKdRposbIfvjmZCBqX3F1PY17PsrBQmHXOOzXJIuxf4+qg/eGeSm79Sbu1so4CtyHJ/KblNuyqTc",
-	"B3Q6EFTwerPblFBA0HLIndsGH/puKib4KNcv7o0xUf1elGWpWDvjreojskInTBhVw96q9zmQ7je1t0nJ",
-	"BjLvWNKtgQrufJS7mS2VHIN3cRzgq3J3TfxvX9cYY6rAYKP7TkZ/OUGXjzqEjurMsP0cvTsbNJ1+ZWyg",
-	"gl+2ZwdvJ/tuR+/mln7P2Tv8FrAWNAqnnAJib63mY65h1K+MFr3XEdUDyWHZl5i/2D7MU8nltbjS1/co",
-	"PbuotOooivLN+P2I6HOW7lRDnWB6R0o7o4JuyeWCr/v7FuXDmTXNmLVFSZKqj4/nFu7Ky6LfkSVRckDl",
-	"ertHTlo4q0MziXqxu9mV5UtITxAq+NIjH9Ni0d/IJn/tyrIAS276Ne8H6BI0FIuxXO66obhsTNpQ6vYP",
-	"JV5F0SyLqpVuBPlSxcSVk8m1n+akK9KzI5/DucfGMz5Bis4kylPOMzj/yqNV+2Pu8W9R5W2gXtQ64g25",
-	"iC6S0Fd8+RT1tDyepry/sln5LAuRoI0/8lL+h3JemJpZH9es9W75yn8mU79F+d6CI4bodnbOEuyf2XRR",
-	"6b3vRT0sTTeNZmHE67kvEMSreQFn/81r5Yx02pjD/fB/AAAA//8MC1D2jQgAAA==",
+	"H4sIAAAAAAAC/+x9b3cbua33V+GZp+d0tx3L9iZpt+6bpslm6202zRMn3XtuJ9WhZiCJ8QypkBw7unv8",
+	"3e8BwPkncSTZm2Rz07yxLQ2HBEEABH4A6Z+T3FQro0F7l5z9nCxBFmDpz+9eygX+LsDlVq28Mjo5Sy68",
+	"NXohQHvl18LLhTBz4ZcgLKwsONBeYstUeCMc6EIoLc7nR8+MhqMfpc+XwptML8CLeyf3xTPjxY+mUHMF",
+	"hbheqhKE8kI5Uet8KfUCilQYG/poXhf8KNM4bO3ACqPLtVDzzXcn4uUSkEYXJ9JlGmdvwTkcXvmlkCI3",
+	"2oP2IjeF0guhYWG8kh4KMVuLh3kOK3/0nQ5PpYVMu3o+V++aHpRPBUwWE5ElR4v/UassyXSSJi5fQiWR",
+	"n369guQscd4qvUhubm7SxIJbGe2A+P7MeB5GzkrALwJF+KdcrUqVE/HHK2tmJVS/f+NwYX7ujfAbC/Pk",
+	"LPl/x93aHvNTd/yc3+Jxh0uLzOKRBYuB0LICJ7TR0DCwgkJJgVPo8ZSJz3RykyL1zYJuC8/LrSUQhSqE",
+	"Nj6sqXBK50Dd4qBeLhbMeL+ETA/liGkk5kakNsaD0OyY2hADnlvIjS4UkvJEqhKKj81wkt8gr73Zb3Bp",
+	"wIhWFzLNM0pRdfJlePFtDY70ANlK9ENBSpcgBYEspPqJgrL4zlpj8dPKmhVYr1gI5/hsewGfyaoVhbc1",
+	"2LVYSSsr8KiEtnkyM8VacA/ppriniQUZGLipCfjsba0srsK/kqaD0P5125WZvYHcY1fn1cpYf7spINOV",
+	"vpKlKpjGVMgZclpcL0ET/ddLU4IolQZkY2gcmwo22R7hKb6o62oGZLkajqTCeWk9mQ0vTrv+lPawAHsb",
+	"3tDAB7DmBeDPbd4Assxtk06sbDW7YROO5lK2svj9XFnnxWl6cnKCFlBY8LXVgCxSHiq3TyX6y3bT0i6t",
+	"lWv8PG8VcUP4mKVmPiQsykilHVi/uxdUPSfalrFu3KVarXb3wpRwX34p/aZFk8J5Y6FAgxYdo14V8iBK",
+	"Q8MUzQC882C1LKcqRviGtPSm2AzWTa3ld9pIRUycfjCzbSnKLWBnU0kSNje2wr8SHOHIqwpiKgONsg7n",
+	"+oIkmaTrjZkJJqnVzFqX4JxoCd3qdQ4+Xx7Cw9BQzK2paLQXUhemwoeT3FTi4fPzJO2morS/90101eZK",
+	"K7dsJ79hY1QF3VxCy95svCpFQbuq7SZ1GP9UEbEOh8m7husNmW/MUyG9nEkHh03dUNd7tfy5WdWl9PCC",
+	"d6QD1YkJDA1TMYNc1o6YuRbXYEHI0oIs1pukd14fMpVaWlhBEPYDZuW89LBvTj+Y2QW1u43ejlFM1oIo",
+	"7bTyAEJD41to3aY5IPWnaXSak+6zEj1l70RgxFRcNLwEXVc45Nsaaiia4ZCoZjz+u2DjGHThdUTunxr2",
+	"wrbZ/dw4L0shi8KCc0LqQuTG2EJp6TsvFZdi056sgoQiYRuWTfl1VM9yU2tv489K6ZWvCxgui6nRjW9n",
+	"xH4BNTd6cZv2K+N8brj51tit9EaeWAA/RVd+53Me5+znLYnbdD2aWfZnEJODZ7Resjx/HPMj+ZlQBWiP",
+	"0YIdLhRFUFJcXDw7fNGaKY67rN1oMdN6Jcs60sE/8evtHlIB1cqv2WtsAs9Lba71XgUkQpvxdrEuSGGj",
+	"RQ9fJWny1xdJmjx6iD/+lqTJ4+/wx9+TNPnuIkmTJ7h9PcEm3/81SZNzfHr+DH/gdz/+V5Imz57ij3/g",
+	"j/9O0uQFvvYSn77CTl9dRNXvuVzAU6UvXdyhfvXiaatpK7mAlNTQzDt3MRUa3nn6emXhSpnaUUu3tYzU",
+	"PCqq2EP0AfYYfeCgnO/3pqlVGgaOrQfO/kfwctsFWko3rYyNiM0FgGieNpx45cA+Vc53EjIzpgRJkbM3",
+	"XpZTMjD795RKsh0NDC492MZDX6gr0AGL0HlZFzClrjd2lz/c3+84trOLMmVpOPqRBQfQsnze481clg62",
+	"7CqUJf6u5LunoBd+mZzdO42IWyWV3t/uJkaVyn1tb0tXKe0ibh4R8Kir6CO/rKuZlqqMS9g2aRsO0bY/",
+	"fdjae4ObXSoeYPw1W4sC5rIufYJse6cqpBYfnaRJpTR/Po05EgvQBUR88X+gFMmiCWpIdpUToXnamqNK",
+	"luxB0B8xq6GlP6x7cCB0a/QUuENDyb6ljISSDmIO2gVAwd5XJS/BkQ7JooCGJAsra4o6V7wT94TwD/cj",
+	"s3SmtnnEAvy0BAvtnuZEbiqgwONsJPJI28ao0oDopJ6rRc3RIz10YK8Ya5GiAA8Wl9h5lePygJXe2Il4",
+	"zALhCPWht4hCMdbdJNO9ZbUtXWQTiQVujf14lUeWOSrqAfTadtb4AdIuVUmrL7WgoFB89eLJI/Gn+w/+",
+	"mAo0VGDLtcCv/vjtyR+/TgU5AdJlegyTo1kMNYqHieAc71alZIFr5dvkeW0t6Lw116Hz0TB22rhjmxA5",
+	"Ireikmih4ciCLOgLbN10fal4fwxDTMSjUoH2TuRSZ9pCucYlQiwZW3vlS2DXtmFcJdctDt5bvICMTFtY",
+	"Lknb7/LaOtP/AlGp3kdVQLUyHnS+nl4CPRl+M7VQOygiD5SerqxZWHAuSROUnak2fjo3tS76XxRQArtu",
+	"b8xs0KT/dwV+aQp6LMvSXAdfr4Nqp23QTG06tDxNau3q1cpYDFUIrJ7S2qWJW9Yeo41pwS5avXLegqym",
+	"tZZXUpXhfaUDsEIrHLVqY+BZH1ncAEcdamwHi7pNp9ahaZAWRONAHmT8egBuxPYp7bzUMcP0XGKmQzdU",
+	"tjg+bUupkI51LaDNOUlmksaDjjrCiL+9fPlc8MOB0DeZgmhYSzIeUaalsV64uqqkXY9oDyuJQ0d/bqyA",
+	"K5pVq84x0vmLzcFevThvPPx141xtjVVbfXZtdAF2Xpdn4eszMTcsqpmerYXyTnQmYiLOW0C+Aqk9buEz",
+	"EAVYmAPRWLAWv5PVCrmQxAfZ0uTdfm2QfOZsu1xpYxV7IjIwaFFnr7aLvuNyC98q6Px0BvOoo/yKER8z",
+	"90ehqeCmbJa9CqphoTJXByNlG6zYIGLHFF1dRlyzFT48AOtZga2kBu3LdYzgA/3uMFqMyhfAm3kbjA3p",
+	"vEXAE+v9AqTNl39Ti2WpFks/YuQctYKQP9mAWCjwwI8UooATM/DXAFpk9cnJvbyS9pL+4sTs1o4NVdyh",
+	"RrThQAToQMQn4q1WsJ93IXhnOsd5OCZIywFvd1n3rbVAQqS+jIHnJVzJnuNCLqQ3XZouFTguWLRCFJBa",
+	"fsX3hXNeGuljyBN2t49a1OItXgUnkqhO+1Pfx7eIZCNsir8P2hkHaxDZG8fD9p+W4JFRgUtETLDtLboR",
+	"Dd8RnGgM8ziG1KQAp3Lu0c03lnrFlxvghAU2OOildH5jzDELh/xJd4fsr8JC7krjjGQySKSupQuBUhAu",
+	"t3Yeqr4Q7Uxe4MOpmU9nyvrl9mCPpScm0eND1fjAkYP9P2yO/c2oHVvNaQPvXNjDRh43aIQ3TeOo5RN8",
+	"RvUX6ET6hhn4xa1s2liM/z19f2dTOZKFKuXohJ7K9zKf/iawywC06YJxuz4CT/QQhV+wk3AfU1XsI7SH",
+	"kiOQ2WBqOxNr1Ahbd1jXzvahGRmMhXLe0pjTIqQMDpPjEf/8JX79y9Z0mNCKiI3fUtAqlBgNlBP9/vZJ",
+	"kt45NTbY4WM8GyTDxuzsY5PXFcTwvIfih4t/PDt7+PxcFKGRWJqSC8pE2DPjm9++HbjxDkNxyuXejXLo",
+	"To7sKdzT2ETP9aq+bUzQGsUetvbNgwdpspLeg0Uu/ftf//5LlrnXv/9L88dvdjltmx1VSjefYwDzr6Jo",
+	"G7tdh9JxnE3hapB2owXLGMXpyolLWPl+jlsLC6tS5nA3Qd/rxbapil/miLFbeGcHLBRtsPvV5M+VhZyr",
+	"4oI/RibBgizOBLlVmUbgDBEXjiNVqO3CJhyfgEZtC9Fgpj8Nh47zWPuHHBDfjjjIqm2OyhVbY8N+gpmn",
+	"Q71ZFNG7WloSnWZeSfpLxbxvezfF/SBb3OVYKe/k5SEvUFpyp90OfY1x8Dmu517zXSnd//b0i0G/RfFN",
+	"lO+tuESklvwcjLU6+bWhuQi9bC6A9N6qWe3Bja+ltzXEapCHMM4rLsB4R4XgyjvRLzHs5jAWAtzB6+jg",
+	"2CaNwSr5+kCAkwjsMWBb1PFFpeeGSGZHNvlxLX5qENZQ93cF1jFbTicnkxMudgMtVyo5S+5NTicnCUn0",
+	"kmZ4LItK6WMC6/DzyriIDXq+hQi6Xk4wDntCMKV57c18PqGCK2DZOy8C4PUQByfMMmGmgPN/xYTOeAH7",
+	"LQvX+5DvzZD1KEmb5xa+OTl532MzfLNdOE+PAzrD0T1nwD9i7X6t4d0Kclw2zhRhk5CmaCkcrG6zzRDs",
+	"idU8uIDJa3zvuImPxsXo/9dQA1qEmcwvFxYzZVRdSmlsWRROcN6WbQdobxW4VDxIuUgg04FHKZe+bief",
+	"KaesDflevUQxKyqfpOGqP1lyJstBCbl3YmmuM11JvW7KHW2TTUfXlU8mGA3uz12WxkF4yj1iieS1XGNq",
+	"tyi6VtQdj4wzDQkl5USbrxPSi5Z3x2/MzB3/rIobzmdvq0xTgPGhFGaz4HX7bM83J9+8t+GwJHvkUAmX",
+	"WyLXUDvun5x8TM04DynQZl2aRUZSHpzc+9gHbELFhHKiyQALzABzMt1AOCbDx56wQhqliIIHOnLEeoJb",
+	"IukIFfn1xI98/qbOmEq5awuOT0J9gkapWZG2BJl89Z7hYAggolJI1AJ8LPfga6t5S0MNhVRQIMHVCkQF",
+	"FXl04vDGzLZ3tO+h1U6U6w+4texQmz6JrDj3P+bq/WBmJIxcBvFJStD34DeWkrzptswhOfvXz5FZnT+m",
+	"ZHNyRs5TA7GdseM29Cp2nZR8jbLZZsXdXpmUolTOd6ErSXu7iXHsSqGgLjAYVAzbTsRPiuoj4gcO2wAS",
+	"JR5PEg7rXrdOtSqX6eaAVHvE1QWKhmeFYvvW9+B/6ma8h9dPVaU8EaPbqL0dm0f86vTo9OTk62Y9KDvY",
+	"LUiJHQxOq7YyeHrSqy883VddeJNulf2t5NuaUC5nLDshPYSFzgvTanW+MVtX5UdoHSIvO4/YHkJMD3uJ",
+	"EdNCSSPUDECZ2xHzhAQRy21wqN86QcG0+CqXDo6UdqCd8uoKxhatAarvNKQUrp5xw36647eOkzUHE0G/",
+	"bkUD52h7LL5eGsfpA9x+mQlo96QK+0soV+LNXFWqlDbT3gjl2UusjPNtZrvnjeJSQhFAsLbiylJNDgVa",
+	"rHixab0dzGknBrGLyz22YmmzILQiKGkaKiUKAe9k7sv1CCn0zl2XuUcA1mDfiQB88T2Mr3slu2OyNLRB",
+	"BxcAH0hBW8kcG7x92I1/aMFzxM4gLLphSRhJAoshEFm4tsxphKLuhYipOwyCuiVdm/VX+wmL2L27UXbO",
+	"wHE8dI4R0SDNXV4+snkFFHMT5N8e/hH6rj2mxNBuSkT0IPPddDUI+C+jqqqkcICbv+/qrrxhM9bbpdap",
+	"AMkBN+5o3b0Umc6Soyxp31Eep4HD8M4ljC3Ash0N3aPp5GQu2eI007zgNtzw0SVByYOyTRVScx2BcpwW",
+	"aOOlTJMH9nZQoS6Ohr0aK466rgYPRXh91FrjzIaa2xZUDkcJu9VeaRxhfG+jpCmwj5UKWZbhWdXHXJir",
+	"ivJ4jHS0XtlEPBwkz9kut1cpZDog1SKXGktGA26BtqM0GsJBOXprQjuLsSK8MmnPp0wyjQQ8uvjn5r0S",
+	"gZqldKFqNjdlXe3YD5kBIzxWBTE2ZXHZIiPC8NcfMNBrM5gYwvS7qdxiJfPL99DTlS4mcqV+fzfC2rwV",
+	"duvhnT/O3dWwm8hdMZu5gkEWiyUNYfV+tMLeU/+MXu9IXqa3z+TxUYxvv/n2268nYvtamHxpjGMYO9ND",
+	"iTqjjEXvVFIq5HYSLiVZbCpFM81S11IH71bGcgb3R3BOLuC5zC/b9uIS1m1b7Hty90tgUryt4zJ6h8dl",
+	"m5nfe4Bxx4LdpMm9k/tjxLTCf9y/N4eghz8c9E7vpqBPFjAYBOHNSeZtlj+i7QSjdqf0omTzGs99DCLi",
+	"DwHldnUtByU+Tt/rwO/HYL1HY/U+DBVakeAwNCVOd7616XNRjkehwocZcjNEt47ZCo6CXBd0eqnZt7GH",
+	"kdKM2VoMnKjO50vpON2zx2SyvzI6FNitwNLNOl+jAX508c/gFJI7jy5MjnG6bmIq/j7TLTBFdhtKB02L",
+	"weaRhlNsnf/H49MgmQ4nJaW19K6y/ZtuUizzo5tAlvIKhNFwJiTlq0NWPNzhpjPde6u55YnyW/3ugrPT",
+	"RmHkoqEjFYHhvqPFOByJe8Lc2tzQrsBaVYDb5suYv0X9RKNRXZCcpgkq4+v0C870BWf6gjN9wZm+4Eyf",
+	"IM50uzj33ZEutn0Qv11XdndXjGaaCj4CjRLieGPlg5YScYnmWH63PQbD5sBmOq+9GxzqFW5prJ9w7vtz",
+	"8dB42++nxnsOmqrayxV3BjSd6PNtB43D9YjnePRyvQIRW390wDKNofKwbbPuX4d7JXHBcAXDLUSr0sgC",
+	"eAnDWOjOZVq54C5RaBN8prRxmrrMaM9HQnwKCeCKHy+tdwHFkw0aYM01bqa4sSvvglPlzniDpSIA2mFZ",
+	"sjiOSgcOWsq7REo7RtrAV1O6k6X7yBexpC0iNm1xpYA+btRn0oDNlEL9EhU3MYTFsEcgltqqhTZIm3Am",
+	"VFXp4Lg14BsvONotmV8OHNYWmui7l2beXf6IzcI1b7hMOPYlwIrhjwZv1WGEri6FHGF2XS00t+JxMfvw",
+	"luM+IQbXFvlZqsv2Ll3XRl9+aU29YHLp0hFpIQCBmW4ugZtkmkHDwVWbYREDF3AS7UV92Fv/KkxsGUak",
+	"E/H5skZExcz53lBeEJKqcDCedWcGhViChTRYpAbwzJgM6fFyP1OXBQu98uGy1l7pZOAhC+tEPDG2LSlD",
+	"tKq9Z5Lb0Y0bRAChq96q9ipoLc67ay6O/g5rmuU608yFN2w2qO39k5M/hw6b+KsrYRNygb5YIyPKDsMT",
+	"lgrHqLHSzqP5jYQg59VWCPLBsNPB7bEjW4ipPd1o09yMVnGM06nC+F2yv0JV2sOt1eSTnjg2UnP64GMX",
+	"prXCD4rsUjDVmoPuT3M/ZLkY2Q/5WoBRwOJJXZZHuHWF+wOGWZTZup9rItvSnPVk44JBV6bbqIt8EbYi",
+	"HmzlhrdSV7XzlI2hiANRAmwUTs6EDSxLfpcl7S0FVG3PGbP2/vY35ndZwoCFb8dprjPgixoz/bY2vn1/",
+	"aaWD9v0swz6W2Bbw7ywJ8WMzpgXRHoonO7nrqoSYTeDY9nBYgtuLxrUdi0THS7JuGZl+LgVJzUUAH7Ak",
+	"6VMOSG5nXoYXOexMWgW+3ipthfqY6faA3SBZtZkIOjS5Qzredbk3pfPpWeag2XHL3FTwsqhEsGQzby42",
+	"cAGOpjuL2qRz48XSzdUcZvAdNZNM/9Q4S/NBnWTvMrumEKA5vNpVQVL9JFZI9v7FBB99GbN4j4nK1uJt",
+	"O0H34zcMtfc2fPy6Xhp+UNh7//Sb/eFx5H9TfJKSxyvSZjHSfTW5vexed50u/W8LlIjzxxPRk6iu/DbT",
+	"PdPQBJsoP9Ga226X2eFR92trkw9cifDZJvV+aTLvbtnxX1uBP6P0PCsu6l+4g2SXA0mseF/l/DhWOHy8",
+	"MUoIidv/OMJ7Qld9Ffaoj7350Fnpocn4MLUHNNDHPnT5eZspEqn3UXvwf9L0fFYeB5uHnscRLqDZ9Djo",
+	"fhRHOADqNhSK+PEJ2JHafwwrcosKpi9W5IsV+Q+zIsE+jJVfYcx8HCJfJOKjukXRfOILJoZiqE2QiBGU",
+	"3n8ho+L97rpC+lzC3PczvXuKK8N4XyKjOxqLDjb5D7UWJ3/6Vf6bpnJ9yf9UjQ/JRmd8btqLQbZx6qCh",
+	"TsgZ5nav+3B/sDG9FMBNuqMHb7rT2xv/+S301F6Ssd0P3VgSsuxYf9l2271NTZKb1zf/OwAW4NnIWXgA",
+	"AA==",
 }
 
 // GetSwagger returns the content of the embedded swagger specification file
@@ -7,43 +7,548 @@ import (
 	"time"
 )
 
-// Error defines model for Error.
-type Error struct {
-	// Code Error code
-	Code int32 `json:"code"`
+// Defines values for ExportWonderfulsParamsFormat.
+const (
+	ExportWonderfulsParamsFormatCsv    ExportWonderfulsParamsFormat = "csv"
+	ExportWonderfulsParamsFormatNdjson ExportWonderfulsParamsFormat = "ndjson"
+)
+
+// Defines values for ExportWonderfulsParamsGender.
+const (
+	ExportWonderfulsParamsGenderFemale ExportWonderfulsParamsGender = "female"
+	ExportWonderfulsParamsGenderMale   ExportWonderfulsParamsGender = "male"
+)
+
+// Defines values for GetWonderfulsParamsGender.
+const (
+	GetWonderfulsParamsGenderFemale GetWonderfulsParamsGender = "female"
+	GetWonderfulsParamsGenderMale   GetWonderfulsParamsGender = "male"
+)
+
+// Defines values for JobState.
+const (
+	JobStateDone      JobState = "done"
+	JobStateFailed    JobState = "failed"
+	JobStateFetching  JobState = "fetching"
+	JobStateInserting JobState = "inserting"
+	JobStateQueued    JobState = "queued"
+)
+
+// Defines values for Nationality.
+const (
+	NationalityAU Nationality = "AU"
+	NationalityBR Nationality = "BR"
+	NationalityCA Nationality = "CA"
+	NationalityCH Nationality = "CH"
+	NationalityDE Nationality = "DE"
+	NationalityDK Nationality = "DK"
+	NationalityES Nationality = "ES"
+	NationalityFI Nationality = "FI"
+	NationalityFR Nationality = "FR"
+	NationalityGB Nationality = "GB"
+	NationalityIE Nationality = "IE"
+	NationalityIN Nationality = "IN"
+	NationalityIR Nationality = "IR"
+	NationalityMX Nationality = "MX"
+	NationalityNL Nationality = "NL"
+	NationalityNO Nationality = "NO"
+	NationalityNZ Nationality = "NZ"
+	NationalityRS Nationality = "RS"
+	NationalityTR Nationality = "TR"
+	NationalityUA Nationality = "UA"
+	NationalityUS Nationality = "US"
+)
+
+// Defines values for PopulateRequestGender.
+const (
+	PopulateRequestGenderFemale PopulateRequestGender = "female"
+	PopulateRequestGenderMale   PopulateRequestGender = "male"
+)
+
+// Defines values for PopulateRequestSource.
+const (
+	PopulateRequestSourceFile       PopulateRequestSource = "file"
+	PopulateRequestSourceRandomuser PopulateRequestSource = "randomuser"
+	PopulateRequestSourceSynthetic  PopulateRequestSource = "synthetic"
+)
+
+// Defines values for ProblemErrorCode.
+const (
+	ProblemErrorCodeIdempotencyKeyInProgress ProblemErrorCode = "idempotency_key_in_progress"
+	ProblemErrorCodeIdempotencyKeyReused     ProblemErrorCode = "idempotency_key_reused"
+	ProblemErrorCodeInternalError            ProblemErrorCode = "internal_error"
+	ProblemErrorCodeInvalidBody              ProblemErrorCode = "invalid_body"
+	ProblemErrorCodeInvalidCursor            ProblemErrorCode = "invalid_cursor"
+	ProblemErrorCodeInvalidIdempotencyKey    ProblemErrorCode = "invalid_idempotency_key"
+	ProblemErrorCodeInvalidParameter         ProblemErrorCode = "invalid_parameter"
+	ProblemErrorCodeJobNotFound              ProblemErrorCode = "job_not_found"
+	ProblemErrorCodeMethodNotAllowed         ProblemErrorCode = "method_not_allowed"
+	ProblemErrorCodeNotAcceptable            ProblemErrorCode = "not_acceptable"
+	ProblemErrorCodeNotFound                 ProblemErrorCode = "not_found"
+	ProblemErrorCodePreconditionFailed       ProblemErrorCode = "precondition_failed"
+	ProblemErrorCodeShuttingDown             ProblemErrorCode = "shutting_down"
+	ProblemErrorCodeUnsupportedMediaType     ProblemErrorCode = "unsupported_media_type"
+	ProblemErrorCodeUpstreamUnavailable      ProblemErrorCode = "upstream_unavailable"
+	ProblemErrorCodeUserNotDeleted           ProblemErrorCode = "user_not_deleted"
+	ProblemErrorCodeUserNotFound             ProblemErrorCode = "user_not_found"
+)
+
+// Defines values for UserResourceType.
+const (
+	UserResourceTypeUsers UserResourceType = "users"
+)
+
+// FieldError defines model for FieldError.
+type FieldError struct {
+	// Field Name of the query parameter or of the body field
+	Field  string `json:"field"`
+	Reason string `json:"reason"`
+}
+
+// ImportError defines model for ImportError.
+type ImportError struct {
+	// Field The invalid field, absent when the whole line is invalid
+	Field *string `json:"field,omitempty"`
+
+	// Line Line number in the body, starting at 1
+	Line   int    `json:"line"`
+	Reason string `json:"reason"`
+}
+
+// ImportReport defines model for ImportReport.
+type ImportReport struct {
+	// Errors Errors of the invalid lines, only the first 1,000 are returned
+	Errors []ImportError `json:"errors"`
+
+	// Failed Number of invalid lines
+	Failed int `json:"failed"`
+
+	// Inserted Number of users inserted
+	Inserted int `json:"inserted"`
+
+	// Skipped Number of valid users that did not change a stored one
+	Skipped int `json:"skipped"`
+
+	// Updated Number of users updated, by external_id
+	Updated int `json:"updated"`
+}
+
+// Job defines model for Job.
+type Job struct {
+	CreatedAt time.Time `json:"created_at"`
+
+	// Error Reason the job failed, absent unless failed
+	Error *string `json:"error,omitempty"`
+
+	// Fetched Number of users fetched from the Randomuser.com API
+	Fetched int32 `json:"fetched"`
+
+	// FinishedAt Time the job finished, absent until done or failed
+	FinishedAt *time.Time `json:"finished_at,omitempty"`
+	Id         string     `json:"id"`
+
+	// Inserted Number of new users inserted in the database
+	Inserted int32           `json:"inserted"`
+	Options  PopulateRequest `json:"options"`
+
+	// Skipped Number of users skipped, because they were already in the database unchanged or were repeated
+	Skipped int32    `json:"skipped"`
+	State   JobState `json:"state"`
+
+	// Updated Number of users already in the database that were updated
+	Updated   int32      `json:"updated"`
+	UpdatedAt *time.Time `json:"updated_at,omitempty"`
+}
+
+// JobState defines model for JobState.
+type JobState string
+
+// Location defines model for Location.
+type Location struct {
+	City         *string `json:"city,omitempty"`
+	Country      *string `json:"country,omitempty"`
+	Latitude     float64 `json:"latitude"`
+	Longitude    float64 `json:"longitude"`
+	Postcode     *string `json:"postcode,omitempty"`
+	State        *string `json:"state,omitempty"`
+	StreetName   *string `json:"street_name,omitempty"`
+	StreetNumber *int    `json:"street_number,omitempty"`
+}
+
+// NationalID defines model for NationalID.
+type NationalID struct {
+	// Name Name of the identifier
+	Name string `json:"name"`
+
+	// Value Value of the identifier, empty when it is unknown
+	Value string `json:"value"`
+}
+
+// Nationality defines model for Nationality.
+type Nationality string
+
+// PageLinks defines model for PageLinks.
+type PageLinks struct {
+	First string  `json:"first"`
+	Next  *string `json:"next,omitempty"`
+	Prev  *string `json:"prev,omitempty"`
+	Self  string  `json:"self"`
+}
+
+// PageMeta defines model for PageMeta.
+type PageMeta struct {
+	// HasMore See has_more of the UserList
+	HasMore bool `json:"has_more"`
+
+	// TotalCount Number of users matching the filters, only given with include_total
+	TotalCount *int64 `json:"total_count,omitempty"`
+}
+
+// Phone defines model for Phone.
+type Phone struct {
+	Cell *string `json:"cell,omitempty"`
+	Main *string `json:"main,omitempty"`
+}
+
+// Picture defines model for Picture.
+type Picture struct {
+	Large     *string `json:"large,omitempty"`
+	Medium    *string `json:"medium,omitempty"`
+	Thumbnail *string `json:"thumbnail,omitempty"`
+}
+
+// PopulateRequest defines model for PopulateRequest.
+type PopulateRequest struct {
+	// Count Number of users to add, 5000 by default
+	Count *int `json:"count,omitempty"`
+
+	// Gender Only add users of this gender
+	Gender *PopulateRequestGender `json:"gender,omitempty"`
+
+	// Nat Only add users of these nationalities
+	Nat *[]Nationality `json:"nat,omitempty"`
+
+	// Seed Seed that makes the added users reproducible
+	Seed *string `json:"seed,omitempty"`
+
+	// Source Where the users come from: the Randomuser.com API, the user file
+	// configured on the server or a deterministic generator. Defaults to
+	// the source configured on the server.
+	Source *PopulateRequestSource `json:"source,omitempty"`
+}
+
+// PopulateRequestGender defines model for PopulateRequest.Gender.
+type PopulateRequestGender string
+
+// PopulateRequestSource defines model for PopulateRequest.Source.
+type PopulateRequestSource string
+
+// Problem defines model for Problem.
+type Problem struct {
+	// Detail Explanation of this occurrence of the problem
+	Detail string `json:"detail"`
+
+	// ErrorCode Stable machine-readable code of the kind of problem. Clients can
+	// rely on it, the titles and details may change
+	ErrorCode ProblemErrorCode `json:"error_code"`
+
+	// Errors The invalid query parameters or body fields, absent unless some are known
+	Errors *[]FieldError `json:"errors,omitempty"`
+
+	// Instance Path and query of the request, as sent by the client
+	Instance string `json:"instance"`
+
+	// Status HTTP status code of the response
+	Status int `json:"status"`
+
+	// Title Short summary of the kind of problem, the same for every occurrence
+	Title string `json:"title"`
+
+	// Type URI identifying the kind of problem, urn:wonderful:problem: followed
+	// by its error_code. It is not meant to be dereferenced
+	Type string `json:"type"`
+}
+
+// ProblemErrorCode defines model for Problem.ErrorCode.
+type ProblemErrorCode string
+
+// PurgeRequest defines model for PurgeRequest.
+type PurgeRequest struct {
+	// DeletedBefore Users soft-deleted before this time are removed
+	DeletedBefore time.Time `json:"deleted_before"`
+}
+
+// PurgeResult defines model for PurgeResult.
+type PurgeResult struct {
+	// Purged Number of users permanently removed
+	Purged int64 `json:"purged"`
+}
+
+// ResourceLinks defines model for ResourceLinks.
+type ResourceLinks struct {
+	Self string `json:"self"`
+}
+
+// SearchHighlights defines model for SearchHighlights.
+type SearchHighlights struct {
+	Email string `json:"email"`
 
-	// Message Error message
-	Message string `json:"message"`
+	// Location Postal address of the user, absent unless populated
+	Location *string `json:"location,omitempty"`
+	Name     string  `json:"name"`
+}
+
+// SearchResult defines model for SearchResult.
+type SearchResult struct {
+	Highlights SearchHighlights `json:"highlights"`
+
+	// Rank Relevance of the user to the query, higher is more relevant
+	Rank float32 `json:"rank"`
+	User User    `json:"user"`
+}
+
+// SearchResults defines model for SearchResults.
+type SearchResults struct {
+	Data []SearchResult `json:"data"`
+
+	// HasMore Whether more results follow the page
+	HasMore bool `json:"has_more"`
+
+	// NextCursor Value of starting_after for the next page, absent on the last page
+	NextCursor *string `json:"next_cursor,omitempty"`
 }
 
 // User defines model for User.
 type User struct {
-	Email string `json:"email"`
-	Id    string `json:"id"`
-	Name  string `json:"name"`
-	Phone *struct {
-		Cell *string `json:"cell,omitempty"`
-		Main *string `json:"main,omitempty"`
-	} `json:"phone,omitempty"`
-	Picture *struct {
-		Large     *string `json:"large,omitempty"`
-		Medium    *string `json:"medium,omitempty"`
-		Thumbnail *string `json:"thumbnail,omitempty"`
-	} `json:"picture,omitempty"`
-	RegistrationDate time.Time `json:"registration_date"`
+	// CreatedAt Time the user was added to the system
+	CreatedAt time.Time `json:"created_at"`
+
+	// DateOfBirth Date of birth of the user, absent unless populated
+	DateOfBirth *time.Time `json:"date_of_birth,omitempty"`
+
+	// DeletedAt Time the user was soft-deleted, absent if not deleted
+	DeletedAt *time.Time `json:"deleted_at,omitempty"`
+	Email     string     `json:"email"`
+
+	// FirstName First name part of the name, absent unless populated
+	FirstName *string `json:"first_name,omitempty"`
+
+	// Gender Gender of the user, absent unless populated
+	Gender *string `json:"gender,omitempty"`
+	Id     string  `json:"id"`
+
+	// LastName Last name part of the name, absent unless populated
+	LastName *string   `json:"last_name,omitempty"`
+	Location *Location `json:"location,omitempty"`
+	Name     string    `json:"name"`
+
+	// Nat Nationality of the user, absent unless populated
+	Nat              *string     `json:"nat,omitempty"`
+	NationalId       *NationalID `json:"national_id,omitempty"`
+	Phone            *Phone      `json:"phone,omitempty"`
+	Picture          *Picture    `json:"picture,omitempty"`
+	RegistrationDate time.Time   `json:"registration_date"`
+
+	// Title Title part of the name, absent unless populated
+	Title *string `json:"title,omitempty"`
+
+	// UpdatedAt Last time the user was modified, absent if never modified
+	UpdatedAt *time.Time `json:"updated_at,omitempty"`
+}
+
+// UserDocument defines model for UserDocument.
+type UserDocument struct {
+	Data  UserResource  `json:"data"`
+	Links ResourceLinks `json:"links"`
+}
+
+// UserInput defines model for UserInput.
+type UserInput struct {
+	Email   string   `json:"email"`
+	Name    string   `json:"name"`
+	Phone   *Phone   `json:"phone,omitempty"`
+	Picture *Picture `json:"picture,omitempty"`
+
+	// RegistrationDate Defaults to the current time on create and is kept unchanged on replace
+	RegistrationDate *time.Time `json:"registration_date,omitempty"`
+}
+
+// UserList defines model for UserList.
+type UserList struct {
+	Data []User `json:"data"`
+
+	// HasMore Whether more users follow in the direction the page was read: after
+	// it, or before it when read with ending_before
+	HasMore bool `json:"has_more"`
+
+	// NextCursor Value of starting_after for the next page, absent on the last page
+	NextCursor *string `json:"next_cursor,omitempty"`
+
+	// PrevCursor Value of ending_before for the previous page, absent on the first page
+	PrevCursor *string `json:"prev_cursor,omitempty"`
+
+	// TotalCount Number of users matching the filters, only given with include_total
+	TotalCount *int64 `json:"total_count,omitempty"`
+}
+
+// UserListDocument defines model for UserListDocument.
+type UserListDocument struct {
+	Data  []UserResource `json:"data"`
+	Links PageLinks      `json:"links"`
+	Meta  PageMeta       `json:"meta"`
 }
 
+// UserPatch defines model for UserPatch.
+type UserPatch struct {
+	Email            *string    `json:"email,omitempty"`
+	Name             *string    `json:"name,omitempty"`
+	Phone            *Phone     `json:"phone,omitempty"`
+	Picture          *Picture   `json:"picture,omitempty"`
+	RegistrationDate *time.Time `json:"registration_date,omitempty"`
+}
+
+// UserResource defines model for UserResource.
+type UserResource struct {
+	// Attributes The fields of the User, except its id
+	Attributes map[string]interface{} `json:"attributes"`
+	Id         string                 `json:"id"`
+	Links      *ResourceLinks         `json:"links,omitempty"`
+	Type       UserResourceType       `json:"type"`
+}
+
+// UserResourceType defines model for UserResource.Type.
+type UserResourceType string
+
 // GetWonderfulsParams defines parameters for GetWonderfuls.
 type GetWonderfulsParams struct {
 	// Limit Limit the number of returned users (1-100)
 	Limit *int `form:"limit,omitempty" json:"limit,omitempty"`
 
-	// StartingAfter User ID to start pagination after
+	// StartingAfter Opaque cursor from next_cursor, to list the users after it
 	StartingAfter *string `form:"starting_after,omitempty" json:"starting_after,omitempty"`
 
-	// EndingBefore User ID to start pagination before
+	// EndingBefore Opaque cursor from prev_cursor, to list the users before it
 	EndingBefore *string `form:"ending_before,omitempty" json:"ending_before,omitempty"`
 
 	// Email Filter by user's email (case-insensitive)
 	Email *string `form:"email,omitempty" json:"email,omitempty"`
+
+	// Name Filter by a substring of the user's name (case-insensitive)
+	Name *string `form:"name,omitempty" json:"name,omitempty"`
+
+	// Q Search the users whose name or email contains the query or is similar
+	// to it. The most relevant users are listed first unless sort is given.
+	Q *string `form:"q,omitempty" json:"q,omitempty"`
+
+	// Phone Filter by the user's main phone number, matched exactly
+	Phone *string `form:"phone,omitempty" json:"phone,omitempty"`
+
+	// Cell Filter by the user's cell phone number, matched exactly
+	Cell *string `form:"cell,omitempty" json:"cell,omitempty"`
+
+	// Nat Filter by the user's nationality
+	Nat *Nationality `form:"nat,omitempty" json:"nat,omitempty"`
+
+	// Gender Filter by the user's gender
+	Gender *GetWonderfulsParamsGender `form:"gender,omitempty" json:"gender,omitempty"`
+
+	// RegisteredAfter Only list the users registered after this time
+	RegisteredAfter *time.Time `form:"registered_after,omitempty" json:"registered_after,omitempty"`
+
+	// RegisteredBefore Only list the users registered before this time
+	RegisteredBefore *time.Time `form:"registered_before,omitempty" json:"registered_before,omitempty"`
+
+	// IncludeDeleted Include soft-deleted users
+	IncludeDeleted *bool `form:"include_deleted,omitempty" json:"include_deleted,omitempty"`
+
+	// IncludeTotal Count the users matching the filters in total_count
+	IncludeTotal *bool `form:"include_total,omitempty" json:"include_total,omitempty"`
+
+	// Sort Comma separated fields to sort the users by, each one prefixed with
+	// "-" to sort it in descending order. The fields are name, email,
+	// registration, created_at and relevance, which is only available
+	// with q. Defaults to -registration, or -relevance,-registration with q.
+	Sort *string `form:"sort,omitempty" json:"sort,omitempty"`
+
+	// Fields Comma separated fields of the users to return, all of them by
+	// default. The id is always returned. A part of the phone or of the
+	// picture can be requested alone, e.g. phone.main or picture.thumbnail.
+	// The CSV representation always has every column.
+	Fields *string `form:"fields,omitempty" json:"fields,omitempty"`
+}
+
+// GetWonderfulsParamsGender defines parameters for GetWonderfuls.
+type GetWonderfulsParamsGender string
+
+// ExportWonderfulsParams defines parameters for ExportWonderfuls.
+type ExportWonderfulsParams struct {
+	// Format Format of the export, overrides the Accept header
+	Format *ExportWonderfulsParamsFormat `form:"format,omitempty" json:"format,omitempty"`
+
+	// Email Filter by user's email (case-insensitive)
+	Email *string `form:"email,omitempty" json:"email,omitempty"`
+
+	// Name Filter by a substring of the user's name (case-insensitive)
+	Name *string `form:"name,omitempty" json:"name,omitempty"`
+
+	// Q Search the users whose name or email contains the query or is similar
+	// to it. The most relevant users are listed first unless sort is given.
+	Q *string `form:"q,omitempty" json:"q,omitempty"`
+
+	// Phone Filter by the user's main phone number, matched exactly
+	Phone *string `form:"phone,omitempty" json:"phone,omitempty"`
+
+	// Cell Filter by the user's cell phone number, matched exactly
+	Cell *string `form:"cell,omitempty" json:"cell,omitempty"`
+
+	// Nat Filter by the user's nationality
+	Nat *Nationality `form:"nat,omitempty" json:"nat,omitempty"`
+
+	// Gender Filter by the user's gender
+	Gender *ExportWonderfulsParamsGender `form:"gender,omitempty" json:"gender,omitempty"`
+
+	// RegisteredAfter Only list the users registered after this time
+	RegisteredAfter *time.Time `form:"registered_after,omitempty" json:"registered_after,omitempty"`
+
+	// RegisteredBefore Only list the users registered before this time
+	RegisteredBefore *time.Time `form:"registered_before,omitempty" json:"registered_before,omitempty"`
+
+	// IncludeDeleted Include soft-deleted users
+	IncludeDeleted *bool `form:"include_deleted,omitempty" json:"include_deleted,omitempty"`
 }
+
+// ExportWonderfulsParamsFormat defines parameters for ExportWonderfuls.
+type ExportWonderfulsParamsFormat string
+
+// ExportWonderfulsParamsGender defines parameters for ExportWonderfuls.
+type ExportWonderfulsParamsGender string
+
+// SearchWonderfulsParams defines parameters for SearchWonderfuls.
+type SearchWonderfulsParams struct {
+	// Q Search query
+	Q string `form:"q" json:"q"`
+
+	// Limit Limit the number of returned users (1-100)
+	Limit *int `form:"limit,omitempty" json:"limit,omitempty"`
+
+	// StartingAfter Opaque cursor from next_cursor, to list the results after it
+	StartingAfter *string `form:"starting_after,omitempty" json:"starting_after,omitempty"`
+
+	// IncludeDeleted Include soft-deleted users
+	IncludeDeleted *bool `form:"include_deleted,omitempty" json:"include_deleted,omitempty"`
+}
+
+// PostAdminPurgeJSONRequestBody defines body for PostAdminPurge for application/json ContentType.
+type PostAdminPurgeJSONRequestBody = PurgeRequest
+
+// PostPopulateJSONRequestBody defines body for PostPopulate for application/json ContentType.
+type PostPopulateJSONRequestBody = PopulateRequest
+
+// PostWonderfulsJSONRequestBody defines body for PostWonderfuls for application/json ContentType.
+type PostWonderfulsJSONRequestBody = UserInput
+
+// PatchWonderfulJSONRequestBody defines body for PatchWonderful for application/json ContentType.
+type PatchWonderfulJSONRequestBody = UserPatch
+
+// PutWonderfulJSONRequestBody defines body for PutWonderful for application/json ContentType.
+type PutWonderfulJSONRequestBody = UserInput
@@ -0,0 +1,74 @@
+package v1
+
+import (
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// pageURLs returns the URLs of the first, next and previous pages of a
+// listing. They keep the path and the query parameters of the request and
+// only change its cursor. The URLs of the empty cursors are empty.
+func pageURLs(r *http.Request, nextCursor, prevCursor string) (first, next, prev string) {
+	pageURL := func(cursorParam, cursor string) string {
+		q := r.URL.Query()
+		q.Del("starting_after")
+		q.Del("ending_before")
+		if cursorParam != "" {
+			q.Set(cursorParam, cursor)
+		}
+		u := url.URL{Path: requestPath(r), RawQuery: q.Encode()}
+		return u.String()
+	}
+	first = pageURL("", "")
+	if nextCursor != "" {
+		next = pageURL("starting_after", nextCursor)
+	}
+	if prevCursor != "" {
+		prev = pageURL("ending_before", prevCursor)
+	}
+	return first, next, prev
+}
+
+// pageLinks returns the RFC 8288 Link header value pointing to the first,
+// next and previous pages of a listing, see pageURLs.
+func pageLinks(r *http.Request, nextCursor, prevCursor string) string {
+	first, next, prev := pageURLs(r, nextCursor, prevCursor)
+	link := func(rel, u string) string {
+		return "<" + u + `>; rel="` + rel + `"`
+	}
+	links := []string{link("first", first)}
+	if next != "" {
+		links = append(links, link("next", next))
+	}
+	if prev != "" {
+		links = append(links, link("prev", prev))
+	}
+	return strings.Join(links, ", ")
+}
+
+// requestPath returns the path of a request as the client sent it, with the
+// prefix the API is mounted at, e.g. "/api/v1/wonderfuls", which
+// http.StripPrefix removed from its URL.
+func requestPath(r *http.Request) string {
+	if r.RequestURI == "" {
+		return r.URL.Path
+	}
+	u, err := url.ParseRequestURI(r.RequestURI)
+	if err != nil || u.Path == "" {
+		return r.URL.Path
+	}
+	return u.Path
+}
+
+// requestURI returns the path and the query of a request as the client sent them, see requestPath.
+func requestURI(r *http.Request) string {
+	u := url.URL{Path: requestPath(r), RawQuery: r.URL.RawQuery}
+	return u.RequestURI()
+}
+
+// apiPath returns the path of a resource of the API, with the prefix the
+// request was sent to, e.g. "/api/v1/wonderfuls/1" for "/wonderfuls/1".
+func apiPath(r *http.Request, path string) string {
+	return strings.TrimSuffix(requestPath(r), r.URL.Path) + path
+}
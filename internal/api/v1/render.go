@@ -0,0 +1,241 @@
+package v1
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+
+	"github.com/vmihailenco/msgpack/v5"
+
+	"wonderful/internal/api/v1/openapi"
+	"wonderful/internal/entities"
+)
+
+// writeUserList writes a page of users in the representation of the media
+// type, see userMediaType, with only the given fields when there are some. An
+// error is only returned before the response is written.
+func writeUserList(w http.ResponseWriter, r *http.Request, mediaType string, page *entities.UserPage, fields []string) error {
+	switch mediaType {
+	case mediaTypeCSV:
+		writeCSV(w, http.StatusOK, fields, page.Users...)
+		return nil
+	case mediaTypeJSONAPI:
+		list := toOpenAPIUserList(page)
+		first, next, prev := pageURLs(r, page.NextCursor, page.PrevCursor)
+		doc := openapi.UserListDocument{
+			Data:  make([]openapi.UserResource, 0, len(list.Data)),
+			Links: openapi.PageLinks{Self: requestURI(r), First: first, Next: optional(next), Prev: optional(prev)},
+			Meta:  openapi.PageMeta{HasMore: list.HasMore, TotalCount: list.TotalCount},
+		}
+		for i := range list.Data {
+			res, err := toUserResource(r, &list.Data[i], fields)
+			if err != nil {
+				return err
+			}
+			doc.Data = append(doc.Data, res)
+		}
+		return writeValue(w, http.StatusOK, mediaType, doc)
+	}
+	if len(fields) > 0 {
+		list, err := toSparseUserList(page, fields)
+		if err != nil {
+			return err
+		}
+		return writeValue(w, http.StatusOK, mediaType, list)
+	}
+	return writeValue(w, http.StatusOK, mediaType, toOpenAPIUserList(page))
+}
+
+// writeUser writes a user in the representation of the media type, see
+// userMediaType, with its ETag. An error is only returned before the response
+// is written.
+func writeUser(w http.ResponseWriter, r *http.Request, mediaType string, status int, user *entities.User) error {
+	w.Header().Set("ETag", userETag(user, mediaType))
+	switch mediaType {
+	case mediaTypeCSV:
+		writeCSV(w, status, nil, *user)
+		return nil
+	case mediaTypeJSONAPI:
+		u := toOpenAPIUser(user)
+		res, err := toUserResource(r, &u, nil)
+		if err != nil {
+			return err
+		}
+		return writeValue(w, status, mediaType, openapi.UserDocument{Data: res, Links: *res.Links})
+	}
+	return writeValue(w, status, mediaType, toOpenAPIUser(user))
+}
+
+// userMediaType returns the representation of the users negotiated for the
+// request, JSON when none is acceptable, which the Negotiation middleware
+// has already rejected.
+func userMediaType(w http.ResponseWriter, r *http.Request) string {
+	w.Header().Add("Vary", "Accept")
+	if mediaType := negotiate(r, userMediaTypes...); mediaType != "" {
+		return mediaType
+	}
+	return mediaTypeJSON
+}
+
+// userPath returns the path of a user, with the prefix of the API like the page links.
+func userPath(r *http.Request, id string) string {
+	return apiPath(r, "/wonderfuls/"+url.PathEscape(id))
+}
+
+// toUserResource converts a user to a JSON:API resource object, with only the
+// given fields in its attributes when there are some.
+func toUserResource(r *http.Request, user *openapi.User, fields []string) (openapi.UserResource, error) {
+	res := openapi.UserResource{
+		Type:  openapi.UserResourceTypeUsers,
+		Id:    user.Id,
+		Links: &openapi.ResourceLinks{Self: userPath(r, user.Id)},
+	}
+	var err error
+	if len(fields) > 0 {
+		res.Attributes, err = sparseUser(user, fields)
+	} else {
+		err = marshalTo(user, &res.Attributes)
+	}
+	if err != nil {
+		return res, err
+	}
+	delete(res.Attributes, "id")
+	return res, nil
+}
+
+// writeCSV writes users as CSV, with the columns of csvHeader, only the ones
+// of the given fields and the ID when there are some, see csvColumns.
+func writeCSV(w http.ResponseWriter, status int, fields []string, users ...entities.User) {
+	columns := csvColumns(fields)
+	w.Header().Set("Content-Type", "text/csv; charset=utf-8")
+	w.WriteHeader(status)
+	cw := csv.NewWriter(w)
+	cw.Write(selectColumns(csvHeader, columns)) //nolint:errcheck //ignore error, returned by Flush
+	for i := range users {
+		cw.Write(selectColumns(csvRecord(&users[i]), columns)) //nolint:errcheck //ignore error, returned by Flush
+	}
+	cw.Flush()
+}
+
+// csvFieldColumns maps the fields of a user to their flattened CSV columns.
+var csvFieldColumns = map[string][]string{
+	"id":                {"id"},
+	"name":              {"name"},
+	"email":             {"email"},
+	"phone":             {"phone", "cell"},
+	"phone.main":        {"phone"},
+	"phone.cell":        {"cell"},
+	"picture":           {"picture_large", "picture_medium", "picture_thumbnail"},
+	"picture.large":     {"picture_large"},
+	"picture.medium":    {"picture_medium"},
+	"picture.thumbnail": {"picture_thumbnail"},
+	"registration_date": {"registration_date"},
+	"created_at":        {"created_at"},
+	"updated_at":        {"updated_at"},
+	"deleted_at":        {"deleted_at"},
+	"gender":            {"gender"},
+	"title":             {"title"},
+	"first_name":        {"first_name"},
+	"last_name":         {"last_name"},
+	"date_of_birth":     {"date_of_birth"},
+	"nat":               {"nat"},
+	"national_id":       {"national_id_name", "national_id_value"},
+	"location":          {"street_number", "street_name", "city", "state", "country", "postcode", "latitude", "longitude"},
+}
+
+// csvColumns returns the indexes in csvHeader of the columns of the fields
+// and of the ID, in the order of the header, or nil for all of them when
+// there are no fields.
+func csvColumns(fields []string) []int {
+	if len(fields) == 0 {
+		return nil
+	}
+	selected := map[string]bool{"id": true}
+	for _, f := range fields {
+		for _, column := range csvFieldColumns[f] {
+			selected[column] = true
+		}
+	}
+	columns := make([]int, 0, len(selected))
+	for i, column := range csvHeader {
+		if selected[column] {
+			columns = append(columns, i)
+		}
+	}
+	return columns
+}
+
+// selectColumns returns the values of a CSV row at the indexes, or all of them when there are none.
+func selectColumns(row []string, columns []int) []string {
+	if columns == nil {
+		return row
+	}
+	selected := make([]string, 0, len(columns))
+	for _, i := range columns {
+		selected = append(selected, row[i])
+	}
+	return selected
+}
+
+// writeValue writes v as JSON, or as MessagePack with the same keys and values.
+func writeValue(w http.ResponseWriter, status int, mediaType string, v any) error {
+	if mediaType == mediaTypeMsgPack {
+		b, err := marshalMsgPack(v)
+		if err != nil {
+			return err
+		}
+		w.Header().Set("Content-Type", mediaType)
+		w.WriteHeader(status)
+		w.Write(b) //nolint:errcheck //ignore error
+		return nil
+	}
+	w.Header().Set("Content-Type", mediaType)
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v) //nolint:errcheck //ignore error
+	return nil
+}
+
+// marshalMsgPack encodes v as MessagePack through its JSON representation, so
+// that the keys and the values, e.g. the RFC 3339 times, are the same in both
+// formats. The integers are kept as integers.
+func marshalMsgPack(v any) ([]byte, error) {
+	b, err := json.Marshal(v)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal: %w", err)
+	}
+	d := json.NewDecoder(bytes.NewReader(b))
+	d.UseNumber()
+	var generic any
+	if err := d.Decode(&generic); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal: %w", err)
+	}
+	b, err = msgpack.Marshal(fromJSONNumbers(generic))
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal MessagePack: %w", err)
+	}
+	return b, nil
+}
+
+// fromJSONNumbers replaces the JSON numbers of a decoded JSON value with int64 or float64 values.
+func fromJSONNumbers(v any) any {
+	switch v := v.(type) {
+	case json.Number:
+		if n, err := v.Int64(); err == nil {
+			return n
+		}
+		f, _ := v.Float64()
+		return f
+	case map[string]any:
+		for k, e := range v {
+			v[k] = fromJSONNumbers(e)
+		}
+	case []any:
+		for i, e := range v {
+			v[i] = fromJSONNumbers(e)
+		}
+	}
+	return v
+}
@@ -0,0 +1,161 @@
+package v1
+
+import (
+	"errors"
+	"fmt"
+	"mime"
+	"net/http"
+	"slices"
+	"strings"
+
+	"github.com/getkin/kin-openapi/openapi3"
+	"github.com/getkin/kin-openapi/openapi3filter"
+	"github.com/getkin/kin-openapi/routers"
+	"github.com/getkin/kin-openapi/routers/gorillamux"
+
+	"wonderful/internal/api/v1/openapi"
+)
+
+// errUnsupportedContentType is an error when the body of a request is of a media type the API does not read.
+var errUnsupportedContentType = errors.New("unsupported content type")
+
+// prefixInvalidContentType prefixes the reason of the errors of the validator
+// for the bodies of a media type the spec does not describe.
+const prefixInvalidContentType = "header Content-Type has unexpected value"
+
+// RequestValidator returns a middleware that validates the requests against
+// the spec: the route, the parameters and the body. The invalid requests are
+// rejected with all their errors, see SendRequestError.
+func RequestValidator(swagger *openapi3.T) (func(http.Handler) http.Handler, error) {
+	router, err := gorillamux.NewRouter(swagger)
+	if err != nil {
+		return nil, fmt.Errorf("failed to route the spec: %w", err)
+	}
+	options := &openapi3filter.Options{MultiError: true}
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			route, pathParams, err := router.FindRoute(r)
+			if err != nil {
+				SendRequestError(w, r, err)
+				return
+			}
+			// the validator sets the defaults of the missing parameters in the
+			// query, the links and problems keep the one of the client, the
+			// services apply the same defaults.
+			rawQuery := r.URL.RawQuery
+			input := &openapi3filter.RequestValidationInput{Request: r, PathParams: pathParams, Route: route, Options: options}
+			err = openapi3filter.ValidateRequest(r.Context(), input)
+			r.URL.RawQuery = rawQuery
+			if err != nil {
+				SendRequestError(w, r, err)
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}, nil
+}
+
+// AllowContentType returns a middleware that rejects the requests with a
+// body of another media type than the given ones with 415 Unsupported Media
+// Type. The bodies the spec describes are already checked by RequestValidator.
+func AllowContentType(contentTypes ...string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.ContentLength == 0 {
+				next.ServeHTTP(w, r)
+				return
+			}
+			mediaType, _, err := mime.ParseMediaType(r.Header.Get("Content-Type"))
+			if err != nil || !slices.Contains(contentTypes, mediaType) {
+				SendRequestError(w, r, fmt.Errorf("%w %q, expected one of %s",
+					errUnsupportedContentType, r.Header.Get("Content-Type"), strings.Join(contentTypes, ", ")))
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// SendRequestError sends the problem of a request rejected before its
+// handler runs: by RequestValidator, by AllowContentType or by the binding of
+// its parameters, as the ErrorHandlerFunc of the generated router.
+func SendRequestError(w http.ResponseWriter, r *http.Request, err error) {
+	var reqErr *openapi3filter.RequestError
+	switch {
+	case errors.Is(err, routers.ErrPathNotFound):
+		sendAPIError(w, r, problemNotFound, "No endpoint at "+requestPath(r), err)
+	case errors.Is(err, routers.ErrMethodNotAllowed):
+		sendAPIError(w, r, problemMethodNotAllowed, "Method "+r.Method+" is not allowed at "+requestPath(r), err)
+	case errors.Is(err, errUnsupportedContentType):
+		sendAPIError(w, r, problemUnsupportedMediaType, err.Error(), err)
+	case errors.As(err, &reqErr) && strings.HasPrefix(reqErr.Reason, prefixInvalidContentType):
+		sendAPIError(w, r, problemUnsupportedMediaType, reqErr.Reason, err)
+	case errors.As(err, &reqErr) && reqErr.Parameter == nil:
+		sendAPIError(w, r, problemInvalidBody, "Invalid request body", err)
+	default:
+		sendAPIError(w, r, problemInvalidParameter, "Invalid parameters", err)
+	}
+}
+
+// requestFieldErrors returns the field or parameter of an error of the
+// validation or of the binding of the parameters of a request.
+func requestFieldErrors(err error) []openapi.FieldError {
+	var (
+		reqErr    *openapi3filter.RequestError
+		formatErr *openapi.InvalidParamFormatError
+		required  *openapi.RequiredParamError
+		tooMany   *openapi.TooManyValuesForParamError
+		unmarshal *openapi.UnmarshalingParamError
+		header    *openapi.RequiredHeaderError
+	)
+	switch {
+	case errors.As(err, &reqErr) && reqErr.Parameter != nil:
+		return []openapi.FieldError{{Field: reqErr.Parameter.Name, Reason: requestErrorReason(reqErr)}}
+	case errors.As(err, &reqErr) && reqErr.Err != nil:
+		// the body, each error of its schema is about one of its fields.
+		return schemaFieldErrors(reqErr.Err)
+	case errors.As(err, &formatErr):
+		return []openapi.FieldError{{Field: formatErr.ParamName, Reason: formatErr.Err.Error()}}
+	case errors.As(err, &required):
+		return []openapi.FieldError{{Field: required.ParamName, Reason: "value is required but missing"}}
+	case errors.As(err, &tooMany):
+		return []openapi.FieldError{{Field: tooMany.ParamName, Reason: fmt.Sprintf("expected one value, got %d", tooMany.Count)}}
+	case errors.As(err, &unmarshal):
+		return []openapi.FieldError{{Field: unmarshal.ParamName, Reason: unmarshal.Err.Error()}}
+	case errors.As(err, &header):
+		return []openapi.FieldError{{Field: header.ParamName, Reason: "header is required but missing"}}
+	}
+	return nil
+}
+
+// requestErrorReason returns the reason of the error of a parameter, the one
+// of its schema when it breaks it, e.g. "number must be at most 100".
+func requestErrorReason(reqErr *openapi3filter.RequestError) string {
+	var schemaErr *openapi3.SchemaError
+	if errors.As(reqErr.Err, &schemaErr) {
+		return schemaErr.Reason
+	}
+	if reqErr.Err != nil {
+		return reqErr.Err.Error()
+	}
+	return reqErr.Reason
+}
+
+// schemaFieldErrors returns the fields of the errors of the schema of a body,
+// named by their JSON pointer with dots, e.g. "picture.large". The errors of
+// the whole body have no field.
+func schemaFieldErrors(err error) []openapi.FieldError {
+	var multi openapi3.MultiError
+	if errors.As(err, &multi) {
+		var fields []openapi.FieldError
+		for _, e := range multi {
+			fields = append(fields, schemaFieldErrors(e)...)
+		}
+		return fields
+	}
+	var schemaErr *openapi3.SchemaError
+	if errors.As(err, &schemaErr) && len(schemaErr.JSONPointer()) > 0 {
+		return []openapi.FieldError{{Field: strings.Join(schemaErr.JSONPointer(), "."), Reason: schemaErr.Reason}}
+	}
+	return nil
+}
@@ -0,0 +1,113 @@
+package v1_test
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	api "wonderful/internal/api/v1"
+	"wonderful/internal/api/v1/openapi"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/stretchr/testify/require"
+)
+
+// validatedAPI answers 501 Not Implemented, the requests reaching its
+// handlers were not rejected.
+type validatedAPI struct {
+	openapi.Unimplemented
+}
+
+func TestRequestValidator(t *testing.T) {
+	swagger, err := openapi.GetSwagger()
+	require.NoError(t, err)
+	swagger.Servers = nil
+	validator, err := api.RequestValidator(swagger)
+	require.NoError(t, err)
+	r := chi.NewRouter()
+	r.Use(validator)
+	r.Use(api.AllowContentType("application/json", "application/x-ndjson", "text/csv"))
+	server := httptest.NewServer(openapi.HandlerWithOptions(validatedAPI{}, openapi.ChiServerOptions{
+		BaseRouter:       r,
+		ErrorHandlerFunc: api.SendRequestError,
+	}))
+	defer server.Close()
+
+	for _, tc := range []struct {
+		name, method, path, contentType, body string
+		status                                int
+		code                                  openapi.ProblemErrorCode
+		errors                                []openapi.FieldError
+	}{
+		{
+			name: "bounds", method: http.MethodGet, path: "/wonderfuls?limit=0&gender=other",
+			status: http.StatusBadRequest, code: openapi.ProblemErrorCodeInvalidParameter,
+			errors: []openapi.FieldError{
+				{Field: "limit", Reason: "number must be at least 1"},
+				{Field: "gender", Reason: `value is not one of the allowed values ["male","female"]`},
+			},
+		},
+		{
+			name: "maximum", method: http.MethodGet, path: "/wonderfuls/search?limit=150&q=doe",
+			status: http.StatusBadRequest, code: openapi.ProblemErrorCodeInvalidParameter,
+			errors: []openapi.FieldError{{Field: "limit", Reason: "number must be at most 100"}},
+		},
+		{
+			name: "type", method: http.MethodGet, path: "/wonderfuls?limit=ten",
+			status: http.StatusBadRequest, code: openapi.ProblemErrorCodeInvalidParameter,
+			errors: []openapi.FieldError{{Field: "limit", Reason: "value ten: an invalid integer: invalid syntax"}},
+		},
+		{
+			name: "body", method: http.MethodPost, path: "/populate", contentType: "application/json", body: `{"count": 0}`,
+			status: http.StatusBadRequest, code: openapi.ProblemErrorCodeInvalidBody,
+			errors: []openapi.FieldError{{Field: "count", Reason: "number must be at least 1"}},
+		},
+		{
+			name: "content type", method: http.MethodPost, path: "/populate", contentType: "text/plain", body: "count=1",
+			status: http.StatusUnsupportedMediaType, code: openapi.ProblemErrorCodeUnsupportedMediaType,
+		},
+		{
+			// no body is described, only AllowContentType checks it
+			name: "unexpected body", method: http.MethodGet, path: "/wonderfuls", contentType: "text/plain", body: "count=1",
+			status: http.StatusUnsupportedMediaType, code: openapi.ProblemErrorCodeUnsupportedMediaType,
+		},
+		{
+			name: "not found", method: http.MethodGet, path: "/nowhere",
+			status: http.StatusNotFound, code: openapi.ProblemErrorCodeNotFound,
+		},
+		{
+			name: "method", method: http.MethodPatch, path: "/wonderfuls",
+			status: http.StatusMethodNotAllowed, code: openapi.ProblemErrorCodeMethodNotAllowed,
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			req, err := http.NewRequestWithContext(context.Background(), tc.method, server.URL+tc.path, strings.NewReader(tc.body))
+			require.NoError(t, err)
+			if tc.contentType != "" {
+				req.Header.Set("Content-Type", tc.contentType)
+			}
+			res, err := server.Client().Do(req)
+			require.NoError(t, err)
+			defer res.Body.Close()
+			b, err := io.ReadAll(res.Body)
+			require.NoError(t, err)
+			require.Equal(t, tc.status, res.StatusCode, string(b))
+			require.Equal(t, "application/problem+json", res.Header.Get("Content-Type"))
+			var problem openapi.Problem
+			require.NoError(t, json.Unmarshal(b, &problem))
+			require.Equal(t, tc.code, problem.ErrorCode)
+			require.Equal(t, tc.status, problem.Status)
+			require.Equal(t, tc.path, problem.Instance)
+			if tc.errors == nil {
+				require.Nil(t, problem.Errors)
+				return
+			}
+			require.NotNil(t, problem.Errors)
+			require.Equal(t, tc.errors, *problem.Errors)
+		})
+	}
+}
@@ -5,10 +5,153 @@ import "time"
 // User is a struct that holds the user information.
 type User struct {
 	ID           string
+	ExternalID   string // ID of the user in the source it was populated from, if any
 	Name         string
 	Email        string
 	Phone        string
 	Cell         string
 	Picture      map[string]string
 	Registration time.Time
+	CreatedAt    time.Time
+	UpdatedAt    *time.Time
+	DeletedAt    *time.Time
+	// The profile below is only known for populated users.
+	Gender      string
+	Title       string
+	FirstName   string
+	LastName    string
+	DateOfBirth *time.Time
+	Nat         string
+	NationalID  *NationalID
+	Location    *Location
+}
+
+// NationalID is a struct that holds a national identifier of a user, e.g. a SSN.
+type NationalID struct {
+	Name  string
+	Value string
+}
+
+// Location is a struct that holds the postal address and coordinates of a user.
+type Location struct {
+	StreetNumber int
+	StreetName   string
+	City         string
+	State        string
+	Country      string
+	Postcode     string
+	Latitude     float64
+	Longitude    float64
+}
+
+// UserPage is a struct that holds a page of users, in the order of the
+// listing, and the cursors of the pages around it. HasMore tells whether
+// more users follow in the direction the page was read: after it, or before it
+// when read with ending_before. The cursors are empty when there is no such page.
+type UserPage struct {
+	Users      []User
+	HasMore    bool
+	NextCursor string
+	PrevCursor string
+	TotalCount *int64 // only set on request
+}
+
+// SearchResult is a struct that holds a user found by a search and how well
+// it matches. The headlines are its fields with the matches between <mark> tags.
+type SearchResult struct {
+	User             User
+	Rank             float32
+	NameHeadline     string
+	EmailHeadline    string
+	LocationHeadline string
+}
+
+// SearchPage is a struct that holds a page of search results, the most
+// relevant first, and the cursor of the next page, empty on the last page.
+type SearchPage struct {
+	Results    []SearchResult
+	HasMore    bool
+	NextCursor string
+}
+
+// UserPatch holds the user fields to change in a partial update. Nil fields
+// are left untouched.
+type UserPatch struct {
+	Name         *string
+	Email        *string
+	Phone        *string
+	Cell         *string
+	Picture      map[string]string
+	Registration *time.Time
+}
+
+// JobState is the state of a background populate job.
+type JobState string
+
+// The states a populate job goes through. Done and failed are final.
+const (
+	JobStateQueued    JobState = "queued"
+	JobStateFetching  JobState = "fetching"
+	JobStateInserting JobState = "inserting"
+	JobStateDone      JobState = "done"
+	JobStateFailed    JobState = "failed"
+)
+
+// CreateResult is a struct that holds how many users a bulk create inserted,
+// updated and skipped because they did not change.
+type CreateResult struct {
+	Inserted int
+	Updated  int
+	Skipped  int
+}
+
+// ImportError is a struct that holds why a line of an import was rejected.
+// Field is the API name of the invalid field, empty when the whole line is.
+type ImportError struct {
+	Line   int
+	Field  string
+	Reason string
+}
+
+// ImportReport is a struct that holds the outcome of an import: how many
+// users were inserted, updated and skipped, and how many lines failed. Only
+// the first errors are kept.
+type ImportReport struct {
+	CreateResult
+	Failed int
+	Errors []ImportError
+}
+
+// Job is a struct that holds the state of a background populate job.
+type Job struct {
+	ID         string
+	State      JobState
+	Fetched    int
+	Inserted   int
+	Updated    int
+	Skipped    int
+	Error      string
+	CreatedAt  time.Time
+	UpdatedAt  *time.Time
+	FinishedAt *time.Time
+	Options    PopulateOptions
+}
+
+// PopulateOptions is a struct that holds what a populate job fetches.
+// Zero values mean the defaults: 5,000 users of any nationality and gender
+// from the configured source.
+type PopulateOptions struct {
+	Count  int
+	Seed   string
+	Nat    []string
+	Gender string
+	Source string
+}
+
+// RecordedResponse is a struct that holds the response recorded for an
+// idempotency key, replayed when the request is repeated.
+type RecordedResponse struct {
+	StatusCode int
+	Header     map[string][]string
+	Body       []byte
 }
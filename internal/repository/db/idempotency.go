@@ -0,0 +1,97 @@
+package db
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"wonderful/internal/repository"
+	"wonderful/internal/repository/db/sqlc"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+// IdempotencyStorage is a postgres implementation of the repository.IdempotencyRepository interface.
+type IdempotencyStorage struct {
+	queries *sqlc.Queries
+}
+
+// NewIdempotencyStorage returns a new IdempotencyStorage.
+func NewIdempotencyStorage(dbConn sqlc.DBTX) *IdempotencyStorage {
+	return &IdempotencyStorage{
+		queries: sqlc.New(dbConn),
+	}
+}
+
+// ClaimKey stores a new key with the fingerprint of its request. Keys created
+// before expiredBefore are claimed again. It returns false if the key is taken.
+func (s *IdempotencyStorage) ClaimKey(ctx context.Context, key, fingerprint string, expiredBefore time.Time) (bool, error) {
+	_, err := s.queries.ClaimIdempotencyKey(ctx, sqlc.ClaimIdempotencyKeyParams{
+		Key:         key,
+		Fingerprint: fingerprint,
+		CreatedAt:   pgtype.Timestamp{Time: expiredBefore, Valid: true},
+	})
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return false, nil
+		}
+		return false, fmt.Errorf("failed to claim idempotency key: %w", err)
+	}
+	return true, nil
+}
+
+// GetKey returns the given key and its recorded response, if any.
+func (s *IdempotencyStorage) GetKey(ctx context.Context, key string) (*repository.IdempotencyKey, error) {
+	row, err := s.queries.GetIdempotencyKey(ctx, key)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, fmt.Errorf("failed to get idempotency key: %w", repository.ErrNotFound)
+		}
+		return nil, fmt.Errorf("failed to get idempotency key: %w", err)
+	}
+	k := &repository.IdempotencyKey{
+		Key:         row.Key,
+		Fingerprint: row.Fingerprint,
+		StatusCode:  int(row.StatusCode.Int32),
+		Body:        row.ResponseBody,
+		CreatedAt:   row.CreatedAt.Time,
+	}
+	if row.ResponseHeaders != nil {
+		if err := json.Unmarshal(row.ResponseHeaders, &k.Header); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal idempotency key headers: %w", err)
+		}
+	}
+	if row.CompletedAt.Valid {
+		k.CompletedAt = &row.CompletedAt.Time
+	}
+	return k, nil
+}
+
+// CompleteKey records the response of the request of a key.
+func (s *IdempotencyStorage) CompleteKey(ctx context.Context, k repository.IdempotencyKey) error {
+	headers, err := json.Marshal(k.Header)
+	if err != nil {
+		return fmt.Errorf("failed to marshal idempotency key headers: %w", err)
+	}
+	err = s.queries.CompleteIdempotencyKey(ctx, sqlc.CompleteIdempotencyKeyParams{
+		Key:             k.Key,
+		StatusCode:      pgtype.Int4{Int32: int32(k.StatusCode), Valid: true},
+		ResponseHeaders: headers,
+		ResponseBody:    k.Body,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to complete idempotency key: %w", err)
+	}
+	return nil
+}
+
+// DeleteKey removes a key, so that its request can be retried.
+func (s *IdempotencyStorage) DeleteKey(ctx context.Context, key string) error {
+	if err := s.queries.DeleteIdempotencyKey(ctx, key); err != nil {
+		return fmt.Errorf("failed to delete idempotency key: %w", err)
+	}
+	return nil
+}
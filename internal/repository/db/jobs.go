@@ -0,0 +1,142 @@
+package db
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"wonderful/internal/repository"
+	"wonderful/internal/repository/db/sqlc"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/segmentio/ksuid"
+)
+
+// JobStorage is a postgres implementation of the repository.JobRepository interface.
+type JobStorage struct {
+	queries *sqlc.Queries
+}
+
+// NewJobStorage returns a new JobStorage.
+func NewJobStorage(dbConn sqlc.DBTX) *JobStorage {
+	return &JobStorage{
+		queries: sqlc.New(dbConn),
+	}
+}
+
+// CreateJob creates a job with the given ID, initial state and options.
+func (s *JobStorage) CreateJob(ctx context.Context, id ksuid.KSUID, state string,
+	opts repository.PopulateOptions,
+) (*repository.Job, error) {
+	options, err := json.Marshal(opts)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal job options: %w", err)
+	}
+	row, err := s.queries.CreateJob(ctx, sqlc.CreateJobParams{
+		ID:      id.String(),
+		State:   state,
+		Options: options,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create job: %w", err)
+	}
+	return rowToJob(row)
+}
+
+// GetJob returns the job with the given ID.
+func (s *JobStorage) GetJob(ctx context.Context, id ksuid.KSUID) (*repository.Job, error) {
+	row, err := s.queries.GetJob(ctx, id.String())
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, fmt.Errorf("failed to get job %s: %w", id, repository.ErrNotFound)
+		}
+		return nil, fmt.Errorf("failed to get job %s: %w", id, err)
+	}
+	return rowToJob(row)
+}
+
+// UpdateJob stores the state, counters, error and finish time of a job.
+func (s *JobStorage) UpdateJob(ctx context.Context, j repository.Job) (*repository.Job, error) {
+	var finishedAt pgtype.Timestamp
+	if j.FinishedAt != nil {
+		finishedAt = pgtype.Timestamp{Time: *j.FinishedAt, Valid: true}
+	}
+	row, err := s.queries.UpdateJob(ctx, sqlc.UpdateJobParams{
+		ID:           j.ID.String(),
+		State:        j.State,
+		Fetched:      int32(j.Fetched),
+		Inserted:     int32(j.Inserted),
+		Updated:      int32(j.Updated),
+		Skipped:      int32(j.Skipped),
+		ErrorMessage: nullableText(j.Error),
+		FinishedAt:   finishedAt,
+	})
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, fmt.Errorf("failed to update job %s: %w", j.ID, repository.ErrNotFound)
+		}
+		return nil, fmt.Errorf("failed to update job %s: %w", j.ID, err)
+	}
+	return rowToJob(row)
+}
+
+// ListJobsByState returns the jobs in the given state, oldest first.
+func (s *JobStorage) ListJobsByState(ctx context.Context, state string) ([]repository.Job, error) {
+	rows, err := s.queries.ListJobsByState(ctx, state)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list %s jobs: %w", state, err)
+	}
+	jobs := make([]repository.Job, 0, len(rows))
+	for _, r := range rows {
+		j, err := rowToJob(r)
+		if err != nil {
+			return nil, err
+		}
+		jobs = append(jobs, *j)
+	}
+	return jobs, nil
+}
+
+// FailInterruptedJobs marks the jobs that were fetching or inserting as failed.
+func (s *JobStorage) FailInterruptedJobs(ctx context.Context, reason string) (int64, error) {
+	n, err := s.queries.FailInterruptedJobs(ctx, nullableText(reason))
+	if err != nil {
+		return 0, fmt.Errorf("failed to fail interrupted jobs: %w", err)
+	}
+	return n, nil
+}
+
+// rowToJob converts a database row to a repository.Job.
+func rowToJob(r sqlc.Job) (*repository.Job, error) {
+	id, err := ksuid.Parse(r.ID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse job id: %w", err)
+	}
+	var updatedAt, finishedAt *time.Time
+	if r.UpdatedAt.Valid {
+		updatedAt = &r.UpdatedAt.Time
+	}
+	if r.FinishedAt.Valid {
+		finishedAt = &r.FinishedAt.Time
+	}
+	var opts repository.PopulateOptions
+	if err := json.Unmarshal(r.Options, &opts); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal job options: %w", err)
+	}
+	return &repository.Job{
+		ID:         id,
+		State:      r.State,
+		Fetched:    int(r.Fetched),
+		Inserted:   int(r.Inserted),
+		Updated:    int(r.Updated),
+		Skipped:    int(r.Skipped),
+		Error:      r.ErrorMessage.String,
+		CreatedAt:  r.CreatedAt.Time,
+		UpdatedAt:  updatedAt,
+		FinishedAt: finishedAt,
+		Options:    opts,
+	}, nil
+}
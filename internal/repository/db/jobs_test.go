@@ -0,0 +1,99 @@
+package db_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"wonderful/internal/repository"
+	"wonderful/internal/repository/db"
+	"wonderful/internal/repository/db/test"
+
+	"github.com/segmentio/ksuid"
+	"github.com/stretchr/testify/require"
+	"github.com/stretchr/testify/suite"
+	testcontainers "github.com/testcontainers/testcontainers-go/modules/postgres"
+)
+
+type JobsTestSuite struct {
+	suite.Suite
+	container *testcontainers.PostgresContainer
+	s         *db.Storage
+}
+
+// In order for 'go test' to run this suite, we need to create
+// a normal test function and pass our suite to suite.Run.
+func TestJobsTestSuite(t *testing.T) {
+	suite.Run(t, new(JobsTestSuite))
+}
+
+func (ts *JobsTestSuite) SetupSuite() {
+	var err error
+	ctx := context.Background()
+	ts.container, err = test.SetupDB(ctx)
+	require.NoError(ts.T(), err)
+	ts.s, err = db.NewStorage(ctx)
+	require.NoError(ts.T(), err)
+}
+
+func (ts *JobsTestSuite) TearDownSuite() {
+	ctx := context.Background()
+	err := test.TeardownDB(ctx, ts.container)
+	require.NoError(ts.T(), err)
+	ts.s.Close()
+}
+
+func (ts *JobsTestSuite) TestJobLifecycle() {
+	ctx := context.Background()
+	jobs := db.NewJobStorage(ts.s.Pool())
+
+	// create
+	opts := repository.PopulateOptions{Count: 10, Seed: "abc", Nat: []string{"US", "GB"}, Gender: "female"}
+	job, err := jobs.CreateJob(ctx, ksuid.New(), "queued", opts)
+	ts.Require().NoError(err)
+	ts.Require().Equal("queued", job.State)
+	ts.Require().Equal(opts, job.Options)
+	ts.Require().Zero(job.Fetched)
+	ts.Require().Nil(job.FinishedAt)
+
+	// unknown job
+	_, err = jobs.GetJob(ctx, ksuid.New())
+	ts.Require().ErrorIs(err, repository.ErrNotFound)
+
+	// update
+	now := time.Now().UTC().Truncate(time.Microsecond)
+	job.State = "done"
+	job.Fetched = 10
+	job.Inserted = 10
+	job.FinishedAt = &now
+	updated, err := jobs.UpdateJob(ctx, *job)
+	ts.Require().NoError(err)
+	ts.Require().Equal("done", updated.State)
+	ts.Require().Equal(10, updated.Inserted)
+	ts.Require().NotNil(updated.UpdatedAt)
+	ts.Require().True(now.Equal(*updated.FinishedAt))
+
+	got, err := jobs.GetJob(ctx, job.ID)
+	ts.Require().NoError(err)
+	ts.Require().Equal(updated, got)
+
+	// interrupted jobs are failed, queued and finished jobs are kept
+	running, err := jobs.CreateJob(ctx, ksuid.New(), "fetching", repository.PopulateOptions{})
+	ts.Require().NoError(err)
+	queued, err := jobs.CreateJob(ctx, ksuid.New(), "queued", repository.PopulateOptions{})
+	ts.Require().NoError(err)
+	n, err := jobs.FailInterruptedJobs(ctx, "interrupted")
+	ts.Require().NoError(err)
+	ts.Require().Equal(int64(1), n)
+
+	got, err = jobs.GetJob(ctx, running.ID)
+	ts.Require().NoError(err)
+	ts.Require().Equal("failed", got.State)
+	ts.Require().Equal("interrupted", got.Error)
+	ts.Require().NotNil(got.FinishedAt)
+
+	pending, err := jobs.ListJobsByState(ctx, "queued")
+	ts.Require().NoError(err)
+	ts.Require().Len(pending, 1)
+	ts.Require().Equal(queued.ID, pending[0].ID)
+}
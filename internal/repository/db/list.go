@@ -0,0 +1,358 @@
+package db
+
+import (
+	"strconv"
+	"strings"
+
+	"wonderful/internal/repository"
+	"wonderful/internal/repository/db/sqlc"
+
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+// userColumns are the columns of a listed user, in the order of the fields of
+// listedUser, and the value listed instead of each one when its field is not
+// selected. The ID is always listed.
+var userColumns = []struct {
+	field  repository.UserField
+	column string
+	empty  string
+}{
+	{"", "id", ""},
+	{repository.FieldName, "name", "''"},
+	{repository.FieldEmail, "email", "''"},
+	{repository.FieldPhone, "phone", "''"},
+	{repository.FieldCell, "cell", "NULL::varchar"},
+	{repository.FieldPicture, "picture", "'null'::jsonb"},
+	{repository.FieldRegistration, "registration", "NULL::timestamp"},
+	{repository.FieldCreatedAt, "created_at", "NULL::timestamp"},
+	{repository.FieldUpdatedAt, "updated_at", "NULL::timestamp"},
+	{repository.FieldDeletedAt, "deleted_at", "NULL::timestamp"},
+	{repository.FieldGender, "gender", "NULL::varchar"},
+	{repository.FieldTitle, "title", "NULL::varchar"},
+	{repository.FieldFirstName, "first_name", "NULL::varchar"},
+	{repository.FieldLastName, "last_name", "NULL::varchar"},
+	{repository.FieldDateOfBirth, "date_of_birth", "NULL::timestamp"},
+	{repository.FieldNat, "nat", "NULL::varchar"},
+	{repository.FieldNationalID, "national_id", "NULL::jsonb"},
+	{repository.FieldLocation, "location", "NULL::jsonb"},
+}
+
+// pictureSizes are the parts of the picture, in the order of their fields.
+var pictureSizes = []struct {
+	field repository.UserField
+	key   string
+}{
+	{repository.FieldPictureLarge, "large"},
+	{repository.FieldPictureMedium, "medium"},
+	{repository.FieldPictureThumbnail, "thumbnail"},
+}
+
+// sortFields are the user fields holding the values of the sort fields.
+var sortFields = map[repository.SortField]repository.UserField{
+	repository.SortName:         repository.FieldName,
+	repository.SortEmail:        repository.FieldEmail,
+	repository.SortRegistration: repository.FieldRegistration,
+	repository.SortCreatedAt:    repository.FieldCreatedAt,
+}
+
+// versionFields are the user fields of the version of a user, one of which
+// changes with every write: the entity tags of the pages are made of them.
+var versionFields = []repository.UserField{repository.FieldCreatedAt, repository.FieldUpdatedAt, repository.FieldDeletedAt}
+
+// selectList returns the select list of a listing of the fields, which
+// always includes the fields of the sort order as the cursors are made of
+// them, and the version fields, see versionFields.
+func selectList(fields []repository.UserField, sort []repository.SortKey) string {
+	if fields == nil {
+		columns := make([]string, 0, len(userColumns))
+		for _, c := range userColumns {
+			columns = append(columns, c.column)
+		}
+		return strings.Join(columns, ", ")
+	}
+	selected := make(map[repository.UserField]bool, len(fields)+len(sort)+len(versionFields))
+	for _, f := range fields {
+		selected[f] = true
+	}
+	for _, f := range versionFields {
+		selected[f] = true
+	}
+	if len(sort) == 0 {
+		sort = defaultSort
+	}
+	for _, s := range sort {
+		selected[sortFields[s.Field]] = true
+	}
+	columns := make([]string, 0, len(userColumns))
+	for _, c := range userColumns {
+		switch {
+		case c.field == "" || selected[c.field]:
+			columns = append(columns, c.column)
+		case c.field == repository.FieldPicture:
+			columns = append(columns, pictureColumn(selected))
+		default:
+			// not aliased, so that the ORDER BY can only mean the columns.
+			columns = append(columns, c.empty)
+		}
+	}
+	return strings.Join(columns, ", ")
+}
+
+// pictureColumn returns the column of a picture restricted to the selected sizes.
+func pictureColumn(selected map[repository.UserField]bool) string {
+	var sizes []string
+	for _, size := range pictureSizes {
+		if selected[size.field] {
+			sizes = append(sizes, "'"+size.key+"', picture->'"+size.key+"'")
+		}
+	}
+	if len(sizes) == 0 {
+		return "'null'::jsonb"
+	}
+	return "jsonb_strip_nulls(jsonb_build_object(" + strings.Join(sizes, ", ") + "))"
+}
+
+// listedUser is a row of a user listing.
+type listedUser struct {
+	sqlc.GetUserByIDRow
+	Rank float32
+}
+
+// exportedUser is a row of an export, a listed user with its external ID.
+type exportedUser struct {
+	listedUser
+	ExternalID pgtype.Text
+}
+
+// noRank is the rank of the users listed without a search query.
+const noRank = "0::real"
+
+// defaultSort is the listing order when none is given.
+var defaultSort = []repository.SortKey{{Field: repository.SortRegistration, Desc: true}}
+
+// sortColumns maps the sortable fields to their columns.
+var sortColumns = map[repository.SortField]string{
+	repository.SortName:         "name",
+	repository.SortEmail:        "email",
+	repository.SortRegistration: "registration",
+	repository.SortCreatedAt:    "created_at",
+	// the relevance is computed by the query, see queryBuilder.rank.
+	repository.SortRelevance: "",
+}
+
+// sortCasts are the types of the seek values, which Postgres can not always
+// infer in a row comparison.
+var sortCasts = map[repository.SortField]string{
+	repository.SortName:         "::varchar",
+	repository.SortEmail:        "::varchar",
+	repository.SortRegistration: "::timestamp",
+	repository.SortCreatedAt:    "::timestamp",
+	repository.SortRelevance:    "::real",
+}
+
+// queryBuilder builds the user listing queries. The SQL only ever holds
+// column names from the code: the values given by the clients are passed
+// as arguments.
+type queryBuilder struct {
+	where []string
+	args  []any
+	// rank is the relevance of a user to the search query.
+	rank string
+}
+
+// arg adds an argument to the query and returns its placeholder.
+func (q *queryBuilder) arg(v any) string {
+	q.args = append(q.args, v)
+	return "$" + strconv.Itoa(len(q.args))
+}
+
+// likePattern returns the ILIKE pattern matching the strings containing s.
+func likePattern(s string) string {
+	return "%" + strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`).Replace(s) + "%"
+}
+
+// filter adds the filters, ignoring the pagination.
+func (q *queryBuilder) filter(f *repository.Filters) {
+	q.rank = noRank
+	if !f.IncludeDeleted {
+		q.where = append(q.where, "deleted_at IS NULL")
+	}
+	if f.Email != nil {
+		q.where = append(q.where, "email ILIKE "+q.arg(likePattern(*f.Email)))
+	}
+	if f.Name != nil {
+		q.where = append(q.where, "name ILIKE "+q.arg(likePattern(*f.Name)))
+	}
+	if f.Query != nil {
+		// the users containing the query, or similar to it (pg_trgm).
+		pattern, query := q.arg(likePattern(*f.Query)), q.arg(*f.Query)+"::text"
+		q.where = append(q.where, "(name ILIKE "+pattern+" OR email ILIKE "+pattern+" OR name % "+query+" OR email % "+query+")")
+		q.rank = "GREATEST(similarity(name, " + query + "), similarity(email, " + query + "))"
+	}
+	equal := []struct {
+		column string
+		value  *string
+	}{{"phone", f.Phone}, {"cell", f.Cell}, {"nat", f.Nat}, {"gender", f.Gender}}
+	for _, e := range equal {
+		if e.value != nil {
+			q.where = append(q.where, e.column+" = "+q.arg(*e.value))
+		}
+	}
+	if f.RegisteredAfter != nil {
+		q.where = append(q.where, "registration > "+q.arg(nullableTimestamp(f.RegisteredAfter))+"::timestamp")
+	}
+	if f.RegisteredBefore != nil {
+		q.where = append(q.where, "registration < "+q.arg(nullableTimestamp(f.RegisteredBefore))+"::timestamp")
+	}
+}
+
+// sortTerm is a column of a listing order with the value of the seek key in it.
+type sortTerm struct {
+	column string
+	cast   string
+	desc   bool
+	value  any
+}
+
+// arg adds the seek value of the term to the query and returns its placeholder.
+func (t sortTerm) arg(q *queryBuilder) string {
+	return q.arg(t.value) + t.cast
+}
+
+// sortTerms returns the terms of the sort order, closed by the ID so that the
+// order is total. The ID follows the direction of the last sort key.
+func (q *queryBuilder) sortTerms(sort []repository.SortKey, k *repository.SeekKey) []sortTerm {
+	if len(sort) == 0 {
+		sort = defaultSort
+	}
+	if k == nil {
+		k = &repository.SeekKey{}
+	}
+	terms := make([]sortTerm, 0, len(sort)+1)
+	for _, s := range sort {
+		column := sortColumns[s.Field]
+		if s.Field == repository.SortRelevance {
+			column = q.rank
+		}
+		terms = append(terms, sortTerm{column: column, cast: sortCasts[s.Field], desc: s.Desc, value: seekValue(k, s.Field)})
+	}
+	return append(terms, sortTerm{column: "id", cast: "::varchar", desc: sort[len(sort)-1].Desc, value: k.ID.String()})
+}
+
+// seekValue returns the value of the field in the seek key.
+func seekValue(k *repository.SeekKey, f repository.SortField) any {
+	switch f {
+	case repository.SortName:
+		return k.Name
+	case repository.SortEmail:
+		return k.Email
+	case repository.SortCreatedAt:
+		return pgtype.Timestamp{Time: k.CreatedAt, Valid: true}
+	case repository.SortRelevance:
+		return k.Rank
+	default:
+		return pgtype.Timestamp{Time: k.Registration, Valid: true}
+	}
+}
+
+// seek adds the condition of the users listed after the seek key, or before it.
+func (q *queryBuilder) seek(terms []sortTerm, before bool) {
+	// the order is descending on a term when it is listed in descending
+	// order and read forward, or in ascending order and read backward.
+	lessThan := func(t sortTerm) bool { return t.desc != before }
+	sameDirection := true
+	for _, t := range terms {
+		sameDirection = sameDirection && lessThan(t) == lessThan(terms[0])
+	}
+	if sameDirection {
+		// a row comparison can use the index on the columns.
+		columns := make([]string, 0, len(terms))
+		values := make([]string, 0, len(terms))
+		for _, t := range terms {
+			columns = append(columns, t.column)
+			values = append(values, t.arg(q))
+		}
+		q.where = append(q.where, "("+strings.Join(columns, ", ")+") "+comparison(lessThan(terms[0]))+
+			" ("+strings.Join(values, ", ")+")")
+		return
+	}
+	// (a, b) after (x, y) is a > x OR (a = x AND b > y) with mixed directions.
+	alternatives := make([]string, 0, len(terms))
+	for i, t := range terms {
+		conditions := make([]string, 0, i+1)
+		for _, prev := range terms[:i] {
+			conditions = append(conditions, prev.column+" = "+prev.arg(q))
+		}
+		conditions = append(conditions, t.column+" "+comparison(lessThan(t))+" "+t.arg(q))
+		alternatives = append(alternatives, "("+strings.Join(conditions, " AND ")+")")
+	}
+	q.where = append(q.where, "("+strings.Join(alternatives, " OR ")+")")
+}
+
+func comparison(lessThan bool) string {
+	if lessThan {
+		return "<"
+	}
+	return ">"
+}
+
+func (q *queryBuilder) whereClause() string {
+	if len(q.where) == 0 {
+		return ""
+	}
+	return "\nWHERE\n    " + strings.Join(q.where, "\n    AND ")
+}
+
+// listUsersQuery returns the query listing the users of p and its arguments.
+// ending_before reads the order backwards, so that the users closest to the
+// cursor are returned. They are put back in order by the caller.
+func listUsersQuery(p repository.Params) (string, []any) {
+	q := &queryBuilder{}
+	q.filter(&p.Filters)
+	backward := p.EndingBefore != nil
+	seekKey := p.StartingAfter
+	if backward {
+		seekKey = p.EndingBefore
+	}
+	terms := q.sortTerms(p.Sort, seekKey)
+	if seekKey != nil {
+		q.seek(terms, backward)
+	}
+	order := make([]string, 0, len(terms))
+	for _, t := range terms {
+		if t.desc != backward {
+			order = append(order, t.column+" DESC")
+		} else {
+			order = append(order, t.column+" ASC")
+		}
+	}
+	// This is for safety. The API by default returns a limit of 10.
+	limit := p.Limit
+	if limit == 0 {
+		limit = 10
+	}
+	return "SELECT\n    " + selectList(p.Fields, p.Sort) + ",\n    " + q.rank + "\nFROM\n    users" + q.whereClause() +
+		"\nORDER BY\n    " + strings.Join(order, ", ") + "\nLIMIT " + q.arg(limit), q.args
+}
+
+// countUsersQuery returns the query counting the users matching the filters of p and its arguments.
+func countUsersQuery(p repository.Params) (string, []any) {
+	q := &queryBuilder{}
+	q.filter(&p.Filters)
+	return "SELECT\n    COUNT(*)\nFROM\n    users" + q.whereClause(), q.args
+}
+
+// exportUsersQuery returns the query of all the users matching the filters,
+// in the default order, and its arguments. The rows are exportedUser ones.
+func exportUsersQuery(f *repository.Filters) (string, []any) {
+	q := &queryBuilder{}
+	q.filter(f)
+	terms := q.sortTerms(nil, nil)
+	order := make([]string, 0, len(terms))
+	for _, t := range terms {
+		order = append(order, t.column+" DESC")
+	}
+	return "SELECT\n    " + selectList(nil, nil) + ",\n    " + q.rank + ",\n    external_id\nFROM\n    users" + q.whereClause() +
+		"\nORDER BY\n    " + strings.Join(order, ", "), q.args
+}
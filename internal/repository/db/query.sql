@@ -1,5 +1,58 @@
--- name: ListUsers :many
-SELECT
+-- name: ClaimIdempotencyKey :one
+-- Claims a new key, or an existing one created before $3 (expired).
+-- No row is returned when the key is already taken.
+INSERT INTO idempotency_keys (
+    key,
+    fingerprint
+) VALUES (
+    $1, $2
+)
+ON CONFLICT (key) DO UPDATE SET
+    fingerprint = EXCLUDED.fingerprint,
+    status_code = NULL,
+    response_headers = NULL,
+    response_body = NULL,
+    created_at = CURRENT_TIMESTAMP,
+    completed_at = NULL
+WHERE
+    idempotency_keys.created_at < $3
+RETURNING
+    key;
+
+-- name: CompleteIdempotencyKey :exec
+UPDATE
+    idempotency_keys
+SET
+    status_code = $2,
+    response_headers = $3,
+    response_body = $4,
+    completed_at = CURRENT_TIMESTAMP
+WHERE
+    key = $1;
+
+-- name: CreateJob :one
+INSERT INTO jobs (
+    id,
+    state,
+    options
+) VALUES (
+    $1, $2, $3
+)
+RETURNING
+    id,
+    state,
+    fetched,
+    inserted,
+    error_message,
+    created_at,
+    updated_at,
+    finished_at,
+    options,
+    updated,
+    skipped;
+
+-- name: CreateUser :one
+INSERT INTO users (
     id,
     name,
     email,
@@ -7,36 +60,426 @@ SELECT
     cell,
     picture,
     registration
+) VALUES (
+    $1, $2, $3, $4, $5, $6, $7
+)
+RETURNING
+    id,
+    name,
+    email,
+    phone,
+    cell,
+    picture,
+    registration,
+    created_at,
+    updated_at,
+    deleted_at,
+    gender,
+    title,
+    first_name,
+    last_name,
+    date_of_birth,
+    nat,
+    national_id,
+    location;
+
+-- name: DeleteIdempotencyKey :exec
+DELETE FROM
+    idempotency_keys
+WHERE
+    key = $1;
+
+-- name: DeleteUser :execrows
+UPDATE
+    users
+SET
+    deleted_at = CURRENT_TIMESTAMP
+WHERE
+    id = $1 AND deleted_at IS NULL;
+
+-- name: FailInterruptedJobs :execrows
+UPDATE
+    jobs
+SET
+    state = 'failed',
+    error_message = $1,
+    updated_at = CURRENT_TIMESTAMP,
+    finished_at = CURRENT_TIMESTAMP
+WHERE
+    state IN ('fetching', 'inserting');
+
+-- name: GetIdempotencyKey :one
+SELECT
+    key,
+    fingerprint,
+    status_code,
+    response_headers,
+    response_body,
+    created_at,
+    completed_at
+FROM
+    idempotency_keys
+WHERE
+    key = $1;
+
+-- name: GetJob :one
+SELECT
+    id,
+    state,
+    fetched,
+    inserted,
+    error_message,
+    created_at,
+    updated_at,
+    finished_at,
+    options,
+    updated,
+    skipped
+FROM
+    jobs
+WHERE
+    id = $1;
+
+-- name: GetUserByID :one
+SELECT
+    id,
+    name,
+    email,
+    phone,
+    cell,
+    picture,
+    registration,
+    created_at,
+    updated_at,
+    deleted_at,
+    gender,
+    title,
+    first_name,
+    last_name,
+    date_of_birth,
+    nat,
+    national_id,
+    location
 FROM
     users
 WHERE
-	-- email substring
-    (email LIKE '%' || $1 || '%' OR $1 IS NULL)
-    -- starting_after
-	AND ($2 = '' OR $2 IS NULL OR ( 
-		(registration < (select registration from users where id = $2)) OR 
-		(registration = (select registration from users where id = $2) AND id < $2)
-	))
-    -- ending_before
-	AND ($3 = '' OR $3 IS NULL OR ( 
-		(registration > (select registration from users where id = $3)) OR 
-		(registration = (select registration from users where id = $3) AND id > $3)
-	))
+    id = $1 AND deleted_at IS NULL;
+
+-- name: GetUserForUpdate :one
+SELECT
+    id,
+    name,
+    email,
+    phone,
+    cell,
+    picture,
+    registration,
+    created_at,
+    updated_at,
+    deleted_at,
+    gender,
+    title,
+    first_name,
+    last_name,
+    date_of_birth,
+    nat,
+    national_id,
+    location
+FROM
+    users
+WHERE
+    id = $1
+FOR UPDATE;
+
+-- name: ListJobsByState :many
+SELECT
+    id,
+    state,
+    fetched,
+    inserted,
+    error_message,
+    created_at,
+    updated_at,
+    finished_at,
+    options,
+    updated,
+    skipped
+FROM
+    jobs
+WHERE
+    state = $1
 ORDER BY
-    registration DESC, id DESC
-LIMIT $4;
+    created_at, id;
 
+-- name: LoadStagedUsers :copyfrom
+INSERT INTO users_staging (
+    batch_id,
+    id,
+    external_id,
+    name,
+    email,
+    phone,
+    cell,
+    picture,
+    registration,
+    gender,
+    title,
+    first_name,
+    last_name,
+    date_of_birth,
+    nat,
+    national_id,
+    location
+) VALUES (
+    $1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16, $17
+);
 
--- name: LoadBulkUsers :copyfrom
+-- name: MergeStagedUsers :many
+-- Moves a staged batch into users. Users with a known external_id are updated
+-- when they changed, the others are inserted. The profile fields a user comes
+-- without, like the imported ones, keep their stored values. Only inserted and
+-- updated users are returned, inserted tells them apart.
+WITH batch AS (
+    DELETE FROM
+        users_staging
+    WHERE
+        batch_id = $1
+    RETURNING
+        id,
+        external_id,
+        name,
+        email,
+        phone,
+        cell,
+        picture,
+        registration,
+        gender,
+        title,
+        first_name,
+        last_name,
+        date_of_birth,
+        nat,
+        national_id,
+        location
+)
 INSERT INTO users (
     id,
+    external_id,
     name,
     email,
     phone,
     cell,
     picture,
-    registration
-) VALUES (  
-    $1, $2, $3, $4, $5, $6, $7
-);
+    registration,
+    gender,
+    title,
+    first_name,
+    last_name,
+    date_of_birth,
+    nat,
+    national_id,
+    location
+)
+SELECT
+    id,
+    external_id,
+    name,
+    email,
+    phone,
+    cell,
+    picture,
+    registration,
+    gender,
+    title,
+    first_name,
+    last_name,
+    date_of_birth,
+    nat,
+    national_id,
+    location
+FROM
+    batch
+ON CONFLICT (external_id) DO UPDATE SET
+    name = EXCLUDED.name,
+    email = EXCLUDED.email,
+    phone = EXCLUDED.phone,
+    cell = EXCLUDED.cell,
+    picture = EXCLUDED.picture,
+    registration = EXCLUDED.registration,
+    gender = COALESCE(EXCLUDED.gender, users.gender),
+    title = COALESCE(EXCLUDED.title, users.title),
+    first_name = COALESCE(EXCLUDED.first_name, users.first_name),
+    last_name = COALESCE(EXCLUDED.last_name, users.last_name),
+    date_of_birth = COALESCE(EXCLUDED.date_of_birth, users.date_of_birth),
+    nat = COALESCE(EXCLUDED.nat, users.nat),
+    national_id = COALESCE(EXCLUDED.national_id, users.national_id),
+    location = COALESCE(EXCLUDED.location, users.location),
+    updated_at = CURRENT_TIMESTAMP
+WHERE
+    (users.name, users.email, users.phone, users.cell, users.picture, users.registration,
+        users.gender, users.title, users.first_name, users.last_name, users.date_of_birth, users.nat, users.national_id, users.location)
+    IS DISTINCT FROM
+    (EXCLUDED.name, EXCLUDED.email, EXCLUDED.phone, EXCLUDED.cell, EXCLUDED.picture, EXCLUDED.registration,
+        COALESCE(EXCLUDED.gender, users.gender), COALESCE(EXCLUDED.title, users.title),
+        COALESCE(EXCLUDED.first_name, users.first_name), COALESCE(EXCLUDED.last_name, users.last_name),
+        COALESCE(EXCLUDED.date_of_birth, users.date_of_birth), COALESCE(EXCLUDED.nat, users.nat),
+        COALESCE(EXCLUDED.national_id, users.national_id), COALESCE(EXCLUDED.location, users.location))
+RETURNING
+    (xmax = 0) AS inserted;
+
+-- name: PurgeUsers :execrows
+DELETE FROM
+    users
+WHERE
+    deleted_at IS NOT NULL AND deleted_at < $1;
+
+-- name: RestoreUser :one
+UPDATE
+    users
+SET
+    deleted_at = NULL,
+    updated_at = CURRENT_TIMESTAMP
+WHERE
+    id = $1 AND deleted_at IS NOT NULL
+RETURNING
+    id,
+    name,
+    email,
+    phone,
+    cell,
+    picture,
+    registration,
+    created_at,
+    updated_at,
+    deleted_at,
+    gender,
+    title,
+    first_name,
+    last_name,
+    date_of_birth,
+    nat,
+    national_id,
+    location;
 
+-- name: SearchUsers :many
+-- Full-text search of the users, the most relevant first. $2 and $3 are the
+-- rank and ID of the last user of the previous page. The headlines are only
+-- computed for the users of the page.
+WITH page AS (
+    SELECT
+        id,
+        name,
+        email,
+        phone,
+        cell,
+        picture,
+        registration,
+        created_at,
+        updated_at,
+        deleted_at,
+        gender,
+        title,
+        first_name,
+        last_name,
+        date_of_birth,
+        nat,
+        national_id,
+        location,
+        ts_rank(search, to_tsquery('simple', $1::text)) AS rank
+    FROM
+        users
+    WHERE
+        search @@ to_tsquery('simple', $1::text)
+        AND (deleted_at IS NULL OR $4::boolean)
+        AND ($2::real IS NULL OR (ts_rank(search, to_tsquery('simple', $1::text)), id) < ($2::real, $3::varchar))
+    ORDER BY
+        rank DESC, id DESC
+    LIMIT $5
+)
+SELECT
+    id,
+    name,
+    email,
+    phone,
+    cell,
+    picture,
+    registration,
+    created_at,
+    updated_at,
+    deleted_at,
+    gender,
+    title,
+    first_name,
+    last_name,
+    date_of_birth,
+    nat,
+    national_id,
+    location,
+    rank,
+    ts_headline('simple', name, to_tsquery('simple', $1::text), 'HighlightAll=true, StartSel=<mark>, StopSel=</mark>') AS name_headline,
+    ts_headline('simple', email, to_tsquery('simple', $1::text), 'HighlightAll=true, StartSel=<mark>, StopSel=</mark>') AS email_headline,
+    ts_headline('simple', users_location_text(location), to_tsquery('simple', $1::text), 'HighlightAll=true, StartSel=<mark>, StopSel=</mark>') AS location_headline
+FROM
+    page
+ORDER BY
+    rank DESC, id DESC;
+
+-- name: UpdateJob :one
+UPDATE
+    jobs
+SET
+    state = $2,
+    fetched = $3,
+    inserted = $4,
+    updated = $5,
+    skipped = $6,
+    error_message = $7,
+    finished_at = $8,
+    updated_at = CURRENT_TIMESTAMP
+WHERE
+    id = $1
+RETURNING
+    id,
+    state,
+    fetched,
+    inserted,
+    error_message,
+    created_at,
+    updated_at,
+    finished_at,
+    options,
+    updated,
+    skipped;
+
+-- name: UpdateUser :one
+UPDATE
+    users
+SET
+    name = $2,
+    email = $3,
+    phone = $4,
+    cell = $5,
+    picture = $6,
+    registration = $7,
+    updated_at = CURRENT_TIMESTAMP
+WHERE
+    id = $1 AND deleted_at IS NULL
+RETURNING
+    id,
+    name,
+    email,
+    phone,
+    cell,
+    picture,
+    registration,
+    created_at,
+    updated_at,
+    deleted_at,
+    gender,
+    title,
+    first_name,
+    last_name,
+    date_of_birth,
+    nat,
+    national_id,
+    location;
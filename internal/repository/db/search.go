@@ -0,0 +1,42 @@
+package db
+
+import (
+	"strings"
+)
+
+// tsQuery converts a search query to the to_tsquery syntax: the terms are
+// and-ed, a term ending with "*" matches as a prefix and the terms between
+// double quotes as a phrase. Every term is quoted, so that the characters of
+// the query are never read as tsquery operators.
+func tsQuery(query string) string {
+	var terms []string
+	for i, part := range strings.Split(query, `"`) {
+		words := tsWords(part)
+		if i%2 == 1 && len(words) > 0 {
+			// between double quotes
+			terms = append(terms, "("+strings.Join(words, " <-> ")+")")
+			continue
+		}
+		terms = append(terms, words...)
+	}
+	return strings.Join(terms, " & ")
+}
+
+// tsWords returns the quoted tsquery lexemes of the words of s.
+func tsWords(s string) []string {
+	fields := strings.Fields(s)
+	words := make([]string, 0, len(fields))
+	quote := strings.NewReplacer(`\`, `\\`, "'", "''")
+	for _, f := range fields {
+		word := strings.TrimRight(f, "*")
+		if word == "" {
+			continue
+		}
+		lexeme := "'" + quote.Replace(word) + "'"
+		if word != f {
+			lexeme += ":*"
+		}
+		words = append(words, lexeme)
+	}
+	return words
+}
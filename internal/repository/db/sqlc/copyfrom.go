@@ -9,13 +9,13 @@ import (
 	"context"
 )
 
-// iteratorForLoadBulkUsers implements pgx.CopyFromSource.
-type iteratorForLoadBulkUsers struct {
-	rows                 []LoadBulkUsersParams
+// iteratorForLoadStagedUsers implements pgx.CopyFromSource.
+type iteratorForLoadStagedUsers struct {
+	rows                 []LoadStagedUsersParams
 	skippedFirstNextCall bool
 }
 
-func (r *iteratorForLoadBulkUsers) Next() bool {
+func (r *iteratorForLoadStagedUsers) Next() bool {
 	if len(r.rows) == 0 {
 		return false
 	}
@@ -27,22 +27,32 @@ func (r *iteratorForLoadBulkUsers) Next() bool {
 	return len(r.rows) > 0
 }
 
-func (r iteratorForLoadBulkUsers) Values() ([]interface{}, error) {
+func (r iteratorForLoadStagedUsers) Values() ([]interface{}, error) {
 	return []interface{}{
+		r.rows[0].BatchID,
 		r.rows[0].ID,
+		r.rows[0].ExternalID,
 		r.rows[0].Name,
 		r.rows[0].Email,
 		r.rows[0].Phone,
 		r.rows[0].Cell,
 		r.rows[0].Picture,
 		r.rows[0].Registration,
+		r.rows[0].Gender,
+		r.rows[0].Title,
+		r.rows[0].FirstName,
+		r.rows[0].LastName,
+		r.rows[0].DateOfBirth,
+		r.rows[0].Nat,
+		r.rows[0].NationalID,
+		r.rows[0].Location,
 	}, nil
 }
 
-func (r iteratorForLoadBulkUsers) Err() error {
+func (r iteratorForLoadStagedUsers) Err() error {
 	return nil
 }
 
-func (q *Queries) LoadBulkUsers(ctx context.Context, arg []LoadBulkUsersParams) (int64, error) {
-	return q.db.CopyFrom(ctx, []string{"users"}, []string{"id", "name", "email", "phone", "cell", "picture", "registration"}, &iteratorForLoadBulkUsers{rows: arg})
+func (q *Queries) LoadStagedUsers(ctx context.Context, arg []LoadStagedUsersParams) (int64, error) {
+	return q.db.CopyFrom(ctx, []string{"users_staging"}, []string{"batch_id", "id", "external_id", "name", "email", "phone", "cell", "picture", "registration", "gender", "title", "first_name", "last_name", "date_of_birth", "nat", "national_id", "location"}, &iteratorForLoadStagedUsers{rows: arg})
 }
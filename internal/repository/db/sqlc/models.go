@@ -8,6 +8,30 @@ import (
 	"github.com/jackc/pgx/v5/pgtype"
 )
 
+type IdempotencyKey struct {
+	Key             string
+	Fingerprint     string
+	StatusCode      pgtype.Int4
+	ResponseHeaders []byte
+	ResponseBody    []byte
+	CreatedAt       pgtype.Timestamp
+	CompletedAt     pgtype.Timestamp
+}
+
+type Job struct {
+	ID           string
+	State        string
+	Fetched      int32
+	Inserted     int32
+	ErrorMessage pgtype.Text
+	CreatedAt    pgtype.Timestamp
+	UpdatedAt    pgtype.Timestamp
+	FinishedAt   pgtype.Timestamp
+	Options      []byte
+	Updated      int32
+	Skipped      int32
+}
+
 type User struct {
 	ID           string
 	Name         string
@@ -18,4 +42,35 @@ type User struct {
 	Registration pgtype.Timestamp
 	CreatedAt    pgtype.Timestamp
 	UpdatedAt    pgtype.Timestamp
+	DeletedAt    pgtype.Timestamp
+	ExternalID   pgtype.Text
+	Gender       pgtype.Text
+	Title        pgtype.Text
+	FirstName    pgtype.Text
+	LastName     pgtype.Text
+	DateOfBirth  pgtype.Timestamp
+	Nat          pgtype.Text
+	NationalID   []byte
+	Location     []byte
+	Search       interface{}
+}
+
+type UsersStaging struct {
+	BatchID      string
+	ID           string
+	ExternalID   pgtype.Text
+	Name         string
+	Email        string
+	Phone        string
+	Cell         pgtype.Text
+	Picture      []byte
+	Registration pgtype.Timestamp
+	Gender       pgtype.Text
+	Title        pgtype.Text
+	FirstName    pgtype.Text
+	LastName     pgtype.Text
+	DateOfBirth  pgtype.Timestamp
+	Nat          pgtype.Text
+	NationalID   []byte
+	Location     []byte
 }
@@ -11,8 +11,127 @@ import (
 	"github.com/jackc/pgx/v5/pgtype"
 )
 
-const listUsers = `-- name: ListUsers :many
-SELECT
+const claimIdempotencyKey = `-- name: ClaimIdempotencyKey :one
+INSERT INTO idempotency_keys (
+    key,
+    fingerprint
+) VALUES (
+    $1, $2
+)
+ON CONFLICT (key) DO UPDATE SET
+    fingerprint = EXCLUDED.fingerprint,
+    status_code = NULL,
+    response_headers = NULL,
+    response_body = NULL,
+    created_at = CURRENT_TIMESTAMP,
+    completed_at = NULL
+WHERE
+    idempotency_keys.created_at < $3
+RETURNING
+    key
+`
+
+type ClaimIdempotencyKeyParams struct {
+	Key         string
+	Fingerprint string
+	CreatedAt   pgtype.Timestamp
+}
+
+// Claims a new key, or an existing one created before $3 (expired).
+// No row is returned when the key is already taken.
+func (q *Queries) ClaimIdempotencyKey(ctx context.Context, arg ClaimIdempotencyKeyParams) (string, error) {
+	row := q.db.QueryRow(ctx, claimIdempotencyKey,
+		arg.Key,
+		arg.Fingerprint,
+		arg.CreatedAt,
+	)
+	var key string
+	err := row.Scan(&key)
+	return key, err
+}
+
+const completeIdempotencyKey = `-- name: CompleteIdempotencyKey :exec
+UPDATE
+    idempotency_keys
+SET
+    status_code = $2,
+    response_headers = $3,
+    response_body = $4,
+    completed_at = CURRENT_TIMESTAMP
+WHERE
+    key = $1
+`
+
+type CompleteIdempotencyKeyParams struct {
+	Key             string
+	StatusCode      pgtype.Int4
+	ResponseHeaders []byte
+	ResponseBody    []byte
+}
+
+func (q *Queries) CompleteIdempotencyKey(ctx context.Context, arg CompleteIdempotencyKeyParams) error {
+	_, err := q.db.Exec(ctx, completeIdempotencyKey,
+		arg.Key,
+		arg.StatusCode,
+		arg.ResponseHeaders,
+		arg.ResponseBody,
+	)
+	return err
+}
+
+const createJob = `-- name: CreateJob :one
+INSERT INTO jobs (
+    id,
+    state,
+    options
+) VALUES (
+    $1, $2, $3
+)
+RETURNING
+    id,
+    state,
+    fetched,
+    inserted,
+    error_message,
+    created_at,
+    updated_at,
+    finished_at,
+    options,
+    updated,
+    skipped
+`
+
+type CreateJobParams struct {
+	ID      string
+	State   string
+	Options []byte
+}
+
+func (q *Queries) CreateJob(ctx context.Context, arg CreateJobParams) (Job, error) {
+	row := q.db.QueryRow(ctx, createJob,
+		arg.ID,
+		arg.State,
+		arg.Options,
+	)
+	var i Job
+	err := row.Scan(
+		&i.ID,
+		&i.State,
+		&i.Fetched,
+		&i.Inserted,
+		&i.ErrorMessage,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.FinishedAt,
+		&i.Options,
+		&i.Updated,
+		&i.Skipped,
+	)
+	return i, err
+}
+
+const createUser = `-- name: CreateUser :one
+INSERT INTO users (
     id,
     name,
     email,
@@ -20,34 +139,591 @@ SELECT
     cell,
     picture,
     registration
+) VALUES (
+    $1, $2, $3, $4, $5, $6, $7
+)
+RETURNING
+    id,
+    name,
+    email,
+    phone,
+    cell,
+    picture,
+    registration,
+    created_at,
+    updated_at,
+    deleted_at,
+    gender,
+    title,
+    first_name,
+    last_name,
+    date_of_birth,
+    nat,
+    national_id,
+    location
+`
+
+type CreateUserParams struct {
+	ID           string
+	Name         string
+	Email        string
+	Phone        string
+	Cell         pgtype.Text
+	Picture      []byte
+	Registration pgtype.Timestamp
+}
+
+type CreateUserRow struct {
+	ID           string
+	Name         string
+	Email        string
+	Phone        string
+	Cell         pgtype.Text
+	Picture      []byte
+	Registration pgtype.Timestamp
+	CreatedAt    pgtype.Timestamp
+	UpdatedAt    pgtype.Timestamp
+	DeletedAt    pgtype.Timestamp
+	Gender       pgtype.Text
+	Title        pgtype.Text
+	FirstName    pgtype.Text
+	LastName     pgtype.Text
+	DateOfBirth  pgtype.Timestamp
+	Nat          pgtype.Text
+	NationalID   []byte
+	Location     []byte
+}
+
+func (q *Queries) CreateUser(ctx context.Context, arg CreateUserParams) (CreateUserRow, error) {
+	row := q.db.QueryRow(ctx, createUser,
+		arg.ID,
+		arg.Name,
+		arg.Email,
+		arg.Phone,
+		arg.Cell,
+		arg.Picture,
+		arg.Registration,
+	)
+	var i CreateUserRow
+	err := row.Scan(
+		&i.ID,
+		&i.Name,
+		&i.Email,
+		&i.Phone,
+		&i.Cell,
+		&i.Picture,
+		&i.Registration,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.DeletedAt,
+		&i.Gender,
+		&i.Title,
+		&i.FirstName,
+		&i.LastName,
+		&i.DateOfBirth,
+		&i.Nat,
+		&i.NationalID,
+		&i.Location,
+	)
+	return i, err
+}
+
+const deleteIdempotencyKey = `-- name: DeleteIdempotencyKey :exec
+DELETE FROM
+    idempotency_keys
+WHERE
+    key = $1
+`
+
+func (q *Queries) DeleteIdempotencyKey(ctx context.Context, key string) error {
+	_, err := q.db.Exec(ctx, deleteIdempotencyKey, key)
+	return err
+}
+
+const deleteUser = `-- name: DeleteUser :execrows
+UPDATE
+    users
+SET
+    deleted_at = CURRENT_TIMESTAMP
+WHERE
+    id = $1 AND deleted_at IS NULL
+`
+
+func (q *Queries) DeleteUser(ctx context.Context, id string) (int64, error) {
+	result, err := q.db.Exec(ctx, deleteUser, id)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected(), nil
+}
+
+const failInterruptedJobs = `-- name: FailInterruptedJobs :execrows
+UPDATE
+    jobs
+SET
+    state = 'failed',
+    error_message = $1,
+    updated_at = CURRENT_TIMESTAMP,
+    finished_at = CURRENT_TIMESTAMP
+WHERE
+    state IN ('fetching', 'inserting')
+`
+
+func (q *Queries) FailInterruptedJobs(ctx context.Context, errorMessage pgtype.Text) (int64, error) {
+	result, err := q.db.Exec(ctx, failInterruptedJobs, errorMessage)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected(), nil
+}
+
+const getIdempotencyKey = `-- name: GetIdempotencyKey :one
+SELECT
+    key,
+    fingerprint,
+    status_code,
+    response_headers,
+    response_body,
+    created_at,
+    completed_at
+FROM
+    idempotency_keys
+WHERE
+    key = $1
+`
+
+func (q *Queries) GetIdempotencyKey(ctx context.Context, key string) (IdempotencyKey, error) {
+	row := q.db.QueryRow(ctx, getIdempotencyKey, key)
+	var i IdempotencyKey
+	err := row.Scan(
+		&i.Key,
+		&i.Fingerprint,
+		&i.StatusCode,
+		&i.ResponseHeaders,
+		&i.ResponseBody,
+		&i.CreatedAt,
+		&i.CompletedAt,
+	)
+	return i, err
+}
+
+const getJob = `-- name: GetJob :one
+SELECT
+    id,
+    state,
+    fetched,
+    inserted,
+    error_message,
+    created_at,
+    updated_at,
+    finished_at,
+    options,
+    updated,
+    skipped
+FROM
+    jobs
+WHERE
+    id = $1
+`
+
+func (q *Queries) GetJob(ctx context.Context, id string) (Job, error) {
+	row := q.db.QueryRow(ctx, getJob, id)
+	var i Job
+	err := row.Scan(
+		&i.ID,
+		&i.State,
+		&i.Fetched,
+		&i.Inserted,
+		&i.ErrorMessage,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.FinishedAt,
+		&i.Options,
+		&i.Updated,
+		&i.Skipped,
+	)
+	return i, err
+}
+
+const getUserByID = `-- name: GetUserByID :one
+SELECT
+    id,
+    name,
+    email,
+    phone,
+    cell,
+    picture,
+    registration,
+    created_at,
+    updated_at,
+    deleted_at,
+    gender,
+    title,
+    first_name,
+    last_name,
+    date_of_birth,
+    nat,
+    national_id,
+    location
 FROM
     users
 WHERE
-	-- email substring
-    (email LIKE '%' || $1 || '%' OR $1 IS NULL)
-    -- starting_after
-	AND ($2 = '' OR $2 IS NULL OR ( 
-		(registration < (select registration from users where id = $2)) OR 
-		(registration = (select registration from users where id = $2) AND id < $2)
-	))
-    -- ending_before
-	AND ($3 = '' OR $3 IS NULL OR ( 
-		(registration > (select registration from users where id = $3)) OR 
-		(registration = (select registration from users where id = $3) AND id > $3)
-	))
+    id = $1 AND deleted_at IS NULL
+`
+
+type GetUserByIDRow struct {
+	ID           string
+	Name         string
+	Email        string
+	Phone        string
+	Cell         pgtype.Text
+	Picture      []byte
+	Registration pgtype.Timestamp
+	CreatedAt    pgtype.Timestamp
+	UpdatedAt    pgtype.Timestamp
+	DeletedAt    pgtype.Timestamp
+	Gender       pgtype.Text
+	Title        pgtype.Text
+	FirstName    pgtype.Text
+	LastName     pgtype.Text
+	DateOfBirth  pgtype.Timestamp
+	Nat          pgtype.Text
+	NationalID   []byte
+	Location     []byte
+}
+
+func (q *Queries) GetUserByID(ctx context.Context, id string) (GetUserByIDRow, error) {
+	row := q.db.QueryRow(ctx, getUserByID, id)
+	var i GetUserByIDRow
+	err := row.Scan(
+		&i.ID,
+		&i.Name,
+		&i.Email,
+		&i.Phone,
+		&i.Cell,
+		&i.Picture,
+		&i.Registration,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.DeletedAt,
+		&i.Gender,
+		&i.Title,
+		&i.FirstName,
+		&i.LastName,
+		&i.DateOfBirth,
+		&i.Nat,
+		&i.NationalID,
+		&i.Location,
+	)
+	return i, err
+}
+
+const getUserForUpdate = `-- name: GetUserForUpdate :one
+SELECT
+    id,
+    name,
+    email,
+    phone,
+    cell,
+    picture,
+    registration,
+    created_at,
+    updated_at,
+    deleted_at,
+    gender,
+    title,
+    first_name,
+    last_name,
+    date_of_birth,
+    nat,
+    national_id,
+    location
+FROM
+    users
+WHERE
+    id = $1
+FOR UPDATE
+`
+
+type GetUserForUpdateRow struct {
+	ID           string
+	Name         string
+	Email        string
+	Phone        string
+	Cell         pgtype.Text
+	Picture      []byte
+	Registration pgtype.Timestamp
+	CreatedAt    pgtype.Timestamp
+	UpdatedAt    pgtype.Timestamp
+	DeletedAt    pgtype.Timestamp
+	Gender       pgtype.Text
+	Title        pgtype.Text
+	FirstName    pgtype.Text
+	LastName     pgtype.Text
+	DateOfBirth  pgtype.Timestamp
+	Nat          pgtype.Text
+	NationalID   []byte
+	Location     []byte
+}
+
+func (q *Queries) GetUserForUpdate(ctx context.Context, id string) (GetUserForUpdateRow, error) {
+	row := q.db.QueryRow(ctx, getUserForUpdate, id)
+	var i GetUserForUpdateRow
+	err := row.Scan(
+		&i.ID,
+		&i.Name,
+		&i.Email,
+		&i.Phone,
+		&i.Cell,
+		&i.Picture,
+		&i.Registration,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.DeletedAt,
+		&i.Gender,
+		&i.Title,
+		&i.FirstName,
+		&i.LastName,
+		&i.DateOfBirth,
+		&i.Nat,
+		&i.NationalID,
+		&i.Location,
+	)
+	return i, err
+}
+
+const listJobsByState = `-- name: ListJobsByState :many
+SELECT
+    id,
+    state,
+    fetched,
+    inserted,
+    error_message,
+    created_at,
+    updated_at,
+    finished_at,
+    options,
+    updated,
+    skipped
+FROM
+    jobs
+WHERE
+    state = $1
 ORDER BY
-    registration DESC, id DESC
-LIMIT $4
+    created_at, id
 `
 
-type ListUsersParams struct {
-	Column1 pgtype.Text
-	Column2 interface{}
-	Column3 interface{}
-	Limit   int32
+func (q *Queries) ListJobsByState(ctx context.Context, state string) ([]Job, error) {
+	rows, err := q.db.Query(ctx, listJobsByState, state)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []Job
+	for rows.Next() {
+		var i Job
+		if err := rows.Scan(
+			&i.ID,
+			&i.State,
+			&i.Fetched,
+			&i.Inserted,
+			&i.ErrorMessage,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.FinishedAt,
+			&i.Options,
+			&i.Updated,
+			&i.Skipped,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+type LoadStagedUsersParams struct {
+	BatchID      string
+	ID           string
+	ExternalID   pgtype.Text
+	Name         string
+	Email        string
+	Phone        string
+	Cell         pgtype.Text
+	Picture      []byte
+	Registration pgtype.Timestamp
+	Gender       pgtype.Text
+	Title        pgtype.Text
+	FirstName    pgtype.Text
+	LastName     pgtype.Text
+	DateOfBirth  pgtype.Timestamp
+	Nat          pgtype.Text
+	NationalID   []byte
+	Location     []byte
+}
+
+const mergeStagedUsers = `-- name: MergeStagedUsers :many
+WITH batch AS (
+    DELETE FROM
+        users_staging
+    WHERE
+        batch_id = $1
+    RETURNING
+        id,
+        external_id,
+        name,
+        email,
+        phone,
+        cell,
+        picture,
+        registration,
+        gender,
+        title,
+        first_name,
+        last_name,
+        date_of_birth,
+        nat,
+        national_id,
+        location
+)
+INSERT INTO users (
+    id,
+    external_id,
+    name,
+    email,
+    phone,
+    cell,
+    picture,
+    registration,
+    gender,
+    title,
+    first_name,
+    last_name,
+    date_of_birth,
+    nat,
+    national_id,
+    location
+)
+SELECT
+    id,
+    external_id,
+    name,
+    email,
+    phone,
+    cell,
+    picture,
+    registration,
+    gender,
+    title,
+    first_name,
+    last_name,
+    date_of_birth,
+    nat,
+    national_id,
+    location
+FROM
+    batch
+ON CONFLICT (external_id) DO UPDATE SET
+    name = EXCLUDED.name,
+    email = EXCLUDED.email,
+    phone = EXCLUDED.phone,
+    cell = EXCLUDED.cell,
+    picture = EXCLUDED.picture,
+    registration = EXCLUDED.registration,
+    gender = COALESCE(EXCLUDED.gender, users.gender),
+    title = COALESCE(EXCLUDED.title, users.title),
+    first_name = COALESCE(EXCLUDED.first_name, users.first_name),
+    last_name = COALESCE(EXCLUDED.last_name, users.last_name),
+    date_of_birth = COALESCE(EXCLUDED.date_of_birth, users.date_of_birth),
+    nat = COALESCE(EXCLUDED.nat, users.nat),
+    national_id = COALESCE(EXCLUDED.national_id, users.national_id),
+    location = COALESCE(EXCLUDED.location, users.location),
+    updated_at = CURRENT_TIMESTAMP
+WHERE
+    (users.name, users.email, users.phone, users.cell, users.picture, users.registration,
+        users.gender, users.title, users.first_name, users.last_name, users.date_of_birth, users.nat, users.national_id, users.location)
+    IS DISTINCT FROM
+    (EXCLUDED.name, EXCLUDED.email, EXCLUDED.phone, EXCLUDED.cell, EXCLUDED.picture, EXCLUDED.registration,
+        COALESCE(EXCLUDED.gender, users.gender), COALESCE(EXCLUDED.title, users.title),
+        COALESCE(EXCLUDED.first_name, users.first_name), COALESCE(EXCLUDED.last_name, users.last_name),
+        COALESCE(EXCLUDED.date_of_birth, users.date_of_birth), COALESCE(EXCLUDED.nat, users.nat),
+        COALESCE(EXCLUDED.national_id, users.national_id), COALESCE(EXCLUDED.location, users.location))
+RETURNING
+    (xmax = 0) AS inserted
+`
+
+// Moves a staged batch into users. Users with a known external_id are updated
+// when they changed, the others are inserted. The profile fields a user comes
+// without, like the imported ones, keep their stored values. Only inserted and
+// updated users are returned, inserted tells them apart.
+func (q *Queries) MergeStagedUsers(ctx context.Context, batchID string) ([]bool, error) {
+	rows, err := q.db.Query(ctx, mergeStagedUsers, batchID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []bool
+	for rows.Next() {
+		var inserted bool
+		if err := rows.Scan(&inserted); err != nil {
+			return nil, err
+		}
+		items = append(items, inserted)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const purgeUsers = `-- name: PurgeUsers :execrows
+DELETE FROM
+    users
+WHERE
+    deleted_at IS NOT NULL AND deleted_at < $1
+`
+
+func (q *Queries) PurgeUsers(ctx context.Context, deletedAt pgtype.Timestamp) (int64, error) {
+	result, err := q.db.Exec(ctx, purgeUsers, deletedAt)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected(), nil
 }
 
-type ListUsersRow struct {
+const restoreUser = `-- name: RestoreUser :one
+UPDATE
+    users
+SET
+    deleted_at = NULL,
+    updated_at = CURRENT_TIMESTAMP
+WHERE
+    id = $1 AND deleted_at IS NOT NULL
+RETURNING
+    id,
+    name,
+    email,
+    phone,
+    cell,
+    picture,
+    registration,
+    created_at,
+    updated_at,
+    deleted_at,
+    gender,
+    title,
+    first_name,
+    last_name,
+    date_of_birth,
+    nat,
+    national_id,
+    location
+`
+
+type RestoreUserRow struct {
 	ID           string
 	Name         string
 	Email        string
@@ -55,22 +731,157 @@ type ListUsersRow struct {
 	Cell         pgtype.Text
 	Picture      []byte
 	Registration pgtype.Timestamp
+	CreatedAt    pgtype.Timestamp
+	UpdatedAt    pgtype.Timestamp
+	DeletedAt    pgtype.Timestamp
+	Gender       pgtype.Text
+	Title        pgtype.Text
+	FirstName    pgtype.Text
+	LastName     pgtype.Text
+	DateOfBirth  pgtype.Timestamp
+	Nat          pgtype.Text
+	NationalID   []byte
+	Location     []byte
+}
+
+func (q *Queries) RestoreUser(ctx context.Context, id string) (RestoreUserRow, error) {
+	row := q.db.QueryRow(ctx, restoreUser, id)
+	var i RestoreUserRow
+	err := row.Scan(
+		&i.ID,
+		&i.Name,
+		&i.Email,
+		&i.Phone,
+		&i.Cell,
+		&i.Picture,
+		&i.Registration,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.DeletedAt,
+		&i.Gender,
+		&i.Title,
+		&i.FirstName,
+		&i.LastName,
+		&i.DateOfBirth,
+		&i.Nat,
+		&i.NationalID,
+		&i.Location,
+	)
+	return i, err
+}
+
+const searchUsers = `-- name: SearchUsers :many
+WITH page AS (
+    SELECT
+        id,
+        name,
+        email,
+        phone,
+        cell,
+        picture,
+        registration,
+        created_at,
+        updated_at,
+        deleted_at,
+        gender,
+        title,
+        first_name,
+        last_name,
+        date_of_birth,
+        nat,
+        national_id,
+        location,
+        ts_rank(search, to_tsquery('simple', $1::text)) AS rank
+    FROM
+        users
+    WHERE
+        search @@ to_tsquery('simple', $1::text)
+        AND (deleted_at IS NULL OR $4::boolean)
+        AND ($2::real IS NULL OR (ts_rank(search, to_tsquery('simple', $1::text)), id) < ($2::real, $3::varchar))
+    ORDER BY
+        rank DESC, id DESC
+    LIMIT $5
+)
+SELECT
+    id,
+    name,
+    email,
+    phone,
+    cell,
+    picture,
+    registration,
+    created_at,
+    updated_at,
+    deleted_at,
+    gender,
+    title,
+    first_name,
+    last_name,
+    date_of_birth,
+    nat,
+    national_id,
+    location,
+    rank,
+    ts_headline('simple', name, to_tsquery('simple', $1::text), 'HighlightAll=true, StartSel=<mark>, StopSel=</mark>') AS name_headline,
+    ts_headline('simple', email, to_tsquery('simple', $1::text), 'HighlightAll=true, StartSel=<mark>, StopSel=</mark>') AS email_headline,
+    ts_headline('simple', users_location_text(location), to_tsquery('simple', $1::text), 'HighlightAll=true, StartSel=<mark>, StopSel=</mark>') AS location_headline
+FROM
+    page
+ORDER BY
+    rank DESC, id DESC
+`
+
+type SearchUsersParams struct {
+	Column1 string
+	Column2 pgtype.Float4
+	Column3 string
+	Column4 bool
+	Limit   int32
+}
+
+type SearchUsersRow struct {
+	ID               string
+	Name             string
+	Email            string
+	Phone            string
+	Cell             pgtype.Text
+	Picture          []byte
+	Registration     pgtype.Timestamp
+	CreatedAt        pgtype.Timestamp
+	UpdatedAt        pgtype.Timestamp
+	DeletedAt        pgtype.Timestamp
+	Gender           pgtype.Text
+	Title            pgtype.Text
+	FirstName        pgtype.Text
+	LastName         pgtype.Text
+	DateOfBirth      pgtype.Timestamp
+	Nat              pgtype.Text
+	NationalID       []byte
+	Location         []byte
+	Rank             float32
+	NameHeadline     string
+	EmailHeadline    string
+	LocationHeadline string
 }
 
-func (q *Queries) ListUsers(ctx context.Context, arg ListUsersParams) ([]ListUsersRow, error) {
-	rows, err := q.db.Query(ctx, listUsers,
+// Full-text search of the users, the most relevant first. $2 and $3 are the
+// rank and ID of the last user of the previous page. The headlines are only
+// computed for the users of the page.
+func (q *Queries) SearchUsers(ctx context.Context, arg SearchUsersParams) ([]SearchUsersRow, error) {
+	rows, err := q.db.Query(ctx, searchUsers,
 		arg.Column1,
 		arg.Column2,
 		arg.Column3,
+		arg.Column4,
 		arg.Limit,
 	)
 	if err != nil {
 		return nil, err
 	}
 	defer rows.Close()
-	var items []ListUsersRow
+	var items []SearchUsersRow
 	for rows.Next() {
-		var i ListUsersRow
+		var i SearchUsersRow
 		if err := rows.Scan(
 			&i.ID,
 			&i.Name,
@@ -79,6 +890,21 @@ func (q *Queries) ListUsers(ctx context.Context, arg ListUsersParams) ([]ListUse
 			&i.Cell,
 			&i.Picture,
 			&i.Registration,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.DeletedAt,
+			&i.Gender,
+			&i.Title,
+			&i.FirstName,
+			&i.LastName,
+			&i.DateOfBirth,
+			&i.Nat,
+			&i.NationalID,
+			&i.Location,
+			&i.Rank,
+			&i.NameHeadline,
+			&i.EmailHeadline,
+			&i.LocationHeadline,
 		); err != nil {
 			return nil, err
 		}
@@ -90,7 +916,108 @@ func (q *Queries) ListUsers(ctx context.Context, arg ListUsersParams) ([]ListUse
 	return items, nil
 }
 
-type LoadBulkUsersParams struct {
+const updateJob = `-- name: UpdateJob :one
+UPDATE
+    jobs
+SET
+    state = $2,
+    fetched = $3,
+    inserted = $4,
+    updated = $5,
+    skipped = $6,
+    error_message = $7,
+    finished_at = $8,
+    updated_at = CURRENT_TIMESTAMP
+WHERE
+    id = $1
+RETURNING
+    id,
+    state,
+    fetched,
+    inserted,
+    error_message,
+    created_at,
+    updated_at,
+    finished_at,
+    options,
+    updated,
+    skipped
+`
+
+type UpdateJobParams struct {
+	ID           string
+	State        string
+	Fetched      int32
+	Inserted     int32
+	Updated      int32
+	Skipped      int32
+	ErrorMessage pgtype.Text
+	FinishedAt   pgtype.Timestamp
+}
+
+func (q *Queries) UpdateJob(ctx context.Context, arg UpdateJobParams) (Job, error) {
+	row := q.db.QueryRow(ctx, updateJob,
+		arg.ID,
+		arg.State,
+		arg.Fetched,
+		arg.Inserted,
+		arg.Updated,
+		arg.Skipped,
+		arg.ErrorMessage,
+		arg.FinishedAt,
+	)
+	var i Job
+	err := row.Scan(
+		&i.ID,
+		&i.State,
+		&i.Fetched,
+		&i.Inserted,
+		&i.ErrorMessage,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.FinishedAt,
+		&i.Options,
+		&i.Updated,
+		&i.Skipped,
+	)
+	return i, err
+}
+
+const updateUser = `-- name: UpdateUser :one
+UPDATE
+    users
+SET
+    name = $2,
+    email = $3,
+    phone = $4,
+    cell = $5,
+    picture = $6,
+    registration = $7,
+    updated_at = CURRENT_TIMESTAMP
+WHERE
+    id = $1 AND deleted_at IS NULL
+RETURNING
+    id,
+    name,
+    email,
+    phone,
+    cell,
+    picture,
+    registration,
+    created_at,
+    updated_at,
+    deleted_at,
+    gender,
+    title,
+    first_name,
+    last_name,
+    date_of_birth,
+    nat,
+    national_id,
+    location
+`
+
+type UpdateUserParams struct {
 	ID           string
 	Name         string
 	Email        string
@@ -99,3 +1026,58 @@ type LoadBulkUsersParams struct {
 	Picture      []byte
 	Registration pgtype.Timestamp
 }
+
+type UpdateUserRow struct {
+	ID           string
+	Name         string
+	Email        string
+	Phone        string
+	Cell         pgtype.Text
+	Picture      []byte
+	Registration pgtype.Timestamp
+	CreatedAt    pgtype.Timestamp
+	UpdatedAt    pgtype.Timestamp
+	DeletedAt    pgtype.Timestamp
+	Gender       pgtype.Text
+	Title        pgtype.Text
+	FirstName    pgtype.Text
+	LastName     pgtype.Text
+	DateOfBirth  pgtype.Timestamp
+	Nat          pgtype.Text
+	NationalID   []byte
+	Location     []byte
+}
+
+func (q *Queries) UpdateUser(ctx context.Context, arg UpdateUserParams) (UpdateUserRow, error) {
+	row := q.db.QueryRow(ctx, updateUser,
+		arg.ID,
+		arg.Name,
+		arg.Email,
+		arg.Phone,
+		arg.Cell,
+		arg.Picture,
+		arg.Registration,
+	)
+	var i UpdateUserRow
+	err := row.Scan(
+		&i.ID,
+		&i.Name,
+		&i.Email,
+		&i.Phone,
+		&i.Cell,
+		&i.Picture,
+		&i.Registration,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.DeletedAt,
+		&i.Gender,
+		&i.Title,
+		&i.FirstName,
+		&i.LastName,
+		&i.DateOfBirth,
+		&i.Nat,
+		&i.NationalID,
+		&i.Location,
+	)
+	return i, err
+}
@@ -3,18 +3,24 @@ package db
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"log/slog"
+	"slices"
+	"strconv"
+	"time"
 
 	"wonderful/internal/repository"
 	"wonderful/internal/repository/db/sqlc"
 
+	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgtype"
 	"github.com/segmentio/ksuid"
 )
 
 // UserStorage is a postgres implementation of the repository.UserStorage interface.
 type UserStorage struct {
+	db      sqlc.DBTX
 	queries *sqlc.Queries
 }
 
@@ -22,83 +28,370 @@ type UserStorage struct {
 func NewUserStorage(dbConn sqlc.DBTX) *UserStorage {
 	queries := sqlc.New(dbConn)
 	return &UserStorage{
+		db:      dbConn,
 		queries: queries,
 	}
 }
 
-func formatParameters(p repository.Params) sqlc.ListUsersParams {
-	params := sqlc.ListUsersParams{}
-	// This is for safety. The API by default returns a limit of 10.
-	if p.Limit == 0 {
-		p.Limit = 10
+// ListUsers returns a list of users.
+func (s *UserStorage) ListUsers(ctx context.Context, p repository.Params) ([]repository.User, error) {
+	query, args := listUsersQuery(p)
+	rows, err := s.db.Query(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list users: %w", err)
 	}
-	params.Limit = int32(p.Limit)
-	// Email
-	params.Column1 = pgtype.Text{}
-	if p.Email != nil {
-		params.Column1 = pgtype.Text{String: *p.Email, Valid: true}
+	userRows, err := pgx.CollectRows(rows, pgx.RowToStructByPos[listedUser])
+	if err != nil {
+		return nil, fmt.Errorf("failed to list users: %w", err)
 	}
-	// StartingAfter
-	params.Column2 = pgtype.Text{}
-	if p.StartingAfter != nil {
-		params.Column2 = pgtype.Text{String: p.StartingAfter.String(), Valid: true}
+
+	users := make([]repository.User, 0, len(userRows))
+	for idx := range userRows {
+		u, err := rowToUser(userRows[idx].GetUserByIDRow)
+		if err != nil {
+			// if there is an error, log it and continue to the next row
+			slog.Error("failed to convert user row", "error", err)
+			continue
+		}
+		u.Rank = userRows[idx].Rank
+		users = append(users, *u)
 	}
-	// EndingBefore
-	params.Column3 = pgtype.Text{}
 	if p.EndingBefore != nil {
-		params.Column3 = pgtype.Text{String: p.EndingBefore.String(), Valid: true}
+		slices.Reverse(users)
 	}
+	return users, nil
+}
 
-	return params
+// exportBatchSize is the number of users fetched at once from the cursor of an export.
+const exportBatchSize = 500
+
+// ExportUsers calls fn with every user matching the filters, by registration
+// descending, with their external ID. The users are read in batches from a
+// server-side cursor, so that only one batch is held in memory. It must run in
+// a transaction (see store.ExecTx), which the cursor lives in. An error of fn
+// stops the export and is returned as is.
+func (s *UserStorage) ExportUsers(ctx context.Context, f repository.Filters, fn func(*repository.User) error) error {
+	query, args := exportUsersQuery(&f)
+	if _, err := s.db.Exec(ctx, "DECLARE export_users NO SCROLL CURSOR FOR "+query, args...); err != nil {
+		return fmt.Errorf("failed to export users: %w", err)
+	}
+	for {
+		rows, err := s.db.Query(ctx, "FETCH "+strconv.Itoa(exportBatchSize)+" FROM export_users")
+		if err != nil {
+			return fmt.Errorf("failed to export users: %w", err)
+		}
+		userRows, err := pgx.CollectRows(rows, pgx.RowToStructByPos[exportedUser])
+		if err != nil {
+			return fmt.Errorf("failed to export users: %w", err)
+		}
+		for idx := range userRows {
+			u, err := rowToUser(userRows[idx].GetUserByIDRow)
+			if err != nil {
+				// if there is an error, log it and continue to the next row
+				slog.Error("failed to convert user row", "error", err)
+				continue
+			}
+			u.ExternalID = userRows[idx].ExternalID.String
+			if err := fn(u); err != nil {
+				return err
+			}
+		}
+		if len(userRows) < exportBatchSize {
+			break
+		}
+	}
+	if _, err := s.db.Exec(ctx, "CLOSE export_users"); err != nil {
+		return fmt.Errorf("failed to export users: %w", err)
+	}
+	return nil
 }
 
-// ListUsers returns a list of users.
-func (s *UserStorage) ListUsers(ctx context.Context, p repository.Params) ([]repository.User, error) {
-	params := formatParameters(p)
+// CountUsers returns the number of users matching the filters, ignoring the pagination.
+func (s *UserStorage) CountUsers(ctx context.Context, p repository.Params) (int64, error) {
+	query, args := countUsersQuery(p)
+	var n int64
+	if err := s.db.QueryRow(ctx, query, args...).Scan(&n); err != nil {
+		return 0, fmt.Errorf("failed to count users: %w", err)
+	}
+	return n, nil
+}
 
-	rows, err := s.queries.ListUsers(ctx, params)
+// SearchUsers returns the users matching a full-text search, the most relevant first.
+func (s *UserStorage) SearchUsers(ctx context.Context, p repository.SearchParams) ([]repository.SearchResult, error) {
+	// This is for safety. The API by default returns a limit of 10.
+	if p.Limit == 0 {
+		p.Limit = 10
+	}
+	params := sqlc.SearchUsersParams{
+		Column1: tsQuery(p.Query),
+		Column4: p.IncludeDeleted,
+		Limit:   int32(p.Limit),
+	}
+	if p.After != nil {
+		params.Column2 = pgtype.Float4{Float32: p.After.Rank, Valid: true}
+		params.Column3 = p.After.ID.String()
+	}
+	rows, err := s.queries.SearchUsers(ctx, params)
 	if err != nil {
-		return nil, fmt.Errorf("failed to list users: %w", err)
+		return nil, fmt.Errorf("failed to search users: %w", err)
 	}
 
-	users := make([]repository.User, 0, len(rows))
+	results := make([]repository.SearchResult, 0, len(rows))
 	for idx := range rows {
-		// to avoid creating a new variable for each iteration, use a pointer to the current row
-		r := rows[idx]
-		var picture map[string]string
-		// json unmarsal picture
-		if err := json.Unmarshal(r.Picture, &picture); err != nil {
-			// if there is an error, log it and continue to the next row
-			slog.Error("failed to unmarshal picture", "error", err)
-			continue
-		}
-		cell := ""
-		if r.Cell.Valid {
-			cell = r.Cell.String
-		}
-		id, err := ksuid.Parse(r.ID)
+		r := &rows[idx]
+		u, err := rowToUser(sqlc.GetUserByIDRow{
+			ID:           r.ID,
+			Name:         r.Name,
+			Email:        r.Email,
+			Phone:        r.Phone,
+			Cell:         r.Cell,
+			Picture:      r.Picture,
+			Registration: r.Registration,
+			CreatedAt:    r.CreatedAt,
+			UpdatedAt:    r.UpdatedAt,
+			DeletedAt:    r.DeletedAt,
+			Gender:       r.Gender,
+			Title:        r.Title,
+			FirstName:    r.FirstName,
+			LastName:     r.LastName,
+			DateOfBirth:  r.DateOfBirth,
+			Nat:          r.Nat,
+			NationalID:   r.NationalID,
+			Location:     r.Location,
+		})
 		if err != nil {
 			// if there is an error, log it and continue to the next row
-			slog.Error("failed to parse id", "error", err)
+			slog.Error("failed to convert user row", "error", err)
 			continue
 		}
-		users = append(users, repository.User{
-			ID:           id,
-			Name:         r.Name,
-			Email:        r.Email,
-			Phone:        r.Phone,
-			Cell:         cell,
-			Picture:      picture,
-			Registration: r.Registration.Time,
+		u.Rank = r.Rank
+		results = append(results, repository.SearchResult{
+			User:             *u,
+			NameHeadline:     r.NameHeadline,
+			EmailHeadline:    r.EmailHeadline,
+			LocationHeadline: r.LocationHeadline,
 		})
 	}
-	return users, nil
+	return results, nil
 }
 
-// Create creates multiple users.
-func (s *UserStorage) Create(ctx context.Context, users []repository.User) error {
-	params := make([]sqlc.LoadBulkUsersParams, 0, len(users))
-	for _, u := range users {
+// GetUserByID returns the user with the given ID.
+func (s *UserStorage) GetUserByID(ctx context.Context, id ksuid.KSUID) (*repository.User, error) {
+	row, err := s.queries.GetUserByID(ctx, id.String())
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, fmt.Errorf("failed to get user %s: %w", id, repository.ErrNotFound)
+		}
+		return nil, fmt.Errorf("failed to get user %s: %w", id, err)
+	}
+	u, err := rowToUser(row)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get user %s: %w", id, err)
+	}
+	return u, nil
+}
+
+// GetUserForUpdate returns a user, soft deleted or not, and locks it until the
+// end of the transaction, so that it is not changed between the reading and
+// the writing of an update. It must run in a transaction (see store.ExecTx).
+func (s *UserStorage) GetUserForUpdate(ctx context.Context, id ksuid.KSUID) (*repository.User, error) {
+	row, err := s.queries.GetUserForUpdate(ctx, id.String())
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, fmt.Errorf("failed to lock user %s: %w", id, repository.ErrNotFound)
+		}
+		return nil, fmt.Errorf("failed to lock user %s: %w", id, err)
+	}
+	u, err := rowToUser(sqlc.GetUserByIDRow(row))
+	if err != nil {
+		return nil, fmt.Errorf("failed to lock user %s: %w", id, err)
+	}
+	return u, nil
+}
+
+// rowToUser converts a database row to a repository.User.
+func rowToUser(r sqlc.GetUserByIDRow) (*repository.User, error) {
+	var picture map[string]string
+	if err := json.Unmarshal(r.Picture, &picture); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal picture: %w", err)
+	}
+	cell := ""
+	if r.Cell.Valid {
+		cell = r.Cell.String
+	}
+	id, err := ksuid.Parse(r.ID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse id: %w", err)
+	}
+	var updatedAt, deletedAt, dateOfBirth *time.Time
+	if r.UpdatedAt.Valid {
+		updatedAt = &r.UpdatedAt.Time
+	}
+	if r.DeletedAt.Valid {
+		deletedAt = &r.DeletedAt.Time
+	}
+	if r.DateOfBirth.Valid {
+		dateOfBirth = &r.DateOfBirth.Time
+	}
+	var nationalID *repository.NationalID
+	if r.NationalID != nil {
+		if err := json.Unmarshal(r.NationalID, &nationalID); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal national id: %w", err)
+		}
+	}
+	var location *repository.Location
+	if r.Location != nil {
+		if err := json.Unmarshal(r.Location, &location); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal location: %w", err)
+		}
+	}
+	return &repository.User{
+		ID:           id,
+		Name:         r.Name,
+		Email:        r.Email,
+		Phone:        r.Phone,
+		Cell:         cell,
+		Picture:      picture,
+		Registration: r.Registration.Time,
+		CreatedAt:    r.CreatedAt.Time,
+		UpdatedAt:    updatedAt,
+		DeletedAt:    deletedAt,
+		Gender:       r.Gender.String,
+		Title:        r.Title.String,
+		FirstName:    r.FirstName.String,
+		LastName:     r.LastName.String,
+		DateOfBirth:  dateOfBirth,
+		Nat:          r.Nat.String,
+		NationalID:   nationalID,
+		Location:     location,
+	}, nil
+}
+
+// marshalPicture converts the picture map to its JSONB representation.
+func marshalPicture(picture map[string]string) ([]byte, error) {
+	if picture == nil {
+		picture = map[string]string{}
+	}
+	b, err := json.Marshal(picture)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal picture: %w", err)
+	}
+	return b, nil
+}
+
+// marshalNullable converts v to its JSONB representation, or to a SQL NULL if v is nil.
+func marshalNullable[T any](v *T) ([]byte, error) {
+	if v == nil {
+		return nil, nil //nolint:nilnil //a nil value is stored as NULL
+	}
+	b, err := json.Marshal(v)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal %T: %w", v, err)
+	}
+	return b, nil
+}
+
+// nullableTimestamp converts a nil time to a SQL NULL.
+func nullableTimestamp(t *time.Time) pgtype.Timestamp {
+	if t == nil {
+		return pgtype.Timestamp{}
+	}
+	return pgtype.Timestamp{Time: *t, Valid: true}
+}
+
+// nullableText converts an empty string to a SQL NULL.
+func nullableText(s string) pgtype.Text {
+	if s == "" {
+		return pgtype.Text{}
+	}
+	return pgtype.Text{String: s, Valid: true}
+}
+
+// CreateUser creates a single user with a new ID.
+func (s *UserStorage) CreateUser(ctx context.Context, u repository.User) (*repository.User, error) {
+	picture, err := marshalPicture(u.Picture)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create user: %w", err)
+	}
+	row, err := s.queries.CreateUser(ctx, sqlc.CreateUserParams{
+		ID:           ksuid.New().String(),
+		Name:         u.Name,
+		Email:        u.Email,
+		Phone:        u.Phone,
+		Cell:         nullableText(u.Cell),
+		Picture:      picture,
+		Registration: pgtype.Timestamp{Time: u.Registration, Valid: true},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create user: %w", err)
+	}
+	created, err := rowToUser(sqlc.GetUserByIDRow(row))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create user: %w", err)
+	}
+	return created, nil
+}
+
+// UpdateUser replaces the editable fields of an existing user and sets its updated_at.
+func (s *UserStorage) UpdateUser(ctx context.Context, u repository.User) (*repository.User, error) {
+	picture, err := marshalPicture(u.Picture)
+	if err != nil {
+		return nil, fmt.Errorf("failed to update user %s: %w", u.ID, err)
+	}
+	row, err := s.queries.UpdateUser(ctx, sqlc.UpdateUserParams{
+		ID:           u.ID.String(),
+		Name:         u.Name,
+		Email:        u.Email,
+		Phone:        u.Phone,
+		Cell:         nullableText(u.Cell),
+		Picture:      picture,
+		Registration: pgtype.Timestamp{Time: u.Registration, Valid: true},
+	})
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, fmt.Errorf("failed to update user %s: %w", u.ID, repository.ErrNotFound)
+		}
+		return nil, fmt.Errorf("failed to update user %s: %w", u.ID, err)
+	}
+	updated, err := rowToUser(sqlc.GetUserByIDRow(row))
+	if err != nil {
+		return nil, fmt.Errorf("failed to update user %s: %w", u.ID, err)
+	}
+	return updated, nil
+}
+
+// DeleteUser soft deletes the user with the given ID.
+func (s *UserStorage) DeleteUser(ctx context.Context, id ksuid.KSUID) error {
+	n, err := s.queries.DeleteUser(ctx, id.String())
+	if err != nil {
+		return fmt.Errorf("failed to delete user %s: %w", id, err)
+	}
+	if n == 0 {
+		return fmt.Errorf("failed to delete user %s: %w", id, repository.ErrNotFound)
+	}
+	return nil
+}
+
+// Create creates or updates multiple users. The users are copied to a
+// staging table and then merged into users, so that users with a known
+// external ID are updated instead of duplicated. It returns how many users
+// were inserted, updated and skipped, either because they did not change,
+// were repeated in the batch or were invalid. It should run in a transaction
+// (see store.ExecTx) so that a failed merge leaves no staged users behind.
+func (s *UserStorage) Create(ctx context.Context, users []repository.User) (repository.CreateResult, error) {
+	// the merge can not update the same user twice, keep the last occurrence.
+	last := make(map[string]int, len(users))
+	for i, u := range users {
+		if u.ExternalID != "" {
+			last[u.ExternalID] = i
+		}
+	}
+	batchID := ksuid.New().String()
+	params := make([]sqlc.LoadStagedUsersParams, 0, len(users))
+	for i, u := range users {
+		if u.ExternalID != "" && last[u.ExternalID] != i {
+			continue
+		}
 		// json marsal picture to byte
 		picture, err := json.Marshal(u.Picture)
 		if err != nil {
@@ -106,24 +399,78 @@ func (s *UserStorage) Create(ctx context.Context, users []repository.User) error
 			slog.Error("failed to marshal picture", "error", err)
 			continue
 		}
-		var cell pgtype.Text
-		if u.Cell != "" {
-			cell = pgtype.Text{String: u.Cell, Valid: true}
+		nationalID, err := marshalNullable(u.NationalID)
+		if err != nil {
+			slog.Error("failed to marshal national id", "error", err)
+			continue
 		}
-		params = append(params, sqlc.LoadBulkUsersParams{
+		location, err := marshalNullable(u.Location)
+		if err != nil {
+			slog.Error("failed to marshal location", "error", err)
+			continue
+		}
+		params = append(params, sqlc.LoadStagedUsersParams{
+			BatchID:      batchID,
 			ID:           ksuid.New().String(),
+			ExternalID:   nullableText(u.ExternalID),
 			Name:         u.Name,
 			Email:        u.Email,
 			Phone:        u.Phone,
-			Cell:         cell,
+			Cell:         nullableText(u.Cell),
 			Picture:      picture,
 			Registration: pgtype.Timestamp{Time: u.Registration, Valid: true},
+			Gender:       nullableText(u.Gender),
+			Title:        nullableText(u.Title),
+			FirstName:    nullableText(u.FirstName),
+			LastName:     nullableText(u.LastName),
+			DateOfBirth:  nullableTimestamp(u.DateOfBirth),
+			Nat:          nullableText(u.Nat),
+			NationalID:   nationalID,
+			Location:     location,
 		})
 	}
 
-	_, err := s.queries.LoadBulkUsers(ctx, params)
+	if _, err := s.queries.LoadStagedUsers(ctx, params); err != nil {
+		return repository.CreateResult{}, fmt.Errorf("failed to stage users: %w", err)
+	}
+	merged, err := s.queries.MergeStagedUsers(ctx, batchID)
 	if err != nil {
-		return fmt.Errorf("failed to create users: %w", err)
+		return repository.CreateResult{}, fmt.Errorf("failed to merge users: %w", err)
 	}
-	return nil
+	var res repository.CreateResult
+	for _, inserted := range merged {
+		if inserted {
+			res.Inserted++
+		} else {
+			res.Updated++
+		}
+	}
+	res.Skipped = len(users) - res.Inserted - res.Updated
+	return res, nil
+}
+
+// RestoreUser undoes the soft delete of the user with the given ID. The users
+// that are not soft deleted are not found.
+func (s *UserStorage) RestoreUser(ctx context.Context, id ksuid.KSUID) (*repository.User, error) {
+	row, err := s.queries.RestoreUser(ctx, id.String())
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, fmt.Errorf("failed to restore user %s: %w", id, repository.ErrNotFound)
+		}
+		return nil, fmt.Errorf("failed to restore user %s: %w", id, err)
+	}
+	u, err := rowToUser(sqlc.GetUserByIDRow(row))
+	if err != nil {
+		return nil, fmt.Errorf("failed to restore user %s: %w", id, err)
+	}
+	return u, nil
+}
+
+// PurgeUsers permanently removes the users soft deleted before the given time.
+func (s *UserStorage) PurgeUsers(ctx context.Context, deletedBefore time.Time) (int64, error) {
+	n, err := s.queries.PurgeUsers(ctx, pgtype.Timestamp{Time: deletedBefore, Valid: true})
+	if err != nil {
+		return 0, fmt.Errorf("failed to purge users: %w", err)
+	}
+	return n, nil
 }
@@ -2,6 +2,8 @@ package db_test
 
 import (
 	"context"
+	"errors"
+	"fmt"
 	"testing"
 	"time"
 
@@ -9,6 +11,7 @@ import (
 	"wonderful/internal/repository/db"
 	"wonderful/internal/repository/db/test"
 
+	"github.com/segmentio/ksuid"
 	"github.com/stretchr/testify/require"
 	"github.com/stretchr/testify/suite"
 	testcontainers "github.com/testcontainers/testcontainers-go/modules/postgres"
@@ -66,6 +69,174 @@ func (ts *UsersTestSuite) TearDownSuite() {
 	ts.s.Close()
 }
 
+func (ts *UsersTestSuite) TestCreateUpsert() {
+	ctx := context.Background()
+	u := db.NewUserStorage(ts.s.Pool())
+	newUsers := func() []repository.User {
+		users := make([]repository.User, len(usersRaw))
+		copy(users, usersRaw)
+		for i := range users {
+			users[i].ExternalID = fmt.Sprintf("external-%d", i)
+		}
+		return users
+	}
+
+	// first import inserts everything
+	res, err := u.Create(ctx, newUsers())
+	ts.Require().NoError(err)
+	ts.Require().Equal(repository.CreateResult{Inserted: 3}, res)
+
+	// importing the same users again changes nothing
+	res, err = u.Create(ctx, newUsers())
+	ts.Require().NoError(err)
+	ts.Require().Equal(repository.CreateResult{Skipped: 3}, res)
+
+	// changed users are updated, repeated ones are only merged once and users
+	// without an external ID are always inserted
+	users := newUsers()
+	users[0].Email = "john.doe@xpto.com"
+	users = append(users, users[0], usersRaw[0])
+	res, err = u.Create(ctx, users)
+	ts.Require().NoError(err)
+	ts.Require().Equal(repository.CreateResult{Inserted: 1, Updated: 1, Skipped: 3}, res)
+
+	all, err := u.ListUsers(ctx, repository.Params{Limit: 100})
+	ts.Require().NoError(err)
+	ts.Require().Len(all, 4)
+	emails := make([]string, 0, len(all))
+	for _, a := range all {
+		emails = append(emails, a.Email)
+	}
+	ts.Require().Contains(emails, "john.doe@xpto.com")
+
+	// the profile is stored and changes to it are merged
+	dob := time.Date(1980, time.May, 4, 0, 0, 0, 0, time.UTC)
+	users = newUsers()[:1]
+	users[0].Gender = "male"
+	users[0].Title, users[0].FirstName, users[0].LastName = "Mr", "John", "Doe"
+	users[0].DateOfBirth = &dob
+	users[0].Nat = "US"
+	users[0].NationalID = &repository.NationalID{Name: "SSN", Value: "123-45-6789"}
+	users[0].Location = &repository.Location{StreetNumber: 42, StreetName: "Main St", City: "Springfield", Postcode: "12345", Latitude: 39.78, Longitude: -89.65}
+	res, err = u.Create(ctx, users)
+	ts.Require().NoError(err)
+	ts.Require().Equal(repository.CreateResult{Updated: 1}, res)
+	all, err = u.ListUsers(ctx, repository.Params{Filters: repository.Filters{Email: &users[0].Email}})
+	ts.Require().NoError(err)
+	ts.Require().Len(all, 1)
+	ts.Require().Equal("Mr", all[0].Title)
+	ts.Require().Equal("Doe", all[0].LastName)
+	ts.Require().Equal("US", all[0].Nat)
+	ts.Require().True(dob.Equal(*all[0].DateOfBirth))
+	ts.Require().Equal(users[0].NationalID, all[0].NationalID)
+	ts.Require().Equal(users[0].Location, all[0].Location)
+	// a user coming without its profile, as imported ones, keeps the stored one
+	res, err = u.Create(ctx, newUsers()[:1])
+	ts.Require().NoError(err)
+	ts.Require().Equal(repository.CreateResult{Skipped: 1}, res)
+	all, err = u.ListUsers(ctx, repository.Params{Filters: repository.Filters{Email: &users[0].Email}})
+	ts.Require().NoError(err)
+	ts.Require().Len(all, 1)
+	ts.Require().Equal("US", all[0].Nat)
+	ts.Require().Equal(users[0].Location, all[0].Location)
+
+	// the filters are combined and applied to the count and the seek
+	str := func(s string) *string { return &s }
+	dayAndHalfAgo := time.Now().Add(-time.Hour * 36)
+	for _, tc := range []struct {
+		filters repository.Filters
+		want    int
+	}{
+		{repository.Filters{Nat: str("US")}, 1},
+		{repository.Filters{Gender: str("male"), Nat: str("US")}, 1},
+		{repository.Filters{Gender: str("female")}, 0},
+		{repository.Filters{Name: str("Smith")}, 1},
+		{repository.Filters{Name: str("smith")}, 1},
+		{repository.Filters{Email: str("JOHN.DOE@")}, 1},
+		{repository.Filters{Email: str("%")}, 0},
+		{repository.Filters{Name: str("_")}, 0},
+		{repository.Filters{Query: str("john")}, 3},
+		{repository.Filters{Phone: str("123456789")}, 3},
+		{repository.Filters{Cell: str("123456789")}, 0},
+		{repository.Filters{RegisteredAfter: &dayAndHalfAgo}, 2},
+		{repository.Filters{RegisteredBefore: &dayAndHalfAgo, Phone: str("123456789")}, 2},
+	} {
+		all, err = u.ListUsers(ctx, repository.Params{Filters: tc.filters, Limit: 100})
+		ts.Require().NoError(err)
+		ts.Require().Len(all, tc.want, "%+v", tc.filters)
+		var n int64
+		n, err = u.CountUsers(ctx, repository.Params{Filters: tc.filters})
+		ts.Require().NoError(err)
+		ts.Require().Equal(int64(tc.want), n, "%+v", tc.filters)
+	}
+	phone := repository.Filters{Phone: str("123456789")}
+	all, err = u.ListUsers(ctx, repository.Params{Filters: phone, Limit: 2})
+	ts.Require().NoError(err)
+	ts.Require().Len(all, 2)
+	key := &repository.SeekKey{Registration: all[1].Registration, ID: all[1].ID}
+	all, err = u.ListUsers(ctx, repository.Params{Filters: phone, StartingAfter: key})
+	ts.Require().NoError(err)
+	ts.Require().Len(all, 1)
+	ts.Require().Equal("123456789", all[0].Phone)
+
+	// the search lists the most relevant users first
+	search := repository.Filters{Query: str("john smith")}
+	byRelevance := []repository.SortKey{{Field: repository.SortRelevance, Desc: true}, {Field: repository.SortRegistration, Desc: true}}
+	all, err = u.ListUsers(ctx, repository.Params{Filters: search, Sort: byRelevance})
+	ts.Require().NoError(err)
+	ts.Require().NotEmpty(all)
+	ts.Require().Equal("Mr. John Smith", all[0].Name)
+	ts.Require().Positive(all[0].Rank)
+	key = &repository.SeekKey{Rank: all[0].Rank, Registration: all[0].Registration, ID: all[0].ID}
+	next, err := u.ListUsers(ctx, repository.Params{Filters: search, Sort: byRelevance, StartingAfter: key})
+	ts.Require().NoError(err)
+	ts.Require().Equal(all[1:], next)
+
+	// the full-text search matches all the terms, prefixes and phrases
+	for _, tc := range []struct {
+		query string
+		want  int
+	}{
+		{"john", 3},
+		{"JOHN smith", 1},
+		{"jo*", 3},
+		{`"john doe"`, 2},
+		{`"doe john"`, 0},
+		{"springfield", 1},
+		{"john & !doe", 2}, // not read as operators
+		{"'", 0},
+	} {
+		var results []repository.SearchResult
+		results, err = u.SearchUsers(ctx, repository.SearchParams{Query: tc.query, Limit: 100})
+		ts.Require().NoError(err, tc.query)
+		ts.Require().Len(results, tc.want, tc.query)
+	}
+	results, err := u.SearchUsers(ctx, repository.SearchParams{Query: "springfield"})
+	ts.Require().NoError(err)
+	ts.Require().Len(results, 1)
+	ts.Require().Equal("Mr. John Doe", results[0].Name)
+	ts.Require().Positive(results[0].Rank)
+	ts.Require().Contains(results[0].LocationHeadline, "<mark>Springfield</mark>")
+	ts.Require().NotContains(results[0].NameHeadline, "<mark>")
+	results, err = u.SearchUsers(ctx, repository.SearchParams{Query: "doe", Limit: 100})
+	ts.Require().NoError(err)
+	ts.Require().Len(results, 3)
+	ts.Require().Contains(results[0].NameHeadline, "<mark>Doe</mark>")
+	key = &repository.SeekKey{Rank: results[0].Rank, ID: results[0].ID}
+	rest, err := u.SearchUsers(ctx, repository.SearchParams{Query: "doe", After: key, Limit: 100})
+	ts.Require().NoError(err)
+	ts.Require().Equal(results[1:], rest)
+
+	// nothing is left in the staging table
+	var staged int
+	ts.Require().NoError(ts.s.Pool().QueryRow(ctx, "SELECT COUNT(*) FROM users_staging").Scan(&staged))
+	ts.Require().Zero(staged)
+
+	// Delete the table to reset the state of the database.
+	_, err = ts.s.Pool().Exec(ctx, "DELETE FROM users")
+	ts.Require().NoError(err)
+}
+
 func (ts *UsersTestSuite) TestData() {
 	ctx := context.Background()
 
@@ -84,8 +255,9 @@ func (ts *UsersTestSuite) TestData() {
 
 	// Insert users
 	{
-		err := u.Create(ctx, usersRaw)
+		res, err := u.Create(ctx, usersRaw)
 		ts.Require().NoError(err)
+		ts.Require().Equal(repository.CreateResult{Inserted: 3}, res)
 	}
 
 	// Find all users after insert
@@ -109,16 +281,183 @@ func (ts *UsersTestSuite) TestData() {
 		ts.Require().Len(users, 1)
 		ts.Require().Equal(users[0].Name, "Mr. John Smith")
 		// Find the previous user
-		p = repository.Params{Limit: 1, StartingAfter: &users[0].ID}
+		p = repository.Params{Limit: 1, StartingAfter: &repository.SeekKey{Registration: users[0].Registration, ID: users[0].ID}}
 		users, err = u.ListUsers(ctx, p)
 		ts.Require().NoError(err)
 		ts.Require().Len(users, 1)
 		ts.Require().Equal(users[0].Name, "Mrs. Jane Doe")
 		// Find the next user
-		p = repository.Params{Limit: 1, EndingBefore: &users[0].ID}
+		p = repository.Params{Limit: 1, EndingBefore: &repository.SeekKey{Registration: users[0].Registration, ID: users[0].ID}}
 		users, err = u.ListUsers(ctx, p)
 		ts.Require().NoError(err)
 		ts.Require().Len(users, 1)
 		ts.Require().Equal(users[0].Name, "Mr. John Smith")
 	}
+
+	// Sort by name and seek on it
+	{
+		byName := []repository.SortKey{{Field: repository.SortName}}
+		users, err := u.ListUsers(ctx, repository.Params{Sort: byName})
+		ts.Require().NoError(err)
+		ts.Require().Len(users, 3)
+		ts.Require().Equal("Mr. John Doe", users[0].Name)
+		ts.Require().Equal("Mr. John Smith", users[1].Name)
+		ts.Require().Equal("Mrs. Jane Doe", users[2].Name)
+		p := repository.Params{Sort: byName, StartingAfter: &repository.SeekKey{Name: users[0].Name, ID: users[0].ID}}
+		users, err = u.ListUsers(ctx, p)
+		ts.Require().NoError(err)
+		ts.Require().Len(users, 2)
+		ts.Require().Equal("Mr. John Smith", users[0].Name)
+		// descending
+		byName[0].Desc = true
+		p = repository.Params{Sort: byName, Limit: 1, EndingBefore: &repository.SeekKey{Name: users[0].Name, ID: users[0].ID}}
+		users, err = u.ListUsers(ctx, p)
+		ts.Require().NoError(err)
+		ts.Require().Len(users, 1)
+		ts.Require().Equal("Mrs. Jane Doe", users[0].Name)
+	}
+
+	// Sort in mixed directions and seek on them
+	{
+		mixed := []repository.SortKey{{Field: repository.SortEmail}, {Field: repository.SortRegistration, Desc: true}}
+		users, err := u.ListUsers(ctx, repository.Params{Sort: mixed, Limit: 1})
+		ts.Require().NoError(err)
+		ts.Require().Len(users, 1)
+		ts.Require().Equal("jane@xpto.com", users[0].Email)
+		key := &repository.SeekKey{Email: users[0].Email, Registration: users[0].Registration, ID: users[0].ID}
+		users, err = u.ListUsers(ctx, repository.Params{Sort: mixed, StartingAfter: key})
+		ts.Require().NoError(err)
+		ts.Require().Len(users, 2)
+		ts.Require().Equal("john@xpto.com", users[0].Email)
+		ts.Require().Equal("smith@xpto.com", users[1].Email)
+		key = &repository.SeekKey{Email: users[1].Email, Registration: users[1].Registration, ID: users[1].ID}
+		users, err = u.ListUsers(ctx, repository.Params{Sort: mixed, EndingBefore: key})
+		ts.Require().NoError(err)
+		ts.Require().Len(users, 2)
+		ts.Require().Equal("jane@xpto.com", users[0].Email)
+		ts.Require().Equal("john@xpto.com", users[1].Email)
+	}
+
+	// Only list some fields, and the ones of the sort order and of the version
+	{
+		byEmail := []repository.SortKey{{Field: repository.SortEmail}}
+		fields := []repository.UserField{repository.FieldName, repository.FieldPicture}
+		users, err := u.ListUsers(ctx, repository.Params{Sort: byEmail, Fields: fields})
+		ts.Require().NoError(err)
+		ts.Require().Len(users, 3)
+		ts.Require().Equal("Mrs. Jane Doe", users[0].Name)
+		ts.Require().Equal("jane@xpto.com", users[0].Email)
+		ts.Require().Equal(usersRaw[1].Picture, users[0].Picture)
+		ts.Require().Empty(users[0].Phone)
+		ts.Require().True(users[0].Registration.IsZero())
+		ts.Require().False(users[0].CreatedAt.IsZero())
+		ts.Require().False(users[0].ID.IsNil())
+		key := &repository.SeekKey{Email: users[0].Email, ID: users[0].ID}
+		users, err = u.ListUsers(ctx, repository.Params{Sort: byEmail, Fields: fields, StartingAfter: key})
+		ts.Require().NoError(err)
+		ts.Require().Len(users, 2)
+		ts.Require().Equal("john@xpto.com", users[0].Email)
+		// the ID alone
+		users, err = u.ListUsers(ctx, repository.Params{Fields: []repository.UserField{}})
+		ts.Require().NoError(err)
+		ts.Require().Len(users, 3)
+		ts.Require().Empty(users[0].Name)
+		ts.Require().Nil(users[0].Picture)
+		ts.Require().False(users[0].Registration.IsZero())
+	}
+
+	// Export the users from a cursor, which needs a transaction
+	{
+		var names []string
+		export := func(u *repository.User) error {
+			names = append(names, u.Name)
+			return nil
+		}
+		ts.Require().Error(u.ExportUsers(ctx, repository.Filters{}, export))
+		tx, err := pool.Begin(ctx)
+		ts.Require().NoError(err)
+		defer tx.Rollback(ctx) //nolint:errcheck //test
+		txUsers := db.NewUserStorage(tx)
+		ts.Require().NoError(txUsers.ExportUsers(ctx, repository.Filters{}, export))
+		ts.Require().Equal([]string{"Mr. John Smith", "Mrs. Jane Doe", "Mr. John Doe"}, names)
+		names = nil
+		john := "john"
+		ts.Require().NoError(txUsers.ExportUsers(ctx, repository.Filters{Name: &john}, export))
+		ts.Require().Equal([]string{"Mr. John Smith", "Mr. John Doe"}, names)
+		// an error of the callback stops the export
+		errStop := errors.New("stop")
+		names = nil
+		err = txUsers.ExportUsers(ctx, repository.Filters{}, func(u *repository.User) error {
+			names = append(names, u.Name)
+			return errStop
+		})
+		ts.Require().ErrorIs(err, errStop)
+		ts.Require().Len(names, 1)
+	}
+
+	// Find a user by ID
+	{
+		users, err := u.ListUsers(ctx, repository.Params{Limit: 1})
+		ts.Require().NoError(err)
+		ts.Require().Len(users, 1)
+		user, err := u.GetUserByID(ctx, users[0].ID)
+		ts.Require().NoError(err)
+		ts.Require().Equal(users[0], *user)
+		// Unknown ID
+		_, err = u.GetUserByID(ctx, ksuid.New())
+		ts.Require().ErrorIs(err, repository.ErrNotFound)
+	}
+}
+
+func (ts *UsersTestSuite) TestUserCRUD() {
+	ctx := context.Background()
+	u := db.NewUserStorage(ts.s.Pool())
+
+	created, err := u.CreateUser(ctx, usersRaw[0])
+	ts.Require().NoError(err)
+	ts.Require().False(created.ID.IsNil())
+	ts.Require().Equal(usersRaw[0].Name, created.Name)
+	ts.Require().Nil(created.UpdatedAt)
+
+	created.Name = "Mr. John Doe Jr."
+	updated, err := u.UpdateUser(ctx, *created)
+	ts.Require().NoError(err)
+	ts.Require().Equal("Mr. John Doe Jr.", updated.Name)
+	ts.Require().NotNil(updated.UpdatedAt)
+
+	err = u.DeleteUser(ctx, created.ID)
+	ts.Require().NoError(err)
+	err = u.DeleteUser(ctx, created.ID)
+	ts.Require().ErrorIs(err, repository.ErrNotFound)
+	_, err = u.UpdateUser(ctx, *created)
+	ts.Require().ErrorIs(err, repository.ErrNotFound)
+	_, err = u.GetUserByID(ctx, created.ID)
+	ts.Require().ErrorIs(err, repository.ErrNotFound)
+
+	// soft-deleted users are only listed on request
+	users, err := u.ListUsers(ctx, repository.Params{})
+	ts.Require().NoError(err)
+	ts.Require().Len(users, 0)
+	users, err = u.ListUsers(ctx, repository.Params{Filters: repository.Filters{IncludeDeleted: true}})
+	ts.Require().NoError(err)
+	ts.Require().Len(users, 1)
+	ts.Require().NotNil(users[0].DeletedAt)
+
+	restored, err := u.RestoreUser(ctx, created.ID)
+	ts.Require().NoError(err)
+	ts.Require().Nil(restored.DeletedAt)
+	// only the soft-deleted users are restored
+	_, err = u.RestoreUser(ctx, created.ID)
+	ts.Require().ErrorIs(err, repository.ErrNotFound)
+
+	err = u.DeleteUser(ctx, created.ID)
+	ts.Require().NoError(err)
+	n, err := u.PurgeUsers(ctx, time.Now().UTC().Add(-time.Hour))
+	ts.Require().NoError(err)
+	ts.Require().Equal(int64(0), n)
+	n, err = u.PurgeUsers(ctx, time.Now().UTC().Add(time.Hour))
+	ts.Require().NoError(err)
+	ts.Require().Equal(int64(1), n)
+	_, err = u.RestoreUser(ctx, created.ID)
+	ts.Require().ErrorIs(err, repository.ErrNotFound)
 }
@@ -2,10 +2,40 @@ package repository
 
 import (
 	"context"
+	"time"
+
+	"github.com/segmentio/ksuid"
 )
 
 // UserRepository represents a repository for users.
 type UserRepository interface {
 	ListUsers(ctx context.Context, p Params) ([]User, error)
-	Create(ctx context.Context, users []User) error
+	CountUsers(ctx context.Context, p Params) (int64, error)
+	SearchUsers(ctx context.Context, p SearchParams) ([]SearchResult, error)
+	ExportUsers(ctx context.Context, f Filters, fn func(*User) error) error
+	GetUserByID(ctx context.Context, id ksuid.KSUID) (*User, error)
+	GetUserForUpdate(ctx context.Context, id ksuid.KSUID) (*User, error)
+	Create(ctx context.Context, users []User) (CreateResult, error)
+	CreateUser(ctx context.Context, u User) (*User, error)
+	UpdateUser(ctx context.Context, u User) (*User, error)
+	DeleteUser(ctx context.Context, id ksuid.KSUID) error
+	RestoreUser(ctx context.Context, id ksuid.KSUID) (*User, error)
+	PurgeUsers(ctx context.Context, deletedBefore time.Time) (int64, error)
+}
+
+// JobRepository represents a repository for background jobs.
+type JobRepository interface {
+	CreateJob(ctx context.Context, id ksuid.KSUID, state string, opts PopulateOptions) (*Job, error)
+	GetJob(ctx context.Context, id ksuid.KSUID) (*Job, error)
+	UpdateJob(ctx context.Context, j Job) (*Job, error)
+	ListJobsByState(ctx context.Context, state string) ([]Job, error)
+	FailInterruptedJobs(ctx context.Context, reason string) (int64, error)
+}
+
+// IdempotencyRepository represents a repository for request idempotency keys.
+type IdempotencyRepository interface {
+	ClaimKey(ctx context.Context, key, fingerprint string, expiredBefore time.Time) (bool, error)
+	GetKey(ctx context.Context, key string) (*IdempotencyKey, error)
+	CompleteKey(ctx context.Context, k IdempotencyKey) error
+	DeleteKey(ctx context.Context, key string) error
 }
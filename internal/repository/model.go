@@ -8,19 +8,197 @@ import (
 
 // Params is a struct that holds the parameters for the ListUsers method.
 type Params struct {
-	Email         *string
-	StartingAfter *ksuid.KSUID
-	EndingBefore  *ksuid.KSUID
+	Filters
+	StartingAfter *SeekKey
+	EndingBefore  *SeekKey
 	Limit         int
+	// Sort is the listing order, by registration descending when empty. The
+	// users are always ordered by ID last, so that the order is total.
+	Sort []SortKey
+	// Fields are the fields of the users to list, all of them when nil. The
+	// ID and the fields of the sort order are always listed, the others are
+	// left empty.
+	Fields []UserField
+}
+
+// Filters is a struct that holds the filters of a user listing. Nil filters are not applied.
+type Filters struct {
+	// Email and Name match the users containing them, ignoring the case.
+	Email *string
+	Name  *string
+	// Query matches the users whose name or email contains it or is similar to it.
+	Query *string
+	// Phone, Cell, Nat and Gender match the users equal to them.
+	Phone  *string
+	Cell   *string
+	Nat    *string
+	Gender *string
+	// RegisteredAfter and RegisteredBefore match the users registered strictly after and before them.
+	RegisteredAfter  *time.Time
+	RegisteredBefore *time.Time
+	IncludeDeleted   bool
+}
+
+// SortField is a user field the listings can be sorted by.
+type SortField string
+
+// The sortable user fields.
+const (
+	SortName         SortField = "name"
+	SortEmail        SortField = "email"
+	SortRegistration SortField = "registration"
+	SortCreatedAt    SortField = "created_at"
+	// SortRelevance sorts by the similarity to the Query filter.
+	SortRelevance SortField = "relevance"
+)
+
+// UserField is a user field a listing can be restricted to.
+type UserField string
+
+// The user fields. The picture sizes select a part of the picture.
+const (
+	FieldName             UserField = "name"
+	FieldEmail            UserField = "email"
+	FieldPhone            UserField = "phone"
+	FieldCell             UserField = "cell"
+	FieldPicture          UserField = "picture"
+	FieldPictureLarge     UserField = "picture.large"
+	FieldPictureMedium    UserField = "picture.medium"
+	FieldPictureThumbnail UserField = "picture.thumbnail"
+	FieldRegistration     UserField = "registration"
+	FieldCreatedAt        UserField = "created_at"
+	FieldUpdatedAt        UserField = "updated_at"
+	FieldDeletedAt        UserField = "deleted_at"
+	FieldGender           UserField = "gender"
+	FieldTitle            UserField = "title"
+	FieldFirstName        UserField = "first_name"
+	FieldLastName         UserField = "last_name"
+	FieldDateOfBirth      UserField = "date_of_birth"
+	FieldNat              UserField = "nat"
+	FieldNationalID       UserField = "national_id"
+	FieldLocation         UserField = "location"
+)
+
+// SortKey is a struct that holds a field of a listing order and its direction.
+type SortKey struct {
+	Field SortField
+	Desc  bool
+}
+
+// SeekKey is a struct that holds the position of a user in the listing order,
+// which the listing seeks to instead of looking the user up. Only the fields
+// of the sort order and the ID are used.
+type SeekKey struct {
+	Name         string
+	Email        string
+	Registration time.Time
+	CreatedAt    time.Time
+	Rank         float32
+	ID           ksuid.KSUID
+}
+
+// SearchParams is a struct that holds the parameters for the SearchUsers method.
+type SearchParams struct {
+	// Query is a list of terms, all of which must match. A term ending with
+	// "*" matches as a prefix and the terms between double quotes as a phrase.
+	Query string
+	// After is the rank and the ID of the last user of the previous page.
+	After          *SeekKey
+	Limit          int
+	IncludeDeleted bool
+}
+
+// SearchResult is a struct that holds a user found by a search, with the
+// matches of its fields highlighted between <mark> tags.
+type SearchResult struct {
+	User
+	NameHeadline     string
+	EmailHeadline    string
+	LocationHeadline string
 }
 
 // User is a struct that holds the user information.
 type User struct {
 	ID           ksuid.KSUID
+	ExternalID   string
 	Name         string
 	Email        string
 	Phone        string
 	Cell         string
 	Picture      map[string]string
 	Registration time.Time
+	CreatedAt    time.Time
+	UpdatedAt    *time.Time
+	DeletedAt    *time.Time
+	Gender       string
+	Title        string
+	FirstName    string
+	LastName     string
+	DateOfBirth  *time.Time
+	Nat          string
+	NationalID   *NationalID
+	Location     *Location
+	// Rank is the relevance of a listed user to the search query, if any.
+	Rank float32
+}
+
+// NationalID is a struct that holds a national identifier of a user, e.g. a SSN.
+type NationalID struct {
+	Name  string `json:"name"`
+	Value string `json:"value"`
+}
+
+// Location is a struct that holds the postal address and coordinates of a user.
+type Location struct {
+	StreetNumber int     `json:"street_number,omitempty"`
+	StreetName   string  `json:"street_name,omitempty"`
+	City         string  `json:"city,omitempty"`
+	State        string  `json:"state,omitempty"`
+	Country      string  `json:"country,omitempty"`
+	Postcode     string  `json:"postcode,omitempty"`
+	Latitude     float64 `json:"latitude"`
+	Longitude    float64 `json:"longitude"`
+}
+
+// CreateResult is a struct that holds the outcome of a bulk create.
+type CreateResult struct {
+	Inserted int
+	Updated  int
+	Skipped  int
+}
+
+// Job is a struct that holds the state of a background populate job.
+type Job struct {
+	ID         ksuid.KSUID
+	State      string
+	Fetched    int
+	Inserted   int
+	Updated    int
+	Skipped    int
+	Error      string
+	CreatedAt  time.Time
+	UpdatedAt  *time.Time
+	FinishedAt *time.Time
+	Options    PopulateOptions
+}
+
+// PopulateOptions is a struct that holds the options a populate job was queued with.
+type PopulateOptions struct {
+	Count  int      `json:"count,omitempty"`
+	Seed   string   `json:"seed,omitempty"`
+	Nat    []string `json:"nat,omitempty"`
+	Gender string   `json:"gender,omitempty"`
+	Source string   `json:"source,omitempty"`
+}
+
+// IdempotencyKey is a struct that holds a request idempotency key and the
+// response recorded for it. StatusCode is 0 until the response is recorded.
+type IdempotencyKey struct {
+	Key         string
+	Fingerprint string
+	StatusCode  int
+	Header      map[string][]string
+	Body        []byte
+	CreatedAt   time.Time
+	CompletedAt *time.Time
 }
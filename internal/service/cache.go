@@ -0,0 +1,160 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"slices"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"wonderful/internal/entities"
+	"wonderful/internal/repository"
+)
+
+// PageCache stores the pages of users of the listings of a cached
+// UserService, by key. NewLRUCache is the in-process one. An implementation
+// must be safe for concurrent use. The pages it returns are shared and must
+// not be modified.
+type PageCache interface {
+	Get(ctx context.Context, key string) (*entities.UserPage, bool)
+	Set(ctx context.Context, key string, page *entities.UserPage, ttl time.Duration)
+	Purge(ctx context.Context)
+}
+
+// CacheStats are the counters of the listings of a cached UserService.
+type CacheStats struct {
+	Hits   int64 `json:"hits"`
+	Misses int64 `json:"misses"`
+}
+
+// cachedUserService is a UserService that caches the pages of users listed
+// by another one for a TTL. Every write through it purges the cache; the
+// writes made around it must call Invalidate. The other methods are not
+// cached.
+type cachedUserService struct {
+	UserService
+	cache   PageCache
+	ttl     time.Duration
+	cursors cursorCodec
+
+	// mu orders the writes of pages with the invalidations: a page read
+	// before an invalidation is not written after it.
+	mu           sync.RWMutex
+	generation   uint64
+	hits, misses atomic.Int64
+}
+
+// NewCachedUserService creates a UserService caching the pages of users listed
+// by next in cache for ttl. The cursors are decoded with cursorSecret, the one
+// of next, so that the listings reading the same users share their page.
+func NewCachedUserService(next UserService, cache PageCache, ttl time.Duration, cursorSecret []byte) *cachedUserService {
+	return &cachedUserService{
+		UserService: next,
+		cache:       cache,
+		ttl:         ttl,
+		cursors:     cursorCodec{secret: cursorSecret},
+	}
+}
+
+// ListUsers returns the cached page of the listing, or lists it and caches it.
+func (c *cachedUserService) ListUsers(ctx context.Context, lp ListParams) (*entities.UserPage, error) {
+	key, err := c.cacheKey(&lp)
+	if err != nil {
+		// the listing is rejected with the same error.
+		return c.UserService.ListUsers(ctx, lp) //nolint:wrapcheck //already wrapped by the service
+	}
+	if page, ok := c.cache.Get(ctx, key); ok {
+		c.hits.Add(1)
+		return page, nil
+	}
+	c.misses.Add(1)
+	c.mu.RLock()
+	generation := c.generation
+	c.mu.RUnlock()
+	page, err := c.UserService.ListUsers(ctx, lp)
+	if err != nil {
+		return nil, err //nolint:wrapcheck //already wrapped by the service
+	}
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	if c.generation == generation {
+		c.cache.Set(ctx, key, page, c.ttl)
+	}
+	return page, nil
+}
+
+// cacheKey returns the key of the page of a listing: its repository.Params,
+// in which the cursors are decoded and the defaults are applied, and whether
+// it is counted. The order of the fields does not change the page.
+func (c *cachedUserService) cacheKey(lp *ListParams) (string, error) {
+	p, err := c.cursors.repositoryParams(lp)
+	if err != nil {
+		return "", err
+	}
+	if p.Limit == 0 {
+		p.Limit = defaultListLimit
+	}
+	p.Fields = slices.Clone(p.Fields)
+	slices.Sort(p.Fields)
+	key, err := json.Marshal(struct {
+		repository.Params
+		IncludeTotal bool
+	}{p, lp.IncludeTotal})
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal the cache key: %w", err)
+	}
+	return string(key), nil
+}
+
+// Invalidate purges the cache, and keeps the pages being listed from being cached.
+func (c *cachedUserService) Invalidate(ctx context.Context) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.generation++
+	c.cache.Purge(ctx)
+}
+
+// Stats returns the numbers of listings read from the cache and listed since the start.
+func (c *cachedUserService) Stats() CacheStats {
+	return CacheStats{Hits: c.hits.Load(), Misses: c.misses.Load()}
+}
+
+// The writes invalidate the cache, also when they fail, as some of them may
+// have been partly applied.
+
+func (c *cachedUserService) ImportUsers(ctx context.Context, next func() (*ImportRecord, error)) (*entities.ImportReport, error) {
+	defer c.Invalidate(ctx)
+	return c.UserService.ImportUsers(ctx, next) //nolint:wrapcheck //already wrapped by the service
+}
+
+func (c *cachedUserService) CreateUser(ctx context.Context, u entities.User) (*entities.User, error) {
+	defer c.Invalidate(ctx)
+	return c.UserService.CreateUser(ctx, u) //nolint:wrapcheck //already wrapped by the service
+}
+
+func (c *cachedUserService) ReplaceUser(ctx context.Context, id string, u entities.User, ifMatch Precondition) (*entities.User, error) {
+	defer c.Invalidate(ctx)
+	return c.UserService.ReplaceUser(ctx, id, u, ifMatch) //nolint:wrapcheck //already wrapped by the service
+}
+
+func (c *cachedUserService) PatchUser(ctx context.Context, id string, patch entities.UserPatch, ifMatch Precondition) (*entities.User, error) {
+	defer c.Invalidate(ctx)
+	return c.UserService.PatchUser(ctx, id, patch, ifMatch) //nolint:wrapcheck //already wrapped by the service
+}
+
+func (c *cachedUserService) DeleteUser(ctx context.Context, id string, ifMatch Precondition) error {
+	defer c.Invalidate(ctx)
+	return c.UserService.DeleteUser(ctx, id, ifMatch) //nolint:wrapcheck //already wrapped by the service
+}
+
+func (c *cachedUserService) RestoreUser(ctx context.Context, id string) (*entities.User, error) {
+	defer c.Invalidate(ctx)
+	return c.UserService.RestoreUser(ctx, id) //nolint:wrapcheck //already wrapped by the service
+}
+
+func (c *cachedUserService) PurgeUsers(ctx context.Context, deletedBefore time.Time) (int64, error) {
+	defer c.Invalidate(ctx)
+	return c.UserService.PurgeUsers(ctx, deletedBefore) //nolint:wrapcheck //already wrapped by the service
+}
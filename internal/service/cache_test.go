@@ -0,0 +1,94 @@
+package service_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"wonderful/internal/entities"
+	"wonderful/internal/service"
+
+	"github.com/stretchr/testify/require"
+)
+
+// listCounter is a UserService counting its listings.
+type listCounter struct {
+	service.UserService
+	lists int
+}
+
+func (l *listCounter) ListUsers(_ context.Context, lp service.ListParams) (*entities.UserPage, error) {
+	if lp.StartingAfter != "" {
+		return nil, service.ErrInvalidCursor
+	}
+	l.lists++
+	return &entities.UserPage{Users: []entities.User{{Name: "Mr. John Doe"}}}, nil
+}
+
+func (l *listCounter) CreateUser(_ context.Context, u entities.User) (*entities.User, error) {
+	return &u, nil
+}
+
+func TestCachedUserService(t *testing.T) {
+	ctx := context.Background()
+	next := &listCounter{}
+	cache := service.NewLRUCache(2)
+	su := service.NewCachedUserService(next, cache, time.Minute, []byte("test"))
+
+	// the listings reading the same users share their page
+	page, err := su.ListUsers(ctx, service.ListParams{Fields: []string{"name", "email"}})
+	require.NoError(t, err)
+	require.Equal(t, "Mr. John Doe", page.Users[0].Name)
+	cached, err := su.ListUsers(ctx, service.ListParams{Limit: 10, Fields: []string{"email", "name"}})
+	require.NoError(t, err)
+	require.Same(t, page, cached)
+	require.Equal(t, 1, next.lists)
+	require.Equal(t, service.CacheStats{Hits: 1, Misses: 1}, su.Stats())
+
+	// the other ones do not
+	_, err = su.ListUsers(ctx, service.ListParams{IncludeTotal: true})
+	require.NoError(t, err)
+	require.Equal(t, 2, next.lists)
+	// and the invalid ones are not cached
+	for range 2 {
+		_, err = su.ListUsers(ctx, service.ListParams{StartingAfter: "not-a-cursor"})
+		require.ErrorIs(t, err, service.ErrInvalidCursor)
+	}
+	require.Equal(t, service.CacheStats{Hits: 1, Misses: 2}, su.Stats())
+
+	// the least recently used page is evicted
+	_, err = su.ListUsers(ctx, service.ListParams{Limit: 1})
+	require.NoError(t, err)
+	require.Equal(t, 2, cache.Len())
+	_, err = su.ListUsers(ctx, service.ListParams{Fields: []string{"name", "email"}})
+	require.NoError(t, err)
+	require.Equal(t, 4, next.lists)
+	_, err = su.ListUsers(ctx, service.ListParams{Limit: 1})
+	require.NoError(t, err)
+	require.Equal(t, 4, next.lists)
+
+	// the writes purge the cache
+	_, err = su.CreateUser(ctx, entities.User{Name: "Mrs. Jane Doe"})
+	require.NoError(t, err)
+	require.Equal(t, 0, cache.Len())
+	_, err = su.ListUsers(ctx, service.ListParams{Limit: 1})
+	require.NoError(t, err)
+	require.Equal(t, 5, next.lists)
+	require.Equal(t, service.CacheStats{Hits: 2, Misses: 5}, su.Stats())
+}
+
+func TestCachedUserServiceTTL(t *testing.T) {
+	ctx := context.Background()
+	next := &listCounter{}
+	su := service.NewCachedUserService(next, service.NewLRUCache(10), 50*time.Millisecond, []byte("test"))
+
+	for range 2 {
+		_, err := su.ListUsers(ctx, service.ListParams{})
+		require.NoError(t, err)
+	}
+	require.Equal(t, 1, next.lists)
+	time.Sleep(100 * time.Millisecond)
+	_, err := su.ListUsers(ctx, service.ListParams{})
+	require.NoError(t, err)
+	require.Equal(t, 2, next.lists)
+}
@@ -0,0 +1,189 @@
+package service
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"wonderful/internal/repository"
+
+	"github.com/segmentio/ksuid"
+)
+
+// The directions a cursor is read in. Next cursors go in starting_after and
+// previous cursors in ending_before.
+const (
+	cursorNext = "next"
+	cursorPrev = "prev"
+)
+
+// sortSearch is the sort of the search cursors, by rank and ID.
+const sortSearch = "search"
+
+// cursorMACSize is the length of the truncated HMAC-SHA256 appended to a cursor.
+const cursorMACSize = 16
+
+// cursorDigestSize is the length of the truncated SHA-256 of the filters of a cursor.
+const cursorDigestSize = 8
+
+// cursor is the position of a page boundary in a user listing. Besides the
+// sort key of the boundary user, made of the sorted fields and the ID, it
+// holds how the listing was sorted and filtered, so that it is not reused
+// for another listing.
+type cursor struct {
+	Name         *string    `json:"n,omitempty"`
+	Email        *string    `json:"m,omitempty"`
+	Registration *time.Time `json:"r,omitempty"`
+	CreatedAt    *time.Time `json:"c,omitempty"`
+	Rank         *float32   `json:"k,omitempty"`
+	ID           string     `json:"i"`
+	Sort         string     `json:"s"`
+	Direction    string     `json:"d"`
+	Filters      string     `json:"f"`
+}
+
+// cursorCodec encodes the cursors as opaque base64 tokens signed with an
+// HMAC, so that clients can not forge or alter them.
+type cursorCodec struct {
+	secret []byte
+}
+
+func (c cursorCodec) mac(payload []byte) []byte {
+	h := hmac.New(sha256.New, c.secret)
+	h.Write(payload) //nolint:errcheck //hash writes never fail
+	return h.Sum(nil)[:cursorMACSize]
+}
+
+// encode returns the token of a cursor.
+func (c cursorCodec) encode(cur cursor) string {
+	payload, _ := json.Marshal(cur) //nolint:errchkjson //a cursor always marshals
+	return base64.RawURLEncoding.EncodeToString(append(payload, c.mac(payload)...))
+}
+
+// decode verifies a token and returns its cursor.
+func (c cursorCodec) decode(token string) (cursor, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(token)
+	if err != nil || len(raw) <= cursorMACSize {
+		return cursor{}, fmt.Errorf("%w: malformed cursor", ErrInvalidCursor)
+	}
+	payload, mac := raw[:len(raw)-cursorMACSize], raw[len(raw)-cursorMACSize:]
+	if !hmac.Equal(mac, c.mac(payload)) {
+		return cursor{}, fmt.Errorf("%w: bad cursor signature", ErrInvalidCursor)
+	}
+	var cur cursor
+	dec := json.NewDecoder(bytes.NewReader(payload))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(&cur); err != nil {
+		return cursor{}, fmt.Errorf("%w: malformed cursor", ErrInvalidCursor)
+	}
+	return cur, nil
+}
+
+// seekKey checks that the cursor belongs to the listing of p, read in the
+// direction, and returns the position to seek to.
+func (cur cursor) seekKey(direction string, p *ListParams) (*repository.SeekKey, error) {
+	if cur.Direction != direction {
+		return nil, fmt.Errorf("%w: cursor is for the %s page", ErrInvalidCursor, cur.Direction)
+	}
+	sort := p.sort()
+	if cur.Sort != formatSort(sort) || cur.Filters != filtersDigest(&p.ListFilters) {
+		return nil, fmt.Errorf("%w: cursor is for another listing", ErrInvalidCursor)
+	}
+	id, err := ksuid.Parse(cur.ID)
+	if err != nil {
+		return nil, fmt.Errorf("%w: malformed cursor", ErrInvalidCursor)
+	}
+	k := &repository.SeekKey{ID: id}
+	for _, s := range sort {
+		var ok bool
+		switch s.Field {
+		case repository.SortName:
+			k.Name, ok = deref(cur.Name), cur.Name != nil
+		case repository.SortEmail:
+			k.Email, ok = deref(cur.Email), cur.Email != nil
+		case repository.SortRegistration:
+			k.Registration, ok = deref(cur.Registration), cur.Registration != nil
+		case repository.SortCreatedAt:
+			k.CreatedAt, ok = deref(cur.CreatedAt), cur.CreatedAt != nil
+		case repository.SortRelevance:
+			k.Rank, ok = deref(cur.Rank), cur.Rank != nil
+		}
+		if !ok {
+			return nil, fmt.Errorf("%w: malformed cursor", ErrInvalidCursor)
+		}
+	}
+	return k, nil
+}
+
+// newCursor returns the cursor of a page boundary user of the listing of p.
+func newCursor(u *repository.User, direction string, p *ListParams) cursor {
+	sort := p.sort()
+	cur := cursor{
+		ID:        u.ID.String(),
+		Sort:      formatSort(sort),
+		Direction: direction,
+		Filters:   filtersDigest(&p.ListFilters),
+	}
+	for _, s := range sort {
+		switch s.Field {
+		case repository.SortName:
+			cur.Name = &u.Name
+		case repository.SortEmail:
+			cur.Email = &u.Email
+		case repository.SortRegistration:
+			cur.Registration = &u.Registration
+		case repository.SortCreatedAt:
+			cur.CreatedAt = &u.CreatedAt
+		case repository.SortRelevance:
+			cur.Rank = &u.Rank
+		}
+	}
+	return cur
+}
+
+// searchSeekKey checks that the cursor belongs to the search of p and returns the position to seek to.
+func (cur cursor) searchSeekKey(p *SearchParams) (*repository.SeekKey, error) {
+	if cur.Direction != cursorNext {
+		return nil, fmt.Errorf("%w: cursor is for the %s page", ErrInvalidCursor, cur.Direction)
+	}
+	if cur.Sort != sortSearch || cur.Filters != filtersDigest(p.searchFilters()) {
+		return nil, fmt.Errorf("%w: cursor is for another listing", ErrInvalidCursor)
+	}
+	id, err := ksuid.Parse(cur.ID)
+	if err != nil || cur.Rank == nil {
+		return nil, fmt.Errorf("%w: malformed cursor", ErrInvalidCursor)
+	}
+	return &repository.SeekKey{Rank: *cur.Rank, ID: id}, nil
+}
+
+// newSearchCursor returns the cursor of the last user of a page of the search of p.
+func newSearchCursor(u *repository.User, p *SearchParams) cursor {
+	return cursor{
+		Rank:      &u.Rank,
+		ID:        u.ID.String(),
+		Sort:      sortSearch,
+		Direction: cursorNext,
+		Filters:   filtersDigest(p.searchFilters()),
+	}
+}
+
+// filtersDigest returns a short digest of the filters of a listing, which
+// tells whether a cursor was made for the same filters.
+func filtersDigest(f *ListFilters) string {
+	b, _ := json.Marshal(f) //nolint:errchkjson //the filters always marshal
+	sum := sha256.Sum256(b)
+	return base64.RawURLEncoding.EncodeToString(sum[:cursorDigestSize])
+}
+
+// deref returns the value p points to, or the zero value if p is nil.
+func deref[T any](p *T) T {
+	var zero T
+	if p == nil {
+		return zero
+	}
+	return *p
+}
@@ -1,6 +1,71 @@
 package service
 
-import "errors"
+import (
+	"errors"
+	"fmt"
+)
 
 // ErrRandomUserAPI is an error when fetching random users from the RandomUserAPI.
 var ErrRandomUserAPI = errors.New("error fetching random users from the RandomUserAPI")
+
+// ErrRandomUserTimeout is an error when the RandomUserAPI did not answer in time.
+var ErrRandomUserTimeout = fmt.Errorf("%w: timeout", ErrRandomUserAPI)
+
+// ErrRandomUserRateLimited is an error when the RandomUserAPI rejected the requests with 429 Too Many Requests.
+var ErrRandomUserRateLimited = fmt.Errorf("%w: rate limited", ErrRandomUserAPI)
+
+// ErrRandomUserBadPayload is an error when the RandomUserAPI response cannot be decoded.
+var ErrRandomUserBadPayload = fmt.Errorf("%w: bad payload", ErrRandomUserAPI)
+
+// ErrRandomUserCircuitOpen is an error when the RandomUserAPI is not called after repeated failures.
+var ErrRandomUserCircuitOpen = fmt.Errorf("%w: circuit open", ErrRandomUserAPI)
+
+// ErrUserNotFound is an error when the requested user does not exist.
+var ErrUserNotFound = errors.New("user not found")
+
+// ErrUserNotDeleted is an error when a user that is not soft deleted is restored.
+var ErrUserNotDeleted = errors.New("user is not deleted")
+
+// ErrJobNotFound is an error when the requested populate job does not exist.
+var ErrJobNotFound = errors.New("job not found")
+
+// ErrShuttingDown is an error when a populate job is requested while the service is stopping.
+var ErrShuttingDown = errors.New("service is shutting down")
+
+// ErrInvalidPopulateOptions is an error when a populate job is requested with invalid options.
+var ErrInvalidPopulateOptions = errors.New("invalid populate options")
+
+// ErrIdempotencyKeyMismatch is an error when an idempotency key is reused for a different request.
+var ErrIdempotencyKeyMismatch = errors.New("idempotency key was used for a different request")
+
+// ErrIdempotencyKeyInProgress is an error when the request of an idempotency key is still running.
+var ErrIdempotencyKeyInProgress = errors.New("request with the same idempotency key is in progress")
+
+// ErrInvalidCursor is an error when a pagination cursor is malformed, tampered with or used for another listing.
+var ErrInvalidCursor = errors.New("invalid cursor")
+
+// ErrInvalidSort is an error when a user listing is sorted by an unknown or repeated field.
+var ErrInvalidSort = errors.New("invalid sort")
+
+// ErrInvalidFields is an error when a user listing is restricted to an unknown or repeated field.
+var ErrInvalidFields = errors.New("invalid fields")
+
+// ErrPreconditionFailed is an error when a user changed since the version a request was made for.
+var ErrPreconditionFailed = errors.New("precondition failed")
+
+// FieldError is an error on a field or a parameter of a request, so that the
+// API can tell which one is wrong. It wraps the error of its kind, e.g.
+// ErrInvalidSort.
+type FieldError struct {
+	Err    error
+	Field  string
+	Reason string
+}
+
+func (e *FieldError) Error() string {
+	return fmt.Sprintf("%v: %s", e.Err, e.Reason)
+}
+
+func (e *FieldError) Unwrap() error {
+	return e.Err
+}
@@ -0,0 +1,83 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"wonderful/internal/entities"
+	"wonderful/internal/repository"
+	"wonderful/internal/store"
+)
+
+// idempotencyKeyTTL is how long a key and its response are kept. Afterwards
+// the key can be used again for any request.
+const idempotencyKeyTTL = 24 * time.Hour
+
+// idempotencyService is an implementation of the IdempotencyService interface.
+type idempotencyService struct {
+	repo repository.IdempotencyRepository
+}
+
+// NewIdempotencyService creates a new IdempotencyService.
+func NewIdempotencyService(s store.Store) *idempotencyService {
+	return &idempotencyService{
+		repo: s.Idempotency(),
+	}
+}
+
+// Begin claims the key for the request with the given fingerprint. It returns
+// nil if the request must run, or the response recorded for the key if it
+// already ran. Reusing the key for another request or while its request is
+// still running are errors.
+func (s *idempotencyService) Begin(ctx context.Context, key, fingerprint string) (*entities.RecordedResponse, error) {
+	claimed, err := s.repo.ClaimKey(ctx, key, fingerprint, time.Now().UTC().Add(-idempotencyKeyTTL))
+	if err != nil {
+		return nil, fmt.Errorf("service failed to claim idempotency key: %w", err)
+	}
+	if claimed {
+		return nil, nil //nolint:nilnil //no recorded response, the request must run
+	}
+	k, err := s.repo.GetKey(ctx, key)
+	if err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			// the request that held the key was abandoned in the meantime.
+			return nil, fmt.Errorf("service failed to get idempotency key: %w", ErrIdempotencyKeyInProgress)
+		}
+		return nil, fmt.Errorf("service failed to get idempotency key: %w", err)
+	}
+	if k.Fingerprint != fingerprint {
+		return nil, fmt.Errorf("service failed to replay idempotency key: %w", ErrIdempotencyKeyMismatch)
+	}
+	if k.CompletedAt == nil {
+		return nil, fmt.Errorf("service failed to replay idempotency key: %w", ErrIdempotencyKeyInProgress)
+	}
+	return &entities.RecordedResponse{
+		StatusCode: k.StatusCode,
+		Header:     k.Header,
+		Body:       k.Body,
+	}, nil
+}
+
+// Complete records the response of the request of the key.
+func (s *idempotencyService) Complete(ctx context.Context, key string, resp entities.RecordedResponse) error {
+	err := s.repo.CompleteKey(ctx, repository.IdempotencyKey{
+		Key:        key,
+		StatusCode: resp.StatusCode,
+		Header:     resp.Header,
+		Body:       resp.Body,
+	})
+	if err != nil {
+		return fmt.Errorf("service failed to complete idempotency key: %w", err)
+	}
+	return nil
+}
+
+// Abandon releases the key without recording a response, so that the request can be retried.
+func (s *idempotencyService) Abandon(ctx context.Context, key string) error {
+	if err := s.repo.DeleteKey(ctx, key); err != nil {
+		return fmt.Errorf("service failed to abandon idempotency key: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,121 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/mail"
+	"time"
+	"unicode/utf8"
+
+	"wonderful/internal/entities"
+)
+
+const (
+	// importChunkSize is the number of valid users created at once by an import.
+	importChunkSize = 1000
+	// importMaxErrors is the number of line errors an import report keeps.
+	importMaxErrors = 1000
+)
+
+// ImportRecord is a struct that holds a user read from a line of an import,
+// or the error of the line when it could not be read.
+type ImportRecord struct {
+	Line int
+	User entities.User
+	Err  *entities.ImportError
+}
+
+// ImportUsers creates the users read from next, which returns io.EOF after
+// the last one. The invalid lines are reported instead of failing the import,
+// the valid users are created in chunks of importChunkSize, each one in its
+// own transaction, so that the chunks created before an error are kept. As
+// with populate, the users with a known external ID are updated.
+func (s *userService) ImportUsers(ctx context.Context, next func() (*ImportRecord, error)) (*entities.ImportReport, error) {
+	report := &entities.ImportReport{}
+	reject := func(e *entities.ImportError) {
+		report.Failed++
+		if len(report.Errors) < importMaxErrors {
+			report.Errors = append(report.Errors, *e)
+		}
+	}
+	chunk := make([]entities.User, 0, importChunkSize)
+	flush := func() error {
+		if len(chunk) == 0 {
+			return nil
+		}
+		res, err := createUsers(ctx, s.store, chunk)
+		if err != nil {
+			return err
+		}
+		report.Inserted += res.Inserted
+		report.Updated += res.Updated
+		report.Skipped += res.Skipped
+		chunk = chunk[:0]
+		return nil
+	}
+	for {
+		rec, err := next()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return report, fmt.Errorf("service failed to import users: %w", err)
+		}
+		if rec.Err == nil {
+			rec.Err = validateImportedUser(rec.Line, &rec.User)
+		}
+		if rec.Err != nil {
+			reject(rec.Err)
+			continue
+		}
+		if rec.User.Registration.IsZero() {
+			rec.User.Registration = time.Now().UTC()
+		}
+		if rec.User.Picture == nil {
+			rec.User.Picture = map[string]string{}
+		}
+		chunk = append(chunk, rec.User)
+		if len(chunk) == importChunkSize {
+			if err := flush(); err != nil {
+				return report, fmt.Errorf("service failed to import users: %w", err)
+			}
+		}
+	}
+	if err := flush(); err != nil {
+		return report, fmt.Errorf("service failed to import users: %w", err)
+	}
+	return report, nil
+}
+
+// validateImportedUser checks the fields of an imported user against the
+// constraints of the database, so that every user of a chunk can be stored.
+func validateImportedUser(line int, u *entities.User) *entities.ImportError {
+	invalid := func(field, reason string) *entities.ImportError {
+		return &entities.ImportError{Line: line, Field: field, Reason: reason}
+	}
+	for _, f := range []struct {
+		name     string
+		value    string
+		required bool
+		max      int
+	}{
+		{"external_id", u.ExternalID, false, 64},
+		{"name", u.Name, true, 255},
+		{"email", u.Email, true, 255},
+		{"phone.main", u.Phone, false, 31},
+		{"phone.cell", u.Cell, false, 31},
+	} {
+		if f.required && f.value == "" {
+			return invalid(f.name, "is required")
+		}
+		if utf8.RuneCountInString(f.value) > f.max {
+			return invalid(f.name, fmt.Sprintf("is longer than %d characters", f.max))
+		}
+	}
+	if addr, err := mail.ParseAddress(u.Email); err != nil || addr.Address != u.Email {
+		return invalid("email", "is not a valid email address")
+	}
+	return nil
+}
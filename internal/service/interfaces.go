@@ -2,13 +2,35 @@ package service
 
 import (
 	"context"
+	"time"
 
 	"wonderful/internal/entities"
-	"wonderful/internal/repository"
 )
 
 // UserService is a domain service for users.
 type UserService interface {
-	ListUsers(ctx context.Context, p repository.Params) ([]entities.User, error)
-	Create(ctx context.Context) error
+	ListUsers(ctx context.Context, p ListParams) (*entities.UserPage, error)
+	SearchUsers(ctx context.Context, p SearchParams) (*entities.SearchPage, error)
+	ExportUsers(ctx context.Context, f ListFilters, fn func(*entities.User) error) error
+	ImportUsers(ctx context.Context, next func() (*ImportRecord, error)) (*entities.ImportReport, error)
+	GetUser(ctx context.Context, id string) (*entities.User, error)
+	CreateUser(ctx context.Context, u entities.User) (*entities.User, error)
+	ReplaceUser(ctx context.Context, id string, u entities.User, ifMatch Precondition) (*entities.User, error)
+	PatchUser(ctx context.Context, id string, patch entities.UserPatch, ifMatch Precondition) (*entities.User, error)
+	DeleteUser(ctx context.Context, id string, ifMatch Precondition) error
+	RestoreUser(ctx context.Context, id string) (*entities.User, error)
+	PurgeUsers(ctx context.Context, deletedBefore time.Time) (int64, error)
+}
+
+// PopulateService runs the populate use case as background jobs.
+type PopulateService interface {
+	Enqueue(ctx context.Context, opts entities.PopulateOptions) (*entities.Job, error)
+	GetJob(ctx context.Context, id string) (*entities.Job, error)
+}
+
+// IdempotencyService records the responses of requests by idempotency key.
+type IdempotencyService interface {
+	Begin(ctx context.Context, key, fingerprint string) (*entities.RecordedResponse, error)
+	Complete(ctx context.Context, key string, resp entities.RecordedResponse) error
+	Abandon(ctx context.Context, key string) error
 }
@@ -0,0 +1,100 @@
+package service
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"wonderful/internal/entities"
+)
+
+// lruCache is an in-process PageCache holding up to size pages. The least
+// recently used page is evicted to make room for a new one, and the pages
+// expire after their TTL.
+type lruCache struct {
+	size int
+	now  func() time.Time
+
+	mu      sync.Mutex
+	entries map[string]*lruEntry
+	// head is the sentinel of the circular list of the entries, from the most
+	// recently used one after it to the least recently used one before it.
+	head lruEntry
+}
+
+type lruEntry struct {
+	key        string
+	page       *entities.UserPage
+	expires    time.Time
+	prev, next *lruEntry
+}
+
+// NewLRUCache creates an in-process PageCache holding up to size pages.
+func NewLRUCache(size int) *lruCache {
+	c := &lruCache{
+		size:    max(size, 1),
+		now:     time.Now,
+		entries: make(map[string]*lruEntry, size),
+	}
+	c.head.prev, c.head.next = &c.head, &c.head
+	return c
+}
+
+func (c *lruCache) Get(_ context.Context, key string) (*entities.UserPage, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	e, ok := c.entries[key]
+	if !ok {
+		return nil, false
+	}
+	if !c.now().Before(e.expires) {
+		c.remove(e)
+		return nil, false
+	}
+	c.unlink(e)
+	c.pushFront(e)
+	return e.page, true
+}
+
+func (c *lruCache) Set(_ context.Context, key string, page *entities.UserPage, ttl time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if e, ok := c.entries[key]; ok {
+		c.remove(e)
+	}
+	if len(c.entries) >= c.size {
+		c.remove(c.head.prev)
+	}
+	e := &lruEntry{key: key, page: page, expires: c.now().Add(ttl)}
+	c.entries[key] = e
+	c.pushFront(e)
+}
+
+func (c *lruCache) Purge(_ context.Context) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	clear(c.entries)
+	c.head.prev, c.head.next = &c.head, &c.head
+}
+
+// Len returns the number of pages held, including the expired ones not evicted yet.
+func (c *lruCache) Len() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return len(c.entries)
+}
+
+func (c *lruCache) pushFront(e *lruEntry) {
+	e.prev, e.next = &c.head, c.head.next
+	e.next.prev = e
+	c.head.next = e
+}
+
+func (c *lruCache) unlink(e *lruEntry) {
+	e.prev.next, e.next.prev = e.next, e.prev
+}
+
+func (c *lruCache) remove(e *lruEntry) {
+	c.unlink(e)
+	delete(c.entries, e.key)
+}
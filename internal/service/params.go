@@ -3,46 +3,263 @@ package service
 import (
 	"fmt"
 	"strings"
+	"time"
 
 	"wonderful/internal/repository"
-
-	"github.com/segmentio/ksuid"
 )
 
-// sanitize email substring to prevent SQL injection.
-func sanitizeEmail(emailSub string) string {
-	invalidChars := []string{"'", "\"", ";", " ", "*", "#"}
-	for _, c := range invalidChars {
-		emailSub = strings.ReplaceAll(emailSub, c, "")
+// defaultListLimit is the number of users listed when no limit is given.
+const defaultListLimit = 10
+
+// sortFields are the fields the user listings can be sorted by.
+var sortFields = map[string]repository.SortField{
+	"name":         repository.SortName,
+	"email":        repository.SortEmail,
+	"registration": repository.SortRegistration,
+	"created_at":   repository.SortCreatedAt,
+	"relevance":    repository.SortRelevance,
+}
+
+// defaultSort is the order of the user listings when none is given.
+var defaultSort = []repository.SortKey{{Field: repository.SortRegistration, Desc: true}}
+
+// defaultSearchSort is the order of the user searches when none is given: the most relevant users first.
+var defaultSearchSort = []repository.SortKey{{Field: repository.SortRelevance, Desc: true}, {Field: repository.SortRegistration, Desc: true}}
+
+// parseSort parses a sort parameter: a comma separated list of fields, each
+// one prefixed with "-" to sort it in descending order, e.g. "-registration,name".
+func parseSort(sort string) ([]repository.SortKey, error) {
+	fields := strings.Split(sort, ",")
+	keys := make([]repository.SortKey, 0, len(fields))
+	seen := make(map[repository.SortField]bool, len(fields))
+	for _, f := range fields {
+		name, desc := strings.CutPrefix(f, "-")
+		field, ok := sortFields[name]
+		if !ok {
+			return nil, &FieldError{Err: ErrInvalidSort, Field: "sort", Reason: fmt.Sprintf("unknown field %q", f)}
+		}
+		if seen[field] {
+			return nil, &FieldError{Err: ErrInvalidSort, Field: "sort", Reason: fmt.Sprintf("repeated field %q", name)}
+		}
+		seen[field] = true
+		keys = append(keys, repository.SortKey{Field: field, Desc: desc})
+	}
+	return keys, nil
+}
+
+// checkSortFilters checks that the sort order can be used with the filters:
+// the relevance is only known for a search query.
+func checkSortFilters(keys []repository.SortKey, f *ListFilters) error {
+	for _, k := range keys {
+		if k.Field == repository.SortRelevance && f.Query == nil {
+			return &FieldError{Err: ErrInvalidSort, Field: "sort", Reason: "relevance can only be sorted by with q"}
+		}
+	}
+	return nil
+}
+
+// CheckSortFilters parses a sort parameter and checks that it can be used with the filters.
+func CheckSortFilters(sort string, f *ListFilters) error {
+	keys, err := parseSort(sort)
+	if err != nil {
+		return err
 	}
-	return emailSub
+	return checkSortFilters(keys, f)
+}
+
+// userFields maps the fields of the users in the API to the repository fields they are read from.
+var userFields = map[string][]repository.UserField{
+	"id":                nil, // always listed
+	"name":              {repository.FieldName},
+	"email":             {repository.FieldEmail},
+	"phone":             {repository.FieldPhone, repository.FieldCell},
+	"phone.main":        {repository.FieldPhone},
+	"phone.cell":        {repository.FieldCell},
+	"picture":           {repository.FieldPicture},
+	"picture.large":     {repository.FieldPictureLarge},
+	"picture.medium":    {repository.FieldPictureMedium},
+	"picture.thumbnail": {repository.FieldPictureThumbnail},
+	"registration_date": {repository.FieldRegistration},
+	"created_at":        {repository.FieldCreatedAt},
+	"updated_at":        {repository.FieldUpdatedAt},
+	"deleted_at":        {repository.FieldDeletedAt},
+	"gender":            {repository.FieldGender},
+	"title":             {repository.FieldTitle},
+	"first_name":        {repository.FieldFirstName},
+	"last_name":         {repository.FieldLastName},
+	"date_of_birth":     {repository.FieldDateOfBirth},
+	"nat":               {repository.FieldNat},
+	"national_id":       {repository.FieldNationalID},
+	"location":          {repository.FieldLocation},
+}
+
+// ParseFields parses a fields parameter: a comma separated list of the fields
+// of the users to return, e.g. "id,name,picture.thumbnail". A field of the
+// phone or the picture followed by "." and the name of one of its parts only
+// returns that part.
+func ParseFields(fields string) ([]string, error) {
+	names := strings.Split(fields, ",")
+	seen := make(map[string]bool, len(names))
+	for _, name := range names {
+		if _, ok := userFields[name]; !ok {
+			return nil, &FieldError{Err: ErrInvalidFields, Field: "fields", Reason: fmt.Sprintf("unknown field %q", name)}
+		}
+		if seen[name] {
+			return nil, &FieldError{Err: ErrInvalidFields, Field: "fields", Reason: fmt.Sprintf("repeated field %q", name)}
+		}
+		seen[name] = true
+	}
+	return names, nil
+}
+
+// formatSort returns the sort parameter of the keys.
+func formatSort(keys []repository.SortKey) string {
+	fields := make([]string, 0, len(keys))
+	for _, k := range keys {
+		if k.Desc {
+			fields = append(fields, "-"+string(k.Field))
+		} else {
+			fields = append(fields, string(k.Field))
+		}
+	}
+	return strings.Join(fields, ",")
+}
+
+// ListFilters is a struct that holds the filters of a user listing. Nil filters are not applied.
+type ListFilters struct {
+	// Email and Name match the users containing them, ignoring the case.
+	Email *string
+	Name  *string
+	// Query matches the users whose name or email contains it or is similar to it.
+	Query *string
+	// Phone, Cell, Nat and Gender match the users equal to them.
+	Phone  *string
+	Cell   *string
+	Nat    *string
+	Gender *string
+	// RegisteredAfter and RegisteredBefore match the users registered strictly after and before them.
+	RegisteredAfter  *time.Time
+	RegisteredBefore *time.Time
+	IncludeDeleted   bool
+}
+
+// ListParams is a struct that holds the parameters of a user listing as the
+// API receives them. The cursors are the opaque tokens of a previous page.
+type ListParams struct {
+	ListFilters
+	StartingAfter string
+	EndingBefore  string
+	Limit         int
+	IncludeTotal  bool
+	// Sort is the listing order, by registration descending when empty.
+	Sort []repository.SortKey
+	// Fields are the fields of the users to return, as named by the API, all
+	// of them when empty. See ParseFields.
+	Fields []string
 }
 
-// ConvertParams converts the API input parameters to the repository.Params type.
-func ConvertParams(limit *int, startingAfter, endingBefore, email *string) (*repository.Params, error) {
-	params := new(repository.Params)
+// ConvertParams converts the API input parameters to the ListParams type.
+func ConvertParams(limit *int, startingAfter, endingBefore, sort, fields *string, includeTotal *bool, filters ListFilters) (*ListParams, error) {
+	params := &ListParams{ListFilters: filters}
 
 	// Open API always validates the input, so we can safely assume that the input is valid.
 	if limit != nil {
 		params.Limit = *limit
 	}
 	if startingAfter != nil {
-		id, err := ksuid.Parse(*startingAfter)
+		params.StartingAfter = *startingAfter
+	}
+	if endingBefore != nil {
+		params.EndingBefore = *endingBefore
+	}
+	if includeTotal != nil {
+		params.IncludeTotal = *includeTotal
+	}
+	if sort != nil {
+		keys, err := parseSort(*sort)
 		if err != nil {
-			return nil, fmt.Errorf("invalid startingAfter: %w", err)
+			return nil, err
+		}
+		if err := checkSortFilters(keys, &filters); err != nil {
+			return nil, err
 		}
-		params.StartingAfter = &id
+		params.Sort = keys
 	}
-	if endingBefore != nil {
-		id, err := ksuid.Parse(*endingBefore)
+	if fields != nil {
+		names, err := ParseFields(*fields)
 		if err != nil {
-			return nil, fmt.Errorf("invalid endingBefore: %w", err)
+			return nil, err
 		}
-		params.EndingBefore = &id
+		params.Fields = names
 	}
-	if email != nil {
-		sanitizedEmail := sanitizeEmail(*email)
-		params.Email = &sanitizedEmail
+	return params, nil
+}
+
+// repositoryParams verifies the cursors of p and converts it to the repository.Params type.
+func (c cursorCodec) repositoryParams(p *ListParams) (repository.Params, error) {
+	params := repository.Params{
+		Filters: repository.Filters(p.ListFilters),
+		Limit:   p.Limit,
+		Sort:    p.sort(),
+		Fields:  p.repositoryFields(),
+	}
+	if p.StartingAfter != "" {
+		cur, err := c.decode(p.StartingAfter)
+		if err != nil {
+			return params, fmt.Errorf("invalid startingAfter: %w", err)
+		}
+		if params.StartingAfter, err = cur.seekKey(cursorNext, p); err != nil {
+			return params, fmt.Errorf("invalid startingAfter: %w", err)
+		}
+	}
+	if p.EndingBefore != "" {
+		cur, err := c.decode(p.EndingBefore)
+		if err != nil {
+			return params, fmt.Errorf("invalid endingBefore: %w", err)
+		}
+		if params.EndingBefore, err = cur.seekKey(cursorPrev, p); err != nil {
+			return params, fmt.Errorf("invalid endingBefore: %w", err)
+		}
 	}
 	return params, nil
 }
+
+// sort returns the listing order of p.
+func (p *ListParams) sort() []repository.SortKey {
+	switch {
+	case len(p.Sort) > 0:
+		return p.Sort
+	case p.Query != nil:
+		return defaultSearchSort
+	default:
+		return defaultSort
+	}
+}
+
+// repositoryFields returns the repository fields the fields of p are read from.
+func (p *ListParams) repositoryFields() []repository.UserField {
+	if len(p.Fields) == 0 {
+		return nil
+	}
+	fields := make([]repository.UserField, 0, len(p.Fields))
+	for _, name := range p.Fields {
+		fields = append(fields, userFields[name]...)
+	}
+	return fields
+}
+
+// SearchParams is a struct that holds the parameters of a user search as the
+// API receives them. The cursor is the opaque token of a previous page.
+type SearchParams struct {
+	// Query is a list of terms, all of which must match. A term ending with
+	// "*" matches as a prefix and the terms between double quotes as a phrase.
+	Query          string
+	StartingAfter  string
+	Limit          int
+	IncludeDeleted bool
+}
+
+// searchFilters returns the filters of a search, which its cursors are made for.
+func (p *SearchParams) searchFilters() *ListFilters {
+	return &ListFilters{Query: &p.Query, IncludeDeleted: p.IncludeDeleted}
+}
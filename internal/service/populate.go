@@ -0,0 +1,298 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"sync"
+	"time"
+
+	"wonderful/internal/entities"
+	"wonderful/internal/repository"
+	"wonderful/internal/store"
+
+	"github.com/segmentio/ksuid"
+)
+
+// interruptedReason is stored as the error of jobs that were running when the
+// server stopped without checkpointing them, e.g. after a crash.
+const interruptedReason = "interrupted: the server stopped while the job was running"
+
+// populateService is an implementation of the PopulateService interface.
+// Jobs are persisted and run one at a time by a single worker, since the
+// RandomUser API does not like concurrent requests.
+type populateService struct {
+	store         store.Store
+	sources       map[string]UserSource
+	defaultSource string
+	// onUsersChanged is called after a job created users, see OnUsersChanged.
+	onUsersChanged func(context.Context)
+
+	mu      sync.Mutex
+	pending []ksuid.KSUID
+	closed  bool
+	wake    chan struct{}
+	stop    chan struct{}
+	done    chan struct{}
+	cancel  context.CancelFunc
+}
+
+// NewPopulateService creates a new PopulateService that adds the users of the
+// given sources, by name. Jobs that do not select a source use defaultSource.
+// Start must be called before jobs are run.
+func NewPopulateService(s store.Store, sources map[string]UserSource, defaultSource string) (*populateService, error) {
+	if _, ok := sources[defaultSource]; !ok {
+		return nil, fmt.Errorf("service failed to create populate service: unknown default source %q", defaultSource)
+	}
+	return &populateService{
+		store:         s,
+		sources:       sources,
+		defaultSource: defaultSource,
+		wake:          make(chan struct{}, 1),
+		stop:          make(chan struct{}),
+		done:          make(chan struct{}),
+	}, nil
+}
+
+// OnUsersChanged sets a function called after the users of a job are created,
+// e.g. to invalidate a cache of the users. It must be called before Start.
+func (s *populateService) OnUsersChanged(fn func(context.Context)) {
+	s.onUsersChanged = fn
+}
+
+// Start resumes the jobs left queued by a previous run and starts the worker.
+// Jobs left fetching or inserting were not checkpointed and are marked as failed.
+func (s *populateService) Start(ctx context.Context) error {
+	jobs := s.store.Jobs()
+	n, err := jobs.FailInterruptedJobs(ctx, interruptedReason)
+	if err != nil {
+		return fmt.Errorf("service failed to start populate jobs: %w", err)
+	}
+	if n > 0 {
+		slog.Warn("marked interrupted populate jobs as failed", "count", n)
+	}
+	queued, err := jobs.ListJobsByState(ctx, string(entities.JobStateQueued))
+	if err != nil {
+		return fmt.Errorf("service failed to start populate jobs: %w", err)
+	}
+	for i := range queued {
+		s.push(queued[i].ID)
+	}
+
+	runCtx, cancel := context.WithCancel(context.WithoutCancel(ctx))
+	s.cancel = cancel
+	go s.work(runCtx)
+	return nil
+}
+
+// Shutdown stops accepting jobs and waits for the running job to finish.
+// If ctx expires first, the running job is cancelled and checkpointed back
+// to queued so that it is resumed by the next Start. Jobs still waiting in
+// the queue are already persisted as queued.
+func (s *populateService) Shutdown(ctx context.Context) error {
+	s.mu.Lock()
+	if s.closed {
+		s.mu.Unlock()
+		return nil
+	}
+	s.closed = true
+	s.mu.Unlock()
+	close(s.stop)
+
+	select {
+	case <-s.done:
+		return nil
+	case <-ctx.Done():
+		s.cancel()
+		<-s.done
+		return fmt.Errorf("service stopped populate job before it finished: %w", ctx.Err())
+	}
+}
+
+// Enqueue validates the options, persists a new queued populate job and schedules it.
+func (s *populateService) Enqueue(ctx context.Context, opts entities.PopulateOptions) (*entities.Job, error) {
+	opts, err := NormalizePopulateOptions(opts)
+	if err != nil {
+		return nil, fmt.Errorf("service failed to enqueue populate job: %w", err)
+	}
+	if opts.Source == "" {
+		opts.Source = s.defaultSource
+	}
+	source, ok := s.sources[opts.Source]
+	if !ok {
+		return nil, fmt.Errorf("service failed to enqueue populate job: %w",
+			&FieldError{Err: ErrInvalidPopulateOptions, Field: "source", Reason: fmt.Sprintf("source %q is not available", opts.Source)})
+	}
+	// do not queue jobs that would fail right away
+	if c, ok := source.(availabilityChecker); ok {
+		if err := c.Available(); err != nil {
+			return nil, fmt.Errorf("service failed to enqueue populate job: %w", err)
+		}
+	}
+	s.mu.Lock()
+	closed := s.closed
+	s.mu.Unlock()
+	if closed {
+		return nil, fmt.Errorf("service failed to enqueue populate job: %w", ErrShuttingDown)
+	}
+	job, err := s.store.Jobs().CreateJob(ctx, ksuid.New(), string(entities.JobStateQueued), repository.PopulateOptions(opts))
+	if err != nil {
+		return nil, fmt.Errorf("service failed to enqueue populate job: %w", err)
+	}
+	s.push(job.ID)
+	j := toJobEntity(job)
+	return &j, nil
+}
+
+// GetJob returns the populate job with the given ID.
+func (s *populateService) GetJob(ctx context.Context, id string) (*entities.Job, error) {
+	jobID, err := ksuid.Parse(id)
+	if err != nil {
+		return nil, fmt.Errorf("service failed to get job: %w", errors.Join(ErrJobNotFound, err))
+	}
+	job, err := s.store.Jobs().GetJob(ctx, jobID)
+	if err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			return nil, fmt.Errorf("service failed to get job: %w", errors.Join(ErrJobNotFound, err))
+		}
+		return nil, fmt.Errorf("service failed to get job: %w", err)
+	}
+	j := toJobEntity(job)
+	return &j, nil
+}
+
+func (s *populateService) push(id ksuid.KSUID) {
+	s.mu.Lock()
+	s.pending = append(s.pending, id)
+	s.mu.Unlock()
+	select {
+	case s.wake <- struct{}{}:
+	default:
+	}
+}
+
+func (s *populateService) pop() (ksuid.KSUID, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if len(s.pending) == 0 {
+		return ksuid.Nil, false
+	}
+	id := s.pending[0]
+	s.pending = s.pending[1:]
+	return id, true
+}
+
+// work runs the queued jobs until Shutdown is called.
+func (s *populateService) work(ctx context.Context) {
+	defer close(s.done)
+	for {
+		select {
+		case <-s.stop:
+			return
+		default:
+		}
+		id, ok := s.pop()
+		if !ok {
+			select {
+			case <-s.wake:
+				continue
+			case <-s.stop:
+				return
+			}
+		}
+		if err := s.run(ctx, id); err != nil {
+			slog.Error("populate job failed", "job", id, "error", err)
+		}
+	}
+}
+
+// run executes a single job, recording every state change.
+func (s *populateService) run(ctx context.Context, id ksuid.KSUID) error {
+	// state changes are recorded even when the job itself is cancelled.
+	saveCtx := context.WithoutCancel(ctx)
+	jobs := s.store.Jobs()
+	job, err := jobs.GetJob(saveCtx, id)
+	if err != nil {
+		return err //nolint:wrapcheck //already wrapped by the repository
+	}
+	save := func(state entities.JobState) error {
+		job.State = string(state)
+		if state == entities.JobStateDone || state == entities.JobStateFailed {
+			now := time.Now().UTC()
+			job.FinishedAt = &now
+		}
+		if _, err := jobs.UpdateJob(saveCtx, *job); err != nil {
+			return err //nolint:wrapcheck //already wrapped by the repository
+		}
+		return nil
+	}
+	fail := func(err error) error {
+		if ctx.Err() != nil {
+			// checkpoint: nothing was committed, so the job can be run again.
+			slog.Info("checkpointing populate job", "job", id)
+			job.Fetched = 0
+			return errors.Join(err, save(entities.JobStateQueued))
+		}
+		job.Error = err.Error()
+		return errors.Join(err, save(entities.JobStateFailed))
+	}
+
+	if err := save(entities.JobStateFetching); err != nil {
+		return err
+	}
+	users, err := s.fetch(ctx, entities.PopulateOptions(job.Options))
+	if err != nil {
+		return fail(err)
+	}
+	job.Fetched = len(users)
+	if err := save(entities.JobStateInserting); err != nil {
+		return err
+	}
+	res, err := createUsers(ctx, s.store, users)
+	if err != nil {
+		return fail(err)
+	}
+	if s.onUsersChanged != nil {
+		s.onUsersChanged(saveCtx)
+	}
+	job.Inserted = res.Inserted
+	job.Updated = res.Updated
+	job.Skipped = res.Skipped
+	return save(entities.JobStateDone)
+}
+
+// fetch gets the users of a job from its source. The source may no longer
+// be available if the configuration changed since the job was queued.
+func (s *populateService) fetch(ctx context.Context, opts entities.PopulateOptions) ([]entities.User, error) {
+	source, ok := s.sources[opts.Source]
+	if !ok {
+		return nil, fmt.Errorf("service failed to fetch users: source %q is not available", opts.Source)
+	}
+	opts, err := NormalizePopulateOptions(opts)
+	if err != nil {
+		return nil, fmt.Errorf("service failed to fetch users: %w", err)
+	}
+	users, err := source.FetchUsers(ctx, opts)
+	if err != nil {
+		return nil, fmt.Errorf("service failed to fetch users from %s: %w", opts.Source, err)
+	}
+	return users, nil
+}
+
+// toJobEntity converts a repository job to an entities job.
+func toJobEntity(j *repository.Job) entities.Job {
+	return entities.Job{
+		ID:         j.ID.String(),
+		State:      entities.JobState(j.State),
+		Fetched:    j.Fetched,
+		Inserted:   j.Inserted,
+		Updated:    j.Updated,
+		Skipped:    j.Skipped,
+		Error:      j.Error,
+		CreatedAt:  j.CreatedAt,
+		UpdatedAt:  j.UpdatedAt,
+		FinishedAt: j.FinishedAt,
+		Options:    entities.PopulateOptions(j.Options),
+	}
+}
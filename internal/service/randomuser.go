@@ -3,16 +3,32 @@ package service
 import (
 	"context"
 	"encoding/json"
-	"errors"
 	"fmt"
-	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
 	"time"
+
+	"wonderful/internal/entities"
 )
 
 const (
-	randomUserURL = "https://randomuser.me/api/?results=5000"
+	randomUserURL = "https://randomuser.me/api/"
+	// randomUserMaxResults is the maximum number of users the RandomUser API returns per request.
+	randomUserMaxResults = 5000
+	// defaultPopulateCount is the number of users fetched when no count is given.
+	defaultPopulateCount = 5000
+	// maxPopulateCount is the maximum number of users fetched by a single populate.
+	maxPopulateCount = 50000
 )
 
+// randomUserNats are the nationalities supported by the RandomUser API.
+var randomUserNats = map[string]bool{
+	"AU": true, "BR": true, "CA": true, "CH": true, "DE": true, "DK": true, "ES": true,
+	"FI": true, "FR": true, "GB": true, "IE": true, "IN": true, "IR": true, "MX": true,
+	"NL": true, "NO": true, "NZ": true, "RS": true, "TR": true, "UA": true, "US": true,
+}
+
 // RandomUser represents a random user from RandomUser API.
 type RandomUser struct {
 	Results []Results `json:"results"`
@@ -55,6 +71,54 @@ type ID struct {
 	Value string `json:"value"`
 }
 
+// Location represents the address and coordinates of a random user.
+type Location struct {
+	Street      Street      `json:"street"`
+	City        string      `json:"city"`
+	State       string      `json:"state"`
+	Country     string      `json:"country"`
+	Postcode    Postcode    `json:"postcode"`
+	Coordinates Coordinates `json:"coordinates"`
+	Timezone    Timezone    `json:"timezone"`
+}
+
+// Street represents a street address of a random user.
+type Street struct {
+	Number int    `json:"number"`
+	Name   string `json:"name"`
+}
+
+// Postcode is a postal code of a random user. The API returns it as a number
+// for some nationalities and as a string for others.
+type Postcode string
+
+// UnmarshalJSON accepts a postcode given as a string or as a number.
+func (p *Postcode) UnmarshalJSON(b []byte) error {
+	var s string
+	if err := json.Unmarshal(b, &s); err == nil {
+		*p = Postcode(s)
+		return nil
+	}
+	var n json.Number
+	if err := json.Unmarshal(b, &n); err != nil {
+		return fmt.Errorf("failed to decode postcode %s: %w", b, err)
+	}
+	*p = Postcode(n.String())
+	return nil
+}
+
+// Coordinates represents the coordinates of a random user, in decimal degrees.
+type Coordinates struct {
+	Latitude  string `json:"latitude"`
+	Longitude string `json:"longitude"`
+}
+
+// Timezone represents the timezone of a random user.
+type Timezone struct {
+	Offset      string `json:"offset"`
+	Description string `json:"description"`
+}
+
 // Picture represents a picture of a random user.
 type Picture struct {
 	Large     string `json:"large"`
@@ -75,6 +139,7 @@ type Results struct {
 	ID         ID         `json:"id"`
 	Picture    Picture    `json:"picture"`
 	Nat        string     `json:"nat"`
+	Location   Location   `json:"location"`
 }
 
 // Info represents an info of a random user.
@@ -85,30 +150,143 @@ type Info struct {
 	Version string `json:"version"`
 }
 
+// NormalizePopulateOptions validates the populate options and fills in the defaults.
+func NormalizePopulateOptions(opts entities.PopulateOptions) (entities.PopulateOptions, error) {
+	if opts.Count == 0 {
+		opts.Count = defaultPopulateCount
+	}
+	if opts.Count < 1 || opts.Count > maxPopulateCount {
+		return opts, &FieldError{Err: ErrInvalidPopulateOptions, Field: "count", Reason: fmt.Sprintf("count must be between 1 and %d", maxPopulateCount)}
+	}
+	if opts.Gender != "" && opts.Gender != "male" && opts.Gender != "female" {
+		return opts, &FieldError{Err: ErrInvalidPopulateOptions, Field: "gender", Reason: "gender must be male or female"}
+	}
+	nat := make([]string, 0, len(opts.Nat))
+	for _, n := range opts.Nat {
+		n = strings.ToUpper(n)
+		if !randomUserNats[n] {
+			return opts, &FieldError{Err: ErrInvalidPopulateOptions, Field: "nat", Reason: fmt.Sprintf("unsupported nationality %q", n)}
+		}
+		nat = append(nat, n)
+	}
+	if len(nat) > 0 {
+		opts.Nat = nat
+	} else {
+		opts.Nat = nil
+	}
+	return opts, nil
+}
+
+// randomUserQuery builds the RandomUser API URL for a page of results.
+func randomUserQuery(opts entities.PopulateOptions, seed string, results, page int) string {
+	q := url.Values{}
+	q.Set("results", strconv.Itoa(results))
+	if seed != "" {
+		q.Set("seed", seed)
+		q.Set("page", strconv.Itoa(page))
+	}
+	if len(opts.Nat) > 0 {
+		q.Set("nat", strings.Join(opts.Nat, ","))
+	}
+	if opts.Gender != "" {
+		q.Set("gender", opts.Gender)
+	}
+	return randomUserURL + "?" + q.Encode()
+}
+
 // FetchRandomUsers fetches random users from RandomUser API.
-func FetchRandomUsers(ctx context.Context, client http.Client) (*RandomUser, error) {
+// Counts above the API limit are fetched in pages of the same seed, so that
+// the same options with the same seed always return the same users.
+func FetchRandomUsers(ctx context.Context, client *RandomUserClient, opts entities.PopulateOptions) (*RandomUser, error) {
+	opts, err := NormalizePopulateOptions(opts)
+	if err != nil {
+		return nil, err
+	}
+	perPage := min(opts.Count, randomUserMaxResults)
+	seed := opts.Seed
 	var out RandomUser
-	if err := fetch(ctx, client, randomUserURL, &out); err != nil {
-		return nil, errors.Join(ErrRandomUserAPI, err)
+	for page := 1; len(out.Results) < opts.Count; page++ {
+		var r RandomUser
+		if err := client.get(ctx, randomUserQuery(opts, seed, perPage, page), &r); err != nil {
+			return nil, err
+		}
+		if len(r.Results) == 0 {
+			break
+		}
+		// without a seed the API picks one, which the next pages must reuse
+		// to not return the same users again.
+		seed = r.Info.Seed
+		out.Info = r.Info
+		out.Results = append(out.Results, r.Results...)
+	}
+	if len(out.Results) > opts.Count {
+		out.Results = out.Results[:opts.Count]
 	}
 	return &out, nil
 }
 
-func fetch(ctx context.Context, c http.Client, url string, r any) error {
-	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, http.NoBody)
-	if err != nil {
-		return fmt.Errorf("failed to GET request: %w", err)
+// randomUsersToEntities converts the RandomUser API results to entities users.
+func randomUsersToEntities(results []Results) []entities.User {
+	users := make([]entities.User, 0, len(results))
+	for i := range results {
+		u := &results[i]
+		users = append(users, entities.User{
+			ExternalID: u.Login.UUID,
+			Name:       u.Name.Title + " " + u.Name.First + " " + u.Name.Last,
+			Email:      u.Email,
+			Phone:      u.Phone,
+			Cell:       u.Cell,
+			Picture: map[string]string{
+				"large":     u.Picture.Large,
+				"medium":    u.Picture.Medium,
+				"thumbnail": u.Picture.Thumbnail,
+			},
+			Registration: u.Registered.Date,
+			Gender:       u.Gender,
+			Title:        u.Name.Title,
+			FirstName:    u.Name.First,
+			LastName:     u.Name.Last,
+			DateOfBirth:  optionalTime(u.Dob.Date),
+			Nat:          u.Nat,
+			NationalID:   toNationalID(u.ID),
+			Location:     toLocation(&u.Location),
+		})
 	}
-	resp, err := c.Do(req)
-	if err != nil {
-		return fmt.Errorf("failed to GET response: %w", err)
+	return users
+}
+
+// optionalTime returns nil for a zero time, e.g. a date missing from the response.
+func optionalTime(t time.Time) *time.Time {
+	if t.IsZero() {
+		return nil
+	}
+	return &t
+}
+
+// toNationalID converts the RandomUser ID, nil if the user has none.
+func toNationalID(id ID) *entities.NationalID {
+	if id.Name == "" && id.Value == "" {
+		return nil
 	}
-	defer resp.Body.Close()
-	if resp.StatusCode != http.StatusOK {
-		return fmt.Errorf("failed to GET HTTP status OK: %d", resp.StatusCode)
+	return &entities.NationalID{Name: id.Name, Value: id.Value}
+}
+
+// toLocation converts the RandomUser location, nil if the user has none.
+func toLocation(l *Location) *entities.Location {
+	if *l == (Location{}) {
+		return nil
 	}
-	if err := json.NewDecoder(resp.Body).Decode(r); err != nil {
-		return fmt.Errorf("failed to decode response: %w", err)
+	// coordinates that do not parse are left as zero.
+	lat, _ := strconv.ParseFloat(l.Coordinates.Latitude, 64)
+	lng, _ := strconv.ParseFloat(l.Coordinates.Longitude, 64)
+	return &entities.Location{
+		StreetNumber: l.Street.Number,
+		StreetName:   l.Street.Name,
+		City:         l.City,
+		State:        l.State,
+		Country:      l.Country,
+		Postcode:     string(l.Postcode),
+		Latitude:     lat,
+		Longitude:    lng,
 	}
-	return nil
 }
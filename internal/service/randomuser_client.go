@@ -0,0 +1,268 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/rand/v2"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// RandomUserClientConfig tunes the retries and the circuit breaker of a RandomUserClient.
+// Zero fields take the default values.
+type RandomUserClientConfig struct {
+	// MaxAttempts is the number of attempts per request, including the first one. Defaults to 4.
+	MaxAttempts int
+	// AttemptTimeout bounds every attempt. Defaults to 10s.
+	AttemptTimeout time.Duration
+	// BaseBackoff is the backoff before the first retry, doubled on every retry. Defaults to 500ms.
+	BaseBackoff time.Duration
+	// MaxBackoff caps the backoff between attempts, also when given by Retry-After. Defaults to 30s.
+	MaxBackoff time.Duration
+	// FailureThreshold is the number of consecutive failed attempts that opens the circuit. Defaults to 5.
+	FailureThreshold int
+	// OpenTimeout is how long the circuit stays open before a request is let through. Defaults to 30s.
+	OpenTimeout time.Duration
+}
+
+func (cfg RandomUserClientConfig) withDefaults() RandomUserClientConfig {
+	if cfg.MaxAttempts <= 0 {
+		cfg.MaxAttempts = 4
+	}
+	if cfg.AttemptTimeout <= 0 {
+		cfg.AttemptTimeout = 10 * time.Second
+	}
+	if cfg.BaseBackoff <= 0 {
+		cfg.BaseBackoff = 500 * time.Millisecond
+	}
+	if cfg.MaxBackoff <= 0 {
+		cfg.MaxBackoff = 30 * time.Second
+	}
+	if cfg.FailureThreshold <= 0 {
+		cfg.FailureThreshold = 5
+	}
+	if cfg.OpenTimeout <= 0 {
+		cfg.OpenTimeout = 30 * time.Second
+	}
+	return cfg
+}
+
+// RandomUserClient calls the RandomUser API. Failed attempts (network errors,
+// timeouts, 429 and 5xx responses) are retried with a jittered exponential
+// backoff that honours Retry-After. After repeated failures a circuit breaker
+// fails the requests fast until the API had some time to recover.
+type RandomUserClient struct {
+	client  http.Client
+	cfg     RandomUserClientConfig
+	breaker *circuitBreaker
+}
+
+// NewRandomUserClient creates a RandomUserClient sending the requests with c.
+// The timeout of c should be unset, every attempt is bounded by the AttemptTimeout.
+func NewRandomUserClient(c http.Client, cfg RandomUserClientConfig) *RandomUserClient {
+	cfg = cfg.withDefaults()
+	return &RandomUserClient{
+		client:  c,
+		cfg:     cfg,
+		breaker: &circuitBreaker{threshold: cfg.FailureThreshold, openTimeout: cfg.OpenTimeout},
+	}
+}
+
+// Available returns ErrRandomUserCircuitOpen while the circuit is open.
+func (c *RandomUserClient) Available() error {
+	return c.breaker.check(time.Now())
+}
+
+// get fetches rawURL and decodes its JSON response into r.
+func (c *RandomUserClient) get(ctx context.Context, rawURL string, r any) error {
+	var lastErr error
+	for attempt := 1; ; attempt++ {
+		if err := c.breaker.allow(time.Now()); err != nil {
+			return errors.Join(err, lastErr)
+		}
+		retryAfter, err := c.attempt(ctx, rawURL, r)
+		if err == nil {
+			c.breaker.success()
+			return nil
+		}
+		if ctx.Err() != nil {
+			// the caller gave up, this says nothing about the API.
+			c.breaker.release()
+			return fmt.Errorf("failed to GET response: %w", ctx.Err())
+		}
+		var perm *permanentError
+		if errors.As(err, &perm) && !errors.Is(err, ErrRandomUserBadPayload) {
+			// our request was rejected, the API itself is fine.
+			c.breaker.release()
+			return perm.err
+		}
+		c.breaker.failure(time.Now())
+		if perm != nil {
+			return perm.err
+		}
+		lastErr = err
+		if attempt == c.cfg.MaxAttempts {
+			return lastErr
+		}
+		if err := sleep(ctx, c.backoff(attempt, retryAfter)); err != nil {
+			return fmt.Errorf("failed to GET response: %w", errors.Join(err, lastErr))
+		}
+	}
+}
+
+// permanentError marks the failures retrying does not fix: rejected requests
+// and responses that cannot be decoded.
+type permanentError struct {
+	err error
+}
+
+func (e *permanentError) Error() string { return e.err.Error() }
+func (e *permanentError) Unwrap() error { return e.err }
+
+// attempt makes a single request. It returns the Retry-After delay of the response, if any.
+func (c *RandomUserClient) attempt(ctx context.Context, rawURL string, r any) (time.Duration, error) {
+	ctx, cancel := context.WithTimeout(ctx, c.cfg.AttemptTimeout)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, rawURL, http.NoBody)
+	if err != nil {
+		return 0, &permanentError{fmt.Errorf("failed to GET request: %w", errors.Join(ErrRandomUserAPI, err))}
+	}
+	resp, err := c.client.Do(req)
+	if err != nil {
+		if errors.Is(err, context.DeadlineExceeded) {
+			return 0, fmt.Errorf("failed to GET response: %w", errors.Join(ErrRandomUserTimeout, err))
+		}
+		return 0, fmt.Errorf("failed to GET response: %w", errors.Join(ErrRandomUserAPI, err))
+	}
+	defer resp.Body.Close()
+
+	switch {
+	case resp.StatusCode == http.StatusOK:
+	case resp.StatusCode == http.StatusTooManyRequests:
+		return parseRetryAfter(resp.Header.Get("Retry-After"), time.Now()),
+			fmt.Errorf("failed to GET HTTP status OK: %w", ErrRandomUserRateLimited)
+	case resp.StatusCode >= http.StatusInternalServerError:
+		return parseRetryAfter(resp.Header.Get("Retry-After"), time.Now()),
+			fmt.Errorf("failed to GET HTTP status OK: %w: %d", ErrRandomUserAPI, resp.StatusCode)
+	default:
+		return 0, &permanentError{fmt.Errorf("failed to GET HTTP status OK: %w: %d", ErrRandomUserAPI, resp.StatusCode)}
+	}
+	if err := json.NewDecoder(resp.Body).Decode(r); err != nil {
+		if errors.Is(err, context.DeadlineExceeded) {
+			return 0, fmt.Errorf("failed to read response: %w", errors.Join(ErrRandomUserTimeout, err))
+		}
+		return 0, &permanentError{fmt.Errorf("failed to decode response: %w", errors.Join(ErrRandomUserBadPayload, err))}
+	}
+	return 0, nil
+}
+
+// backoff returns the delay before the retry following the attempt: the
+// Retry-After delay when given, a full jitter exponential backoff otherwise.
+func (c *RandomUserClient) backoff(attempt int, retryAfter time.Duration) time.Duration {
+	if retryAfter > 0 {
+		return min(retryAfter, c.cfg.MaxBackoff)
+	}
+	ceiling := min(c.cfg.BaseBackoff<<(attempt-1), c.cfg.MaxBackoff)
+	if ceiling <= 0 {
+		// the shift overflowed
+		ceiling = c.cfg.MaxBackoff
+	}
+	return time.Duration(rand.Int64N(int64(ceiling)) + 1) //nolint:gosec //not used for security
+}
+
+// parseRetryAfter parses a Retry-After header given in seconds or as an HTTP date.
+func parseRetryAfter(v string, now time.Time) time.Duration {
+	if v == "" {
+		return 0
+	}
+	if s, err := strconv.Atoi(v); err == nil {
+		return max(time.Duration(s)*time.Second, 0)
+	}
+	if t, err := http.ParseTime(v); err == nil {
+		return max(t.Sub(now), 0)
+	}
+	return 0
+}
+
+// sleep waits for d or until ctx is done.
+func sleep(ctx context.Context, d time.Duration) error {
+	t := time.NewTimer(d)
+	defer t.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err() //nolint:wrapcheck //wrapped by the caller
+	case <-t.C:
+		return nil
+	}
+}
+
+// circuitBreaker opens after threshold consecutive failures. Once open it
+// rejects the requests for openTimeout, then lets a single probe through:
+// the circuit closes if the probe succeeds and opens again otherwise.
+type circuitBreaker struct {
+	threshold   int
+	openTimeout time.Duration
+
+	mu       sync.Mutex
+	failures int
+	openedAt time.Time
+	probing  bool
+}
+
+// check returns ErrRandomUserCircuitOpen if a request would be rejected now.
+func (b *circuitBreaker) check(now time.Time) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.checkLocked(now)
+}
+
+func (b *circuitBreaker) checkLocked(now time.Time) error {
+	if b.failures < b.threshold {
+		return nil
+	}
+	if b.probing || now.Sub(b.openedAt) < b.openTimeout {
+		return ErrRandomUserCircuitOpen
+	}
+	return nil
+}
+
+// allow reserves a request, which must be reported with success, failure or release.
+func (b *circuitBreaker) allow(now time.Time) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if err := b.checkLocked(now); err != nil {
+		return err
+	}
+	if b.failures >= b.threshold {
+		b.probing = true
+	}
+	return nil
+}
+
+func (b *circuitBreaker) success() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.failures = 0
+	b.probing = false
+}
+
+func (b *circuitBreaker) failure(now time.Time) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.failures++
+	if b.failures >= b.threshold {
+		b.openedAt = now
+	}
+	b.probing = false
+}
+
+// release ends a request that says nothing about the health of the API.
+func (b *circuitBreaker) release() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.probing = false
+}
@@ -0,0 +1,153 @@
+package service_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"wonderful/internal/entities"
+	"wonderful/internal/service"
+
+	"github.com/stretchr/testify/require"
+)
+
+// statusResponses serves the given responses in order, repeating the last one.
+func statusResponses(calls *int, responses ...func(w http.ResponseWriter)) roundTripFunc {
+	return func(_ *http.Request) *http.Response {
+		w := httptest.NewRecorder()
+		responses[min(*calls, len(responses)-1)](w)
+		*calls++
+		return w.Result()
+	}
+}
+
+func respondStatus(code int, retryAfter string) func(w http.ResponseWriter) {
+	return func(w http.ResponseWriter) {
+		if retryAfter != "" {
+			w.Header().Set("Retry-After", retryAfter)
+		}
+		w.WriteHeader(code)
+	}
+}
+
+func respondBody(body string) func(w http.ResponseWriter) {
+	return func(w http.ResponseWriter) {
+		w.Write([]byte(body)) //nolint:errcheck //ignore error
+	}
+}
+
+func TestRandomUserClientRetries(t *testing.T) {
+	ctx := context.Background()
+	opts := entities.PopulateOptions{Count: 1}
+	cfg := service.RandomUserClientConfig{MaxAttempts: 3, BaseBackoff: time.Millisecond, FailureThreshold: 100}
+
+	// 5xx and 429 responses are retried
+	var calls int
+	c := service.NewRandomUserClient(http.Client{Transport: statusResponses(&calls,
+		respondStatus(http.StatusServiceUnavailable, ""),
+		respondStatus(http.StatusTooManyRequests, "0"),
+		respondBody(`{"results": [{"email": "john@mail.com"}]}`),
+	)}, cfg)
+	r, err := service.FetchRandomUsers(ctx, c, opts)
+	require.NoError(t, err)
+	require.Len(t, r.Results, 1)
+	require.Equal(t, 3, calls)
+
+	// the last error is returned once the attempts are exhausted
+	calls = 0
+	c = service.NewRandomUserClient(http.Client{Transport: statusResponses(&calls,
+		respondStatus(http.StatusTooManyRequests, ""),
+	)}, cfg)
+	_, err = service.FetchRandomUsers(ctx, c, opts)
+	require.ErrorIs(t, err, service.ErrRandomUserRateLimited)
+	require.Equal(t, 3, calls)
+
+	// client errors and bad payloads are not retried
+	calls = 0
+	c = service.NewRandomUserClient(http.Client{Transport: statusResponses(&calls,
+		respondStatus(http.StatusBadRequest, ""),
+	)}, cfg)
+	_, err = service.FetchRandomUsers(ctx, c, opts)
+	require.ErrorIs(t, err, service.ErrRandomUserAPI)
+	require.Equal(t, 1, calls)
+
+	calls = 0
+	c = service.NewRandomUserClient(http.Client{Transport: statusResponses(&calls,
+		respondBody(`<html>oops</html>`),
+	)}, cfg)
+	_, err = service.FetchRandomUsers(ctx, c, opts)
+	require.ErrorIs(t, err, service.ErrRandomUserBadPayload)
+	require.Equal(t, 1, calls)
+}
+
+// blockingTransport never answers, the requests only end with their context.
+type blockingTransport struct{}
+
+func (blockingTransport) RoundTrip(r *http.Request) (*http.Response, error) {
+	<-r.Context().Done()
+	return nil, r.Context().Err()
+}
+
+func TestRandomUserClientTimeout(t *testing.T) {
+	ctx := context.Background()
+	c := service.NewRandomUserClient(http.Client{Transport: blockingTransport{}}, service.RandomUserClientConfig{
+		MaxAttempts:      2,
+		AttemptTimeout:   10 * time.Millisecond,
+		BaseBackoff:      time.Millisecond,
+		FailureThreshold: 100,
+	})
+
+	// every attempt has its own timeout
+	_, err := service.FetchRandomUsers(ctx, c, entities.PopulateOptions{Count: 1})
+	require.ErrorIs(t, err, service.ErrRandomUserTimeout)
+
+	// a canceled caller is not retried and is not reported as a timeout
+	canceled, cancel := context.WithCancel(ctx)
+	cancel()
+	_, err = service.FetchRandomUsers(canceled, c, entities.PopulateOptions{Count: 1})
+	require.ErrorIs(t, err, context.Canceled)
+	require.NotErrorIs(t, err, service.ErrRandomUserTimeout)
+}
+
+func TestRandomUserClientCircuitBreaker(t *testing.T) {
+	ctx := context.Background()
+	opts := entities.PopulateOptions{Count: 1}
+	var calls int
+	c := service.NewRandomUserClient(http.Client{Transport: statusResponses(&calls,
+		respondStatus(http.StatusInternalServerError, ""),
+		respondStatus(http.StatusInternalServerError, ""),
+		respondStatus(http.StatusInternalServerError, ""),
+		respondBody(`{"results": [{"email": "john@mail.com"}]}`),
+	)}, service.RandomUserClientConfig{
+		MaxAttempts:      1,
+		FailureThreshold: 2,
+		OpenTimeout:      20 * time.Millisecond,
+	})
+
+	// the circuit opens after the threshold and fails fast
+	for range 2 {
+		_, err := service.FetchRandomUsers(ctx, c, opts)
+		require.ErrorIs(t, err, service.ErrRandomUserAPI)
+		require.NotErrorIs(t, err, service.ErrRandomUserCircuitOpen)
+	}
+	require.ErrorIs(t, c.Available(), service.ErrRandomUserCircuitOpen)
+	_, err := service.FetchRandomUsers(ctx, c, opts)
+	require.ErrorIs(t, err, service.ErrRandomUserCircuitOpen)
+	require.Equal(t, 2, calls)
+
+	// a failed probe opens the circuit again
+	time.Sleep(30 * time.Millisecond)
+	require.NoError(t, c.Available())
+	_, err = service.FetchRandomUsers(ctx, c, opts)
+	require.NotErrorIs(t, err, service.ErrRandomUserCircuitOpen)
+	require.Equal(t, 3, calls)
+	require.ErrorIs(t, c.Available(), service.ErrRandomUserCircuitOpen)
+
+	// a successful probe closes it
+	time.Sleep(30 * time.Millisecond)
+	_, err = service.FetchRandomUsers(ctx, c, opts)
+	require.NoError(t, err)
+	require.NoError(t, c.Available())
+}
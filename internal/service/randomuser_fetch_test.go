@@ -0,0 +1,80 @@
+package service_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+
+	"wonderful/internal/entities"
+	"wonderful/internal/service"
+
+	"github.com/stretchr/testify/require"
+)
+
+// roundTripFunc serves the RandomUser API requests without network access.
+type roundTripFunc func(r *http.Request) *http.Response
+
+func (f roundTripFunc) RoundTrip(r *http.Request) (*http.Response, error) {
+	return f(r), nil
+}
+
+func TestFetchRandomUsersPaging(t *testing.T) {
+	ctx := context.Background()
+	var queries []string
+	c := service.NewRandomUserClient(http.Client{Transport: roundTripFunc(func(r *http.Request) *http.Response {
+		queries = append(queries, r.URL.RawQuery)
+		results, _ := strconv.Atoi(r.URL.Query().Get("results"))
+		out := service.RandomUser{Results: make([]service.Results, results)}
+		out.Info.Seed = "upstream"
+		if seed := r.URL.Query().Get("seed"); seed != "" {
+			out.Info.Seed = seed
+		}
+		w := httptest.NewRecorder()
+		json.NewEncoder(w).Encode(out) //nolint:errcheck //ignore error
+		return w.Result()
+	})}, service.RandomUserClientConfig{})
+
+	// a single request keeps the old behaviour
+	r, err := service.FetchRandomUsers(ctx, c, entities.PopulateOptions{})
+	require.NoError(t, err)
+	require.Len(t, r.Results, 5000)
+	require.Equal(t, []string{"results=5000"}, queries)
+
+	// counts above the upstream cap are paged with the seed of the first page
+	queries = nil
+	r, err = service.FetchRandomUsers(ctx, c, entities.PopulateOptions{Count: 12000, Nat: []string{"us", "gb"}, Gender: "female"})
+	require.NoError(t, err)
+	require.Len(t, r.Results, 12000)
+	require.Equal(t, []string{
+		"gender=female&nat=US%2CGB&results=5000",
+		"gender=female&nat=US%2CGB&page=2&results=5000&seed=upstream",
+		"gender=female&nat=US%2CGB&page=3&results=5000&seed=upstream",
+	}, queries)
+
+	// a given seed is used from the first page
+	queries = nil
+	_, err = service.FetchRandomUsers(ctx, c, entities.PopulateOptions{Count: 10, Seed: "abc"})
+	require.NoError(t, err)
+	require.Equal(t, []string{"page=1&results=10&seed=abc"}, queries)
+
+	// invalid options are rejected before calling the API
+	queries = nil
+	for field, opts := range map[string]entities.PopulateOptions{
+		"count":  {Count: -1},
+		"gender": {Gender: "other"},
+		"nat":    {Nat: []string{"XX"}},
+	} {
+		_, err = service.FetchRandomUsers(ctx, c, opts)
+		require.ErrorIs(t, err, service.ErrInvalidPopulateOptions, "options %+v", opts)
+		// the invalid option is named
+		var fieldErr *service.FieldError
+		require.ErrorAs(t, err, &fieldErr)
+		require.Equal(t, field, fieldErr.Field)
+	}
+	_, err = service.FetchRandomUsers(ctx, c, entities.PopulateOptions{Count: 50001})
+	require.ErrorIs(t, err, service.ErrInvalidPopulateOptions)
+	require.Empty(t, queries)
+}
@@ -4,6 +4,7 @@
 package service_test
 
 import (
+	"wonderful/internal/entities"
 	"wonderful/internal/service"
 	"context"
 	"fmt"
@@ -12,8 +13,8 @@ import (
 
 func ExampleRandomUser() {
 	ctx := context.Background()
-	c := http.Client{}
-	r, err := service.FetchRandomUsers(ctx, c)
+	c := service.NewRandomUserClient(http.Client{}, service.RandomUserClientConfig{})
+	r, err := service.FetchRandomUsers(ctx, c, entities.PopulateOptions{})
 	if err != nil {
 		fmt.Println(err)
 		return
@@ -0,0 +1,234 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"io"
+	"math/rand/v2"
+	"os"
+	"slices"
+	"strings"
+	"time"
+
+	"wonderful/internal/entities"
+)
+
+// The names of the user sources a populate job can select.
+const (
+	SourceRandomUser = "randomuser"
+	SourceFile       = "file"
+	SourceSynthetic  = "synthetic"
+)
+
+// UserSource provides the users added by a populate job.
+// The options are already validated and have their defaults filled in.
+type UserSource interface {
+	FetchUsers(ctx context.Context, opts entities.PopulateOptions) ([]entities.User, error)
+}
+
+// availabilityChecker is implemented by the user sources that can tell they
+// are unavailable before a job is queued.
+type availabilityChecker interface {
+	Available() error
+}
+
+// randomUserSource fetches the users from the RandomUser API.
+type randomUserSource struct {
+	client *RandomUserClient
+}
+
+// NewRandomUserSource returns a UserSource backed by the RandomUser API.
+func NewRandomUserSource(c *RandomUserClient) UserSource {
+	return &randomUserSource{client: c}
+}
+
+// Available fails fast while the RandomUser API circuit is open.
+func (s *randomUserSource) Available() error {
+	return s.client.Available()
+}
+
+func (s *randomUserSource) FetchUsers(ctx context.Context, opts entities.PopulateOptions) ([]entities.User, error) {
+	rUsers, err := FetchRandomUsers(ctx, s.client, opts)
+	if err != nil {
+		return nil, err
+	}
+	return randomUsersToEntities(rUsers.Results), nil
+}
+
+// fileSource reads the users from a local fixture in the RandomUser API format.
+type fileSource struct {
+	path string
+}
+
+// NewFileSource returns a UserSource that reads the users from a local file.
+// The file holds either a RandomUser API response, a JSON array of its
+// results or one result per line (NDJSON). The seed is ignored, the first
+// users matching the nationalities and gender are returned.
+func NewFileSource(path string) UserSource {
+	return &fileSource{path: path}
+}
+
+func (s *fileSource) FetchUsers(ctx context.Context, opts entities.PopulateOptions) ([]entities.User, error) {
+	f, err := os.Open(s.path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open user file: %w", err)
+	}
+	defer f.Close()
+
+	results, err := decodeResults(f)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read user file %s: %w", s.path, err)
+	}
+	matched := make([]Results, 0, min(len(results), opts.Count))
+	for i := range results {
+		if len(matched) == opts.Count {
+			break
+		}
+		if matchesOptions(&results[i], opts) {
+			matched = append(matched, results[i])
+		}
+	}
+	return randomUsersToEntities(matched), ctx.Err()
+}
+
+// decodeResults decodes a RandomUser API response, an array of results or NDJSON results.
+func decodeResults(r io.Reader) ([]Results, error) {
+	dec := json.NewDecoder(r)
+	var results []Results
+	for {
+		var raw json.RawMessage
+		if err := dec.Decode(&raw); err != nil {
+			if errors.Is(err, io.EOF) {
+				return results, nil
+			}
+			return nil, fmt.Errorf("failed to decode users: %w", err)
+		}
+		raw = bytes.TrimSpace(raw)
+		if len(raw) > 0 && raw[0] == '[' {
+			var page []Results
+			if err := json.Unmarshal(raw, &page); err != nil {
+				return nil, fmt.Errorf("failed to decode users: %w", err)
+			}
+			results = append(results, page...)
+			continue
+		}
+		var fields map[string]json.RawMessage
+		if err := json.Unmarshal(raw, &fields); err != nil {
+			return nil, fmt.Errorf("failed to decode users: %w", err)
+		}
+		if _, ok := fields["results"]; ok {
+			var resp RandomUser
+			if err := json.Unmarshal(raw, &resp); err != nil {
+				return nil, fmt.Errorf("failed to decode users: %w", err)
+			}
+			results = append(results, resp.Results...)
+			continue
+		}
+		var u Results
+		if err := json.Unmarshal(raw, &u); err != nil {
+			return nil, fmt.Errorf("failed to decode users: %w", err)
+		}
+		results = append(results, u)
+	}
+}
+
+// matchesOptions reports whether a user matches the nationalities and gender of the options.
+func matchesOptions(u *Results, opts entities.PopulateOptions) bool {
+	if opts.Gender != "" && !strings.EqualFold(u.Gender, opts.Gender) {
+		return false
+	}
+	if len(opts.Nat) == 0 {
+		return true
+	}
+	for _, n := range opts.Nat {
+		if strings.EqualFold(u.Nat, n) {
+			return true
+		}
+	}
+	return false
+}
+
+// syntheticSource generates users without network access. The same seed and
+// options always generate the same users.
+type syntheticSource struct{}
+
+// NewSyntheticSource returns a UserSource that generates deterministic users.
+func NewSyntheticSource() UserSource {
+	return &syntheticSource{}
+}
+
+var (
+	syntheticFirstNames = map[string][]string{
+		"male":   {"James", "Liam", "Noah", "Oliver", "Lucas", "Hugo", "Mateo", "Elias", "Arjun", "Leon"},
+		"female": {"Olivia", "Emma", "Amelia", "Sofia", "Mia", "Chloe", "Lucia", "Hanna", "Priya", "Ella"},
+	}
+	syntheticTitles    = map[string][]string{"male": {"Mr"}, "female": {"Mrs", "Ms", "Miss"}}
+	syntheticPictures  = map[string]string{"male": "men", "female": "women"}
+	syntheticLastNames = []string{
+		"Smith", "Jones", "Martin", "Silva", "Meyer", "Garcia", "Dubois", "Nielsen", "Kumar", "Novak",
+		"Wilson", "Rossi", "Yilmaz", "Jansen", "Berg", "Taylor", "Moreau", "Santos", "Fischer", "Walker",
+	}
+	// syntheticEpoch is the earliest registration date of a synthetic user.
+	syntheticEpoch = time.Date(2010, time.January, 1, 0, 0, 0, 0, time.UTC)
+)
+
+func (s *syntheticSource) FetchUsers(ctx context.Context, opts entities.PopulateOptions) ([]entities.User, error) {
+	h := fnv.New64a()
+	h.Write([]byte(opts.Seed))               //nolint:errcheck //hash writes never fail
+	r := rand.New(rand.NewPCG(h.Sum64(), 0)) //nolint:gosec //not used for security
+
+	nats := opts.Nat
+	if len(nats) == 0 {
+		nats = make([]string, 0, len(randomUserNats))
+		for n := range randomUserNats {
+			nats = append(nats, n)
+		}
+		// map order is random, sort to keep the output deterministic.
+		slices.Sort(nats)
+	}
+	results := make([]Results, 0, opts.Count)
+	for i := range opts.Count {
+		gender := opts.Gender
+		if gender == "" {
+			gender = [...]string{"male", "female"}[r.IntN(2)]
+		}
+		first := syntheticFirstNames[gender][r.IntN(len(syntheticFirstNames[gender]))]
+		last := syntheticLastNames[r.IntN(len(syntheticLastNames))]
+		portrait := fmt.Sprintf("%s/%d.jpg", syntheticPictures[gender], r.IntN(100))
+		results = append(results, Results{
+			Gender: gender,
+			Name: Name{
+				Title: syntheticTitles[gender][r.IntN(len(syntheticTitles[gender]))],
+				First: first,
+				Last:  last,
+			},
+			Email: fmt.Sprintf("%s.%s.%d@example.com", strings.ToLower(first), strings.ToLower(last), i),
+			Login: Login{UUID: syntheticUUID(r)},
+			Registered: Registered{
+				Date: syntheticEpoch.Add(time.Duration(r.Int64N(int64(14 * 365 * 24 * time.Hour)))).Truncate(time.Second),
+			},
+			Phone: fmt.Sprintf("%03d-%03d-%04d", r.IntN(1000), r.IntN(1000), r.IntN(10000)),
+			Cell:  fmt.Sprintf("%03d-%03d-%04d", r.IntN(1000), r.IntN(1000), r.IntN(10000)),
+			Picture: Picture{
+				Large:     "https://randomuser.me/api/portraits/" + portrait,
+				Medium:    "https://randomuser.me/api/portraits/med/" + portrait,
+				Thumbnail: "https://randomuser.me/api/portraits/thumb/" + portrait,
+			},
+			Nat: nats[r.IntN(len(nats))],
+		})
+	}
+	return randomUsersToEntities(results), ctx.Err()
+}
+
+// syntheticUUID returns a random version 4 UUID drawn from r, so that the same
+// seed always generates the same IDs and populating it again updates the users.
+func syntheticUUID(r *rand.Rand) string {
+	hi, lo := r.Uint64(), r.Uint64()
+	hi = hi&^0xf000 | 0x4000     // version 4
+	lo = lo&^(0xc<<60) | 0x8<<60 // RFC 4122 variant
+	return fmt.Sprintf("%08x-%04x-%04x-%04x-%012x", hi>>32, hi>>16&0xffff, hi&0xffff, lo>>48, lo&0xffffffffffff)
+}
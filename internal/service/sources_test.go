@@ -0,0 +1,133 @@
+package service_test
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"wonderful/internal/entities"
+	"wonderful/internal/service"
+
+	"github.com/stretchr/testify/require"
+)
+
+const (
+	johnFixture = `{"gender": "male", "name": {"title": "Mr", "first": "John", "last": "Doe"}, "email": "john@mail.com", "nat": "US"}`
+	janeFixture = `{"gender": "female", "name": {"title": "Mrs", "first": "Jane", "last": "Doe"}, "email": "jane@mail.com", "nat": "GB"}`
+)
+
+func TestFileSource(t *testing.T) {
+	ctx := context.Background()
+	dir := t.TempDir()
+	opts, err := service.NormalizePopulateOptions(entities.PopulateOptions{})
+	require.NoError(t, err)
+
+	// the three supported layouts return the same users
+	for name, content := range map[string]string{
+		"response.json": `{"results": [` + johnFixture + `, ` + janeFixture + `], "info": {"seed": "abc"}}`,
+		"array.json":    `[` + johnFixture + `, ` + janeFixture + `]`,
+		"users.ndjson":  johnFixture + "\n" + janeFixture + "\n",
+	} {
+		path := filepath.Join(dir, name)
+		require.NoError(t, os.WriteFile(path, []byte(content), 0o600))
+		users, err := service.NewFileSource(path).FetchUsers(ctx, opts)
+		require.NoError(t, err, name)
+		require.Len(t, users, 2, name)
+		require.Equal(t, "Mr John Doe", users[0].Name, name)
+		require.Equal(t, "jane@mail.com", users[1].Email, name)
+	}
+
+	// nationality, gender and count filters
+	path := filepath.Join(dir, "users.ndjson")
+	users, err := service.NewFileSource(path).FetchUsers(ctx, entities.PopulateOptions{Count: 10, Nat: []string{"GB"}})
+	require.NoError(t, err)
+	require.Len(t, users, 1)
+	require.Equal(t, "jane@mail.com", users[0].Email)
+	users, err = service.NewFileSource(path).FetchUsers(ctx, entities.PopulateOptions{Count: 10, Gender: "male"})
+	require.NoError(t, err)
+	require.Len(t, users, 1)
+	require.Equal(t, "john@mail.com", users[0].Email)
+	users, err = service.NewFileSource(path).FetchUsers(ctx, entities.PopulateOptions{Count: 1})
+	require.NoError(t, err)
+	require.Len(t, users, 1)
+
+	// missing and malformed files
+	_, err = service.NewFileSource(filepath.Join(dir, "missing.json")).FetchUsers(ctx, opts)
+	require.Error(t, err)
+	require.NoError(t, os.WriteFile(path, []byte(`{"results": [`), 0o600))
+	_, err = service.NewFileSource(path).FetchUsers(ctx, opts)
+	require.Error(t, err)
+}
+
+func TestFileSourceProfile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "users.ndjson")
+	// the API returns the postcode as a number for some nationalities
+	content := `{"gender": "female", "name": {"title": "Ms", "first": "Ana", "last": "Silva"},
+		"location": {"street": {"number": 42, "name": "Rua Augusta"}, "city": "Lisboa", "state": "Lisboa",
+			"country": "Portugal", "postcode": 1100048, "coordinates": {"latitude": "38.7107", "longitude": "-9.1379"}},
+		"email": "ana@mail.com", "dob": {"date": "1984-03-02T10:00:00.000Z", "age": 40},
+		"id": {"name": "NIF", "value": "123456789"}, "nat": "ES"}
+` + johnFixture + "\n"
+	require.NoError(t, os.WriteFile(path, []byte(content), 0o600))
+	opts, err := service.NormalizePopulateOptions(entities.PopulateOptions{})
+	require.NoError(t, err)
+
+	users, err := service.NewFileSource(path).FetchUsers(context.Background(), opts)
+	require.NoError(t, err)
+	require.Len(t, users, 2)
+	ana := users[0]
+	require.Equal(t, "female", ana.Gender)
+	require.Equal(t, []string{"Ms", "Ana", "Silva"}, []string{ana.Title, ana.FirstName, ana.LastName})
+	require.Equal(t, "ES", ana.Nat)
+	require.Equal(t, time.Date(1984, time.March, 2, 10, 0, 0, 0, time.UTC), *ana.DateOfBirth)
+	require.Equal(t, &entities.NationalID{Name: "NIF", Value: "123456789"}, ana.NationalID)
+	require.Equal(t, &entities.Location{
+		StreetNumber: 42,
+		StreetName:   "Rua Augusta",
+		City:         "Lisboa",
+		State:        "Lisboa",
+		Country:      "Portugal",
+		Postcode:     "1100048",
+		Latitude:     38.7107,
+		Longitude:    -9.1379,
+	}, ana.Location)
+
+	// the parts of the profile missing upstream are left empty
+	john := users[1]
+	require.Equal(t, "Doe", john.LastName)
+	require.Nil(t, john.DateOfBirth)
+	require.Nil(t, john.NationalID)
+	require.Nil(t, john.Location)
+}
+
+func TestSyntheticSource(t *testing.T) {
+	ctx := context.Background()
+	source := service.NewSyntheticSource()
+
+	opts := entities.PopulateOptions{Count: 100, Seed: "wonderful"}
+	users, err := source.FetchUsers(ctx, opts)
+	require.NoError(t, err)
+	require.Len(t, users, 100)
+
+	// the same seed generates the same users
+	again, err := source.FetchUsers(ctx, opts)
+	require.NoError(t, err)
+	require.Equal(t, users, again)
+
+	// another seed generates other users
+	opts.Seed = "other"
+	other, err := source.FetchUsers(ctx, opts)
+	require.NoError(t, err)
+	require.NotEqual(t, users, other)
+
+	// the gender selects the titles
+	users, err = source.FetchUsers(ctx, entities.PopulateOptions{Count: 10, Gender: "male"})
+	require.NoError(t, err)
+	for _, u := range users {
+		require.Regexp(t, `^Mr `, u.Name)
+		require.NotEmpty(t, u.Email)
+		require.False(t, u.Registration.IsZero())
+	}
+}
@@ -2,76 +2,390 @@ package service
 
 import (
 	"context"
+	"errors"
 	"fmt"
-	"net/http"
+	"time"
 
 	"wonderful/internal/entities"
 	"wonderful/internal/repository"
 	"wonderful/internal/store"
+
+	"github.com/segmentio/ksuid"
 )
 
 // userService is an implementation of the UserService interface.
 type userService struct {
-	repo   repository.UserRepository
-	client http.Client
+	store   store.Store
+	repo    repository.UserRepository
+	cursors cursorCodec
 }
 
-// NewUserService creates a new UserService.
-func NewUserService(s store.Store, c http.Client) *userService {
+// NewUserService creates a new UserService. The pagination cursors are signed
+// with cursorSecret, the cursors of another secret are rejected.
+func NewUserService(s store.Store, cursorSecret []byte) *userService {
 	return &userService{
-		repo:   s.Users(),
-		client: c,
-	}
-}
-
-func (s *userService) Create(ctx context.Context) error {
-	rUsers, err := FetchRandomUsers(ctx, s.client)
-	if err != nil {
-		return fmt.Errorf("service failed to get random users: %w", err)
-	}
-	// populate a repository with random users.
-	repoUsers := make([]repository.User, 0, len(rUsers.Results))
-	for i := range rUsers.Results {
-		u := rUsers.Results[i] // to avoid creating a new variable in each iteration.
-		repoUsers = append(repoUsers, repository.User{
-			Name:  u.Name.Title + " " + u.Name.First + " " + u.Name.Last,
-			Email: u.Email,
-			Phone: u.Phone,
-			Cell:  u.Cell,
-			Picture: map[string]string{
-				"large":     u.Picture.Large,
-				"medium":    u.Picture.Medium,
-				"thumbnail": u.Picture.Thumbnail,
-			},
-			Registration: u.Registered.Date,
-		})
+		store:   s,
+		repo:    s.Users(),
+		cursors: cursorCodec{secret: cursorSecret},
 	}
-	// insert random users into the repository.
-	if err := s.repo.Create(ctx, repoUsers); err != nil {
-		return fmt.Errorf("service failed to insert random users: %w", err)
+}
+
+// createUsers inserts or updates the users in a single transaction, so that
+// importing the same users again updates them instead of duplicating them.
+func createUsers(ctx context.Context, st store.Store, users []entities.User) (entities.CreateResult, error) {
+	repoUsers := make([]repository.User, 0, len(users))
+	for i := range users {
+		repoUsers = append(repoUsers, fromEntity(&users[i]))
 	}
-	return nil
+	var res repository.CreateResult
+	err := st.ExecTx(ctx, func(tx store.Store) error {
+		var err error
+		res, err = tx.Users().Create(ctx, repoUsers)
+		return err //nolint:wrapcheck //wrapped by the caller
+	})
+	if err != nil {
+		return entities.CreateResult{}, err //nolint:wrapcheck //wrapped by the caller
+	}
+	return entities.CreateResult(res), nil
 }
 
-func (s *userService) ListUsers(ctx context.Context, p repository.Params) ([]entities.User, error) {
+// ListUsers returns a page of users. One more user than the limit is read
+// to tell whether another page follows.
+func (s *userService) ListUsers(ctx context.Context, lp ListParams) (*entities.UserPage, error) {
+	p, err := s.cursors.repositoryParams(&lp)
+	if err != nil {
+		return nil, fmt.Errorf("service failed to list users: %w", err)
+	}
+	limit := p.Limit
+	if limit == 0 {
+		limit = defaultListLimit
+	}
+	p.Limit = limit + 1
 	// fetch users from the repository.
 	repoUsers, err := s.repo.ListUsers(ctx, p)
 	if err != nil {
 		return nil, fmt.Errorf("service failed to list users: %w", err)
 	}
+	backward := p.EndingBefore != nil
+	page := &entities.UserPage{HasMore: len(repoUsers) > limit}
+	if page.HasMore {
+		// the extra user is the farthest one from the cursor.
+		if backward {
+			repoUsers = repoUsers[1:]
+		} else {
+			repoUsers = repoUsers[:limit]
+		}
+	}
 	// convert repository users to entities users.
-	entitiesUsers := make([]entities.User, 0, len(repoUsers))
+	page.Users = make([]entities.User, 0, len(repoUsers))
 	for i := range repoUsers {
-		u := repoUsers[i] // to avoid creating a new variable in each iteration.
-		entitiesUsers = append(entitiesUsers, entities.User{
-			ID:           u.ID.String(),
-			Name:         u.Name,
-			Email:        u.Email,
-			Phone:        u.Phone,
-			Cell:         u.Cell,
-			Picture:      u.Picture,
-			Registration: u.Registration,
+		page.Users = append(page.Users, toEntity(&repoUsers[i]))
+	}
+	if n := len(repoUsers); n > 0 {
+		first, last := &repoUsers[0], &repoUsers[n-1]
+		if page.HasMore || backward {
+			page.NextCursor = s.cursors.encode(newCursor(last, cursorNext, &lp))
+		}
+		if (page.HasMore && backward) || p.StartingAfter != nil {
+			page.PrevCursor = s.cursors.encode(newCursor(first, cursorPrev, &lp))
+		}
+	}
+	if lp.IncludeTotal {
+		total, err := s.repo.CountUsers(ctx, p)
+		if err != nil {
+			return nil, fmt.Errorf("service failed to count users: %w", err)
+		}
+		page.TotalCount = &total
+	}
+	return page, nil
+}
+
+// SearchUsers returns a page of the users matching a full-text search, the
+// most relevant first. One more user than the limit is read to tell whether
+// another page follows.
+func (s *userService) SearchUsers(ctx context.Context, sp SearchParams) (*entities.SearchPage, error) {
+	p := repository.SearchParams{
+		Query:          sp.Query,
+		Limit:          sp.Limit,
+		IncludeDeleted: sp.IncludeDeleted,
+	}
+	if sp.StartingAfter != "" {
+		cur, err := s.cursors.decode(sp.StartingAfter)
+		if err != nil {
+			return nil, fmt.Errorf("service failed to search users: invalid startingAfter: %w", err)
+		}
+		if p.After, err = cur.searchSeekKey(&sp); err != nil {
+			return nil, fmt.Errorf("service failed to search users: invalid startingAfter: %w", err)
+		}
+	}
+	limit := p.Limit
+	if limit == 0 {
+		limit = defaultListLimit
+	}
+	p.Limit = limit + 1
+	results, err := s.repo.SearchUsers(ctx, p)
+	if err != nil {
+		return nil, fmt.Errorf("service failed to search users: %w", err)
+	}
+	page := &entities.SearchPage{HasMore: len(results) > limit}
+	if page.HasMore {
+		results = results[:limit]
+		page.NextCursor = s.cursors.encode(newSearchCursor(&results[limit-1].User, &sp))
+	}
+	page.Results = make([]entities.SearchResult, 0, len(results))
+	for i := range results {
+		r := &results[i]
+		page.Results = append(page.Results, entities.SearchResult{
+			User:             toEntity(&r.User),
+			Rank:             r.Rank,
+			NameHeadline:     r.NameHeadline,
+			EmailHeadline:    r.EmailHeadline,
+			LocationHeadline: r.LocationHeadline,
+		})
+	}
+	return page, nil
+}
+
+// ExportUsers calls fn with every user matching the filters, by registration
+// descending, as they are read. An error of fn stops the export.
+func (s *userService) ExportUsers(ctx context.Context, f ListFilters, fn func(*entities.User) error) error {
+	err := s.store.ExecTx(ctx, func(tx store.Store) error {
+		return tx.Users().ExportUsers(ctx, repository.Filters(f), func(u *repository.User) error { //nolint:wrapcheck //already wrapped by the repository
+			user := toEntity(u)
+			return fn(&user)
 		})
+	})
+	if err != nil {
+		return fmt.Errorf("service failed to export users: %w", err)
+	}
+	return nil
+}
+
+func (s *userService) GetUser(ctx context.Context, id string) (*entities.User, error) {
+	userID, err := parseUserID(id)
+	if err != nil {
+		return nil, fmt.Errorf("service failed to get user: %w", err)
+	}
+	repoUser, err := s.repo.GetUserByID(ctx, userID)
+	if err != nil {
+		return nil, fmt.Errorf("service failed to get user: %w", notFound(err))
+	}
+	u := toEntity(repoUser)
+	return &u, nil
+}
+
+func (s *userService) CreateUser(ctx context.Context, u entities.User) (*entities.User, error) {
+	if u.Registration.IsZero() {
+		u.Registration = time.Now().UTC()
+	}
+	repoUser, err := s.repo.CreateUser(ctx, fromEntity(&u))
+	if err != nil {
+		return nil, fmt.Errorf("service failed to create user: %w", err)
+	}
+	created := toEntity(repoUser)
+	return &created, nil
+}
+
+func (s *userService) ReplaceUser(ctx context.Context, id string, u entities.User, ifMatch Precondition) (*entities.User, error) {
+	return s.updateUser(ctx, id, ifMatch, func(current *repository.User) {
+		replacement := fromEntity(&u)
+		if u.Registration.IsZero() {
+			// the registration date is kept unless it is explicitly replaced.
+			replacement.Registration = current.Registration
+		}
+		*current = replacement
+	})
+}
+
+func (s *userService) PatchUser(ctx context.Context, id string, patch entities.UserPatch, ifMatch Precondition) (*entities.User, error) {
+	return s.updateUser(ctx, id, ifMatch, func(current *repository.User) {
+		if patch.Name != nil {
+			current.Name = *patch.Name
+		}
+		if patch.Email != nil {
+			current.Email = *patch.Email
+		}
+		if patch.Phone != nil {
+			current.Phone = *patch.Phone
+		}
+		if patch.Cell != nil {
+			current.Cell = *patch.Cell
+		}
+		if len(patch.Picture) > 0 && current.Picture == nil {
+			current.Picture = make(map[string]string, len(patch.Picture))
+		}
+		for k, v := range patch.Picture {
+			current.Picture[k] = v
+		}
+		if patch.Registration != nil {
+			current.Registration = *patch.Registration
+		}
+	})
+}
+
+// Precondition checks the current version of a user before it is changed.
+// The change fails with ErrPreconditionFailed when it does not hold. A nil
+// Precondition always holds.
+type Precondition func(current *entities.User) bool
+
+// updateUser reads the user, applies the given changes and writes it back in a
+// single transaction. The user is locked while it is read, so that concurrent
+// updates are applied one after the other and ifMatch sees the latest version.
+func (s *userService) updateUser(ctx context.Context, id string, ifMatch Precondition, apply func(*repository.User)) (*entities.User, error) {
+	userID, err := parseUserID(id)
+	if err != nil {
+		return nil, fmt.Errorf("service failed to update user: %w", err)
+	}
+	var updated *repository.User
+	err = s.store.ExecTx(ctx, func(st store.Store) error {
+		repo := st.Users()
+		current, err := lockUser(ctx, repo, userID, ifMatch)
+		if err != nil {
+			return err
+		}
+		apply(current)
+		current.ID = userID
+		updated, err = repo.UpdateUser(ctx, *current)
+		return err //nolint:wrapcheck //wrapped by the caller
+	})
+	if err != nil {
+		return nil, fmt.Errorf("service failed to update user: %w", notFound(err))
+	}
+	u := toEntity(updated)
+	return &u, nil
+}
+
+func (s *userService) DeleteUser(ctx context.Context, id string, ifMatch Precondition) error {
+	userID, err := parseUserID(id)
+	if err != nil {
+		return fmt.Errorf("service failed to delete user: %w", err)
+	}
+	err = s.store.ExecTx(ctx, func(st store.Store) error {
+		repo := st.Users()
+		if _, err := lockUser(ctx, repo, userID, ifMatch); err != nil {
+			return err
+		}
+		return repo.DeleteUser(ctx, userID) //nolint:wrapcheck //wrapped by the caller
+	})
+	if err != nil {
+		return fmt.Errorf("service failed to delete user: %w", notFound(err))
+	}
+	return nil
+}
+
+// lockUser reads a user that is not soft deleted and locks it until the end
+// of the transaction, then checks ifMatch against it.
+func lockUser(ctx context.Context, repo repository.UserRepository, id ksuid.KSUID, ifMatch Precondition) (*repository.User, error) {
+	current, err := repo.GetUserForUpdate(ctx, id)
+	if err != nil {
+		return nil, err //nolint:wrapcheck //wrapped by the caller
+	}
+	if current.DeletedAt != nil {
+		return nil, fmt.Errorf("user %s is deleted: %w", id, repository.ErrNotFound)
+	}
+	if ifMatch != nil {
+		if u := toEntity(current); !ifMatch(&u) {
+			return nil, fmt.Errorf("user %s: %w", id, ErrPreconditionFailed)
+		}
+	}
+	return current, nil
+}
+
+func (s *userService) RestoreUser(ctx context.Context, id string) (*entities.User, error) {
+	userID, err := parseUserID(id)
+	if err != nil {
+		return nil, fmt.Errorf("service failed to restore user: %w", err)
+	}
+	var restored *repository.User
+	err = s.store.ExecTx(ctx, func(st store.Store) error {
+		repo := st.Users()
+		// locked, so that it is not deleted or restored in between.
+		current, err := repo.GetUserForUpdate(ctx, userID)
+		if err != nil {
+			return err //nolint:wrapcheck //wrapped by the caller
+		}
+		if current.DeletedAt == nil {
+			return fmt.Errorf("user %s: %w", userID, ErrUserNotDeleted)
+		}
+		restored, err = repo.RestoreUser(ctx, userID)
+		return err //nolint:wrapcheck //wrapped by the caller
+	})
+	if err != nil {
+		return nil, fmt.Errorf("service failed to restore user: %w", notFound(err))
+	}
+	u := toEntity(restored)
+	return &u, nil
+}
+
+func (s *userService) PurgeUsers(ctx context.Context, deletedBefore time.Time) (int64, error) {
+	n, err := s.repo.PurgeUsers(ctx, deletedBefore)
+	if err != nil {
+		return 0, fmt.Errorf("service failed to purge users: %w", err)
+	}
+	return n, nil
+}
+
+// parseUserID parses a user ID. A malformed ID can never match a user, so it
+// is reported as not found.
+func parseUserID(id string) (ksuid.KSUID, error) {
+	userID, err := ksuid.Parse(id)
+	if err != nil {
+		return ksuid.Nil, errors.Join(ErrUserNotFound, fmt.Errorf("invalid user id %q: %w", id, err))
+	}
+	return userID, nil
+}
+
+// notFound translates a repository.ErrNotFound into ErrUserNotFound.
+func notFound(err error) error {
+	if errors.Is(err, repository.ErrNotFound) {
+		return errors.Join(ErrUserNotFound, err)
+	}
+	return err
+}
+
+// toEntity converts a repository user to an entities user.
+func toEntity(u *repository.User) entities.User {
+	return entities.User{
+		ID:           u.ID.String(),
+		ExternalID:   u.ExternalID,
+		Name:         u.Name,
+		Email:        u.Email,
+		Phone:        u.Phone,
+		Cell:         u.Cell,
+		Picture:      u.Picture,
+		Registration: u.Registration,
+		CreatedAt:    u.CreatedAt,
+		UpdatedAt:    u.UpdatedAt,
+		DeletedAt:    u.DeletedAt,
+		Gender:       u.Gender,
+		Title:        u.Title,
+		FirstName:    u.FirstName,
+		LastName:     u.LastName,
+		DateOfBirth:  u.DateOfBirth,
+		Nat:          u.Nat,
+		NationalID:   (*entities.NationalID)(u.NationalID),
+		Location:     (*entities.Location)(u.Location),
+	}
+}
+
+// fromEntity converts an entities user to a repository user. The ID is left
+// for the caller to set.
+func fromEntity(u *entities.User) repository.User {
+	return repository.User{
+		ExternalID:   u.ExternalID,
+		Name:         u.Name,
+		Email:        u.Email,
+		Phone:        u.Phone,
+		Cell:         u.Cell,
+		Picture:      u.Picture,
+		Registration: u.Registration,
+		Gender:       u.Gender,
+		Title:        u.Title,
+		FirstName:    u.FirstName,
+		LastName:     u.LastName,
+		DateOfBirth:  u.DateOfBirth,
+		Nat:          u.Nat,
+		NationalID:   (*repository.NationalID)(u.NationalID),
+		Location:     (*repository.Location)(u.Location),
 	}
-	return entitiesUsers, nil
 }
@@ -2,16 +2,17 @@ package service_test
 
 import (
 	"context"
-	"net/http"
+	"errors"
+	"fmt"
+	"io"
 	"testing"
 
-	"wonderful/internal/repository"
+	"wonderful/internal/entities"
 	"wonderful/internal/repository/db"
 	"wonderful/internal/repository/db/test"
 	"wonderful/internal/service"
 	"wonderful/internal/store"
 
-	"github.com/segmentio/ksuid"
 	"github.com/stretchr/testify/require"
 	"github.com/stretchr/testify/suite"
 	testcontainers "github.com/testcontainers/testcontainers-go/modules/postgres"
@@ -54,15 +55,57 @@ func (ts *UsersTestSuite) TearDownSuite() {
 	ts.s.Close()
 }
 
-func (ts *UsersTestSuite) TestBulkLoad() {
+func (ts *UsersTestSuite) TestImportUsers() {
 	s := store.NewPersistentStore(ts.s.Pool())
-	c := http.Client{}
-	su := service.NewUserService(s, c)
+	su := service.NewUserService(s, []byte("test"))
 	ctx := context.Background()
 
-	// get all users empty DB
-	err := su.Create(ctx)
+	// 2,500 users, every 100th one invalid, created in 3 chunks
+	records := make([]service.ImportRecord, 0, 2500)
+	for i := range 2500 {
+		u := entities.User{
+			ExternalID: fmt.Sprintf("import-%d", i),
+			Name:       fmt.Sprintf("User %d", i),
+			Email:      fmt.Sprintf("user%d@mail.com", i),
+		}
+		if i%100 == 0 {
+			u.Email = "not an email"
+		}
+		records = append(records, service.ImportRecord{Line: i + 1, User: u})
+	}
+	records = append(records, service.ImportRecord{Line: 2501, Err: &entities.ImportError{Line: 2501, Reason: "unreadable"}})
+	next := func() func() (*service.ImportRecord, error) {
+		i := 0
+		return func() (*service.ImportRecord, error) {
+			if i == len(records) {
+				return nil, io.EOF
+			}
+			i++
+			return &records[i-1], nil
+		}
+	}
+	report, err := su.ImportUsers(ctx, next())
 	ts.Require().NoError(err)
+	ts.Require().Equal(2475, report.Inserted)
+	ts.Require().Equal(26, report.Failed)
+	ts.Require().Len(report.Errors, 26)
+	ts.Require().Equal(entities.ImportError{Line: 1, Field: "email", Reason: "is not a valid email address"}, report.Errors[0])
+	ts.Require().Equal(2501, report.Errors[25].Line)
+
+	// importing the same users again changes nothing
+	for i := range records[:2500] {
+		records[i].Err = nil
+	}
+	report, err = su.ImportUsers(ctx, next())
+	ts.Require().NoError(err)
+	ts.Require().Zero(report.Inserted)
+	ts.Require().Equal(2475, report.Skipped)
+
+	// a read error stops the import, the chunks before it are kept
+	errRead := errors.New("read error")
+	report, err = su.ImportUsers(ctx, func() (*service.ImportRecord, error) { return nil, errRead })
+	ts.Require().ErrorIs(err, errRead)
+	ts.Require().Zero(report.Inserted)
 
 	_, err = ts.s.Pool().Exec(ctx, deleteStatement)
 	require.NoError(ts.T(), err)
@@ -75,34 +118,123 @@ func (ts *UsersTestSuite) TestListUsers() {
 	require.NoError(ts.T(), err)
 
 	s := store.NewPersistentStore(ts.s.Pool())
-	c := http.Client{}
-	su := service.NewUserService(s, c)
+	su := service.NewUserService(s, []byte("test"))
 
 	// get all users with limit
-	users, err := su.ListUsers(ctx, repository.Params{Limit: 2})
+	page, err := su.ListUsers(ctx, service.ListParams{Limit: 2})
 	require.NoError(ts.T(), err)
-	require.Len(ts.T(), users, 2)
+	require.Len(ts.T(), page.Users, 2)
+	require.True(ts.T(), page.HasMore)
+	require.NotEmpty(ts.T(), page.NextCursor)
+	require.Empty(ts.T(), page.PrevCursor)
+	require.Nil(ts.T(), page.TotalCount)
 
 	// get all users
-	users, err = su.ListUsers(ctx, repository.Params{})
+	page, err = su.ListUsers(ctx, service.ListParams{IncludeTotal: true})
 	require.NoError(ts.T(), err)
+	users := page.Users
 	require.Len(ts.T(), users, 4)
+	require.False(ts.T(), page.HasMore)
+	require.Empty(ts.T(), page.NextCursor)
+	require.Equal(ts.T(), int64(4), *page.TotalCount)
 	// Make sure the users are sorted by registration date
 	require.Equal(ts.T(), "Mr. John Smith", users[0].Name)
 	require.Equal(ts.T(), "Mrs. Jane Doe2", users[1].Name)
 	require.Equal(ts.T(), "Mrs. Jane Doe1", users[2].Name)
 	require.Equal(ts.T(), "Mr. John Doe", users[3].Name)
-	janeDoe1ID, err := ksuid.Parse(users[2].ID)
+	// get the users up to Jane Doe1
+	page, err = su.ListUsers(ctx, service.ListParams{Limit: 3})
 	require.NoError(ts.T(), err)
+	require.Len(ts.T(), page.Users, 3)
+	require.Equal(ts.T(), "Mrs. Jane Doe1", page.Users[2].Name)
+	require.True(ts.T(), page.HasMore)
 	// get all users starting after Jane Doe1
-	users, err = su.ListUsers(ctx, repository.Params{StartingAfter: &janeDoe1ID})
+	page, err = su.ListUsers(ctx, service.ListParams{StartingAfter: page.NextCursor})
 	require.NoError(ts.T(), err)
-	require.Len(ts.T(), users, 1)
-	require.Equal(ts.T(), "Mr. John Doe", users[0].Name)
-	// get all users ending before Jane
-	users, err = su.ListUsers(ctx, repository.Params{EndingBefore: &janeDoe1ID})
+	require.Len(ts.T(), page.Users, 1)
+	require.Equal(ts.T(), "Mr. John Doe", page.Users[0].Name)
+	require.False(ts.T(), page.HasMore)
+	require.Empty(ts.T(), page.NextCursor)
+	require.NotEmpty(ts.T(), page.PrevCursor)
+	johnDoePrev := page.PrevCursor
+	// get all users ending before John Doe
+	page, err = su.ListUsers(ctx, service.ListParams{EndingBefore: johnDoePrev})
 	require.NoError(ts.T(), err)
-	require.Len(ts.T(), users, 2)
-	require.Equal(ts.T(), "Mr. John Smith", users[0].Name)
-	require.Equal(ts.T(), "Mrs. Jane Doe2", users[1].Name)
+	require.Len(ts.T(), page.Users, 3)
+	require.Equal(ts.T(), "Mr. John Smith", page.Users[0].Name)
+	require.Equal(ts.T(), "Mrs. Jane Doe1", page.Users[2].Name)
+	require.False(ts.T(), page.HasMore)
+	require.Empty(ts.T(), page.PrevCursor)
+	require.NotEmpty(ts.T(), page.NextCursor)
+	// the users closest to the cursor are returned before it
+	page, err = su.ListUsers(ctx, service.ListParams{Limit: 1, EndingBefore: johnDoePrev})
+	require.NoError(ts.T(), err)
+	require.Len(ts.T(), page.Users, 1)
+	require.Equal(ts.T(), "Mrs. Jane Doe1", page.Users[0].Name)
+	require.True(ts.T(), page.HasMore)
+	require.NotEmpty(ts.T(), page.PrevCursor)
+	// a cursor is only valid in its own direction and listing
+	_, err = su.ListUsers(ctx, service.ListParams{StartingAfter: johnDoePrev})
+	require.ErrorIs(ts.T(), err, service.ErrInvalidCursor)
+	email := "jane"
+	_, err = su.ListUsers(ctx, service.ListParams{ListFilters: service.ListFilters{Email: &email}, EndingBefore: johnDoePrev})
+	require.ErrorIs(ts.T(), err, service.ErrInvalidCursor)
+	// tampered cursors are rejected
+	tampered := []byte(johnDoePrev)
+	tampered[len(tampered)/2] ^= 1
+	_, err = su.ListUsers(ctx, service.ListParams{EndingBefore: string(tampered)})
+	require.ErrorIs(ts.T(), err, service.ErrInvalidCursor)
+	_, err = su.ListUsers(ctx, service.ListParams{StartingAfter: "not-a-cursor"})
+	require.ErrorIs(ts.T(), err, service.ErrInvalidCursor)
+}
+
+func (ts *UsersTestSuite) TestPreconditions() {
+	s := store.NewPersistentStore(ts.s.Pool())
+	su := service.NewUserService(s, []byte("test"))
+	ctx := context.Background()
+
+	created, err := su.CreateUser(ctx, entities.User{Name: "Mr. John Doe", Email: "jd@mail.com"})
+	ts.Require().NoError(err)
+	unchanged := func(current *entities.User) bool {
+		return current.UpdatedAt == nil
+	}
+
+	// the first of two concurrent updates of the same version wins, the other
+	// one sees the version it made and fails
+	results := make(chan error, 2)
+	for _, name := range []string{"Mr. John Smith", "Mr. John Brown"} {
+		go func(name string) {
+			_, patchErr := su.PatchUser(ctx, created.ID, entities.UserPatch{Name: &name}, unchanged)
+			results <- patchErr
+		}(name)
+	}
+	var failed []error
+	for range 2 {
+		if patchErr := <-results; patchErr != nil {
+			failed = append(failed, patchErr)
+		}
+	}
+	ts.Require().Len(failed, 1)
+	ts.Require().ErrorIs(failed[0], service.ErrPreconditionFailed)
+
+	// the failed preconditions leave the user unchanged
+	patched, err := su.GetUser(ctx, created.ID)
+	ts.Require().NoError(err)
+	_, err = su.ReplaceUser(ctx, created.ID, entities.User{Name: "Nobody", Email: "nobody@mail.com"}, unchanged)
+	ts.Require().ErrorIs(err, service.ErrPreconditionFailed)
+	err = su.DeleteUser(ctx, created.ID, unchanged)
+	ts.Require().ErrorIs(err, service.ErrPreconditionFailed)
+	current, err := su.GetUser(ctx, created.ID)
+	ts.Require().NoError(err)
+	ts.Require().Equal(patched, current)
+
+	// a nil precondition always holds, and the deleted users are not found
+	ts.Require().NoError(su.DeleteUser(ctx, created.ID, nil))
+	_, err = su.PatchUser(ctx, created.ID, entities.UserPatch{}, nil)
+	ts.Require().ErrorIs(err, service.ErrUserNotFound)
+	err = su.DeleteUser(ctx, created.ID, nil)
+	ts.Require().ErrorIs(err, service.ErrUserNotFound)
+
+	_, err = ts.s.Pool().Exec(ctx, deleteStatement)
+	ts.Require().NoError(err)
 }
@@ -9,5 +9,7 @@ import (
 // Store is the interface that wraps the repositories.
 type Store interface {
 	Users() repository.UserRepository
+	Jobs() repository.JobRepository
+	Idempotency() repository.IdempotencyRepository
 	ExecTx(ctx context.Context, fn func(Store) error) error
 }
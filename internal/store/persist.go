@@ -29,6 +29,16 @@ func (s *persistentStore) Users() repository.UserRepository {
 	return db.NewUserStorage(s.conn)
 }
 
+// Jobs returns a JobRepository for managing background jobs.
+func (s *persistentStore) Jobs() repository.JobRepository {
+	return db.NewJobStorage(s.conn)
+}
+
+// Idempotency returns an IdempotencyRepository for managing request idempotency keys.
+func (s *persistentStore) Idempotency() repository.IdempotencyRepository {
+	return db.NewIdempotencyStorage(s.conn)
+}
+
 // ExecTx executes the given function within a database transaction.
 // See the test file for an example of how to use this function.
 func (s *persistentStore) ExecTx(ctx context.Context, fn func(Store) error) error {
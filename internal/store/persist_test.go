@@ -54,7 +54,7 @@ func (ts *StoreTestSuite) TestStoreOK() {
 	require.NoError(ts.T(), err)
 	require.Len(ts.T(), users, 0)
 
-	err = usersStore.Create(ctx, []repository.User{
+	_, err = usersStore.Create(ctx, []repository.User{
 		{
 			Name:         "Mr. John Doe",
 			Email:        "john@test.com",
@@ -80,7 +80,7 @@ func (ts *StoreTestSuite) TestStoreExecTxOK() {
 
 	err := s.ExecTx(ctx, func(st store.Store) error {
 		usersStore := st.Users()
-		err := usersStore.Create(ctx, []repository.User{
+		_, err := usersStore.Create(ctx, []repository.User{
 			{
 				Name:         "Mr. John Doe",
 				Email:        "john@test.com",
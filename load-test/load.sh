@@ -12,6 +12,9 @@ end' | \
     tee results.bin | \
     vegeta report
 
+# hits and misses of the listing cache, see the -cache-ttl and -debug-addr flags
+curl -s http://localhost:6060/debug/vars | jq -c .user_list_cache
+
 vegeta report -type=json results.bin > metrics.json
 cat results.bin | vegeta plot > plot.html
 cat results.bin | vegeta report -type="hist[0,1ms,2ms,3ms,4ms,5ms,6ms,7ms,8ms,9ms,10ms]" > hist.txt
@@ -1,8 +1,8 @@
 #!/bin/bash
 
 # The script is a simple bash script that uses  curl  to make requests to the API. 
-# It starts by getting the last 100 records from the API and then uses the last record's ID to get the next 100 records. 
-# It continues this process until there are no more records to fetch. 
+# It starts by getting the first 100 records from the API and then follows the page's next_cursor to get the next 100 records. 
+# It continues this process until the API reports there are no more records (has_more is false). 
 #
 # The script also measures the time it takes to complete the test. 
 # Running the test 
@@ -12,20 +12,19 @@
 
 start_time=$(date +%s%3N)
 
-total_length=100
-
-last_id=$(curl -s 'localhost:8888/api/v1/wonderfuls?limit=100'  | jq -r '. | last | .id')
+total_length=0
+cursor=""
 
 while : ; do
-    contents=$(curl -s "localhost:8888/api/v1/wonderfuls?limit=100&starting_after=$last_id")
-    length=$(jq -r '. | length' <<< "$contents")
+    contents=$(curl -s "localhost:8888/api/v1/wonderfuls?limit=100${cursor:+&starting_after=$cursor}")
+    length=$(jq -r '.data | length' <<< "$contents")
 
     total_length=$((total_length + length))
-    # echo "Fetched $length records of a total of $total_length records ($last_id)"
-    if [ "$length" -eq 0 ]; then
+    # echo "Fetched $length records of a total of $total_length records ($cursor)"
+    if [ "$(jq -r '.has_more' <<< "$contents")" != "true" ]; then
         break
     fi
-    last_id=$(jq -r '. | last | .id' <<< "$contents")
+    cursor=$(jq -r '.next_cursor' <<< "$contents")
 done
 
 
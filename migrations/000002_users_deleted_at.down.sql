@@ -0,0 +1,3 @@
+DROP INDEX index_users_on_deleted_at;
+
+ALTER TABLE users DROP COLUMN deleted_at;
@@ -0,0 +1,3 @@
+DROP INDEX index_jobs_on_state;
+
+DROP TABLE jobs;
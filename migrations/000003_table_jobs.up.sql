@@ -0,0 +1,12 @@
+CREATE TABLE jobs (
+    id VARCHAR(27) PRIMARY KEY,
+    state VARCHAR(15) NOT NULL,
+    fetched INTEGER NOT NULL DEFAULT 0,
+    inserted INTEGER NOT NULL DEFAULT 0,
+    error_message TEXT,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP NOT NULL,
+    updated_at TIMESTAMP,
+    finished_at TIMESTAMP
+);
+
+CREATE INDEX index_jobs_on_state ON jobs(state);
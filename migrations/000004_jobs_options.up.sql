@@ -0,0 +1 @@
+ALTER TABLE jobs ADD COLUMN options JSONB DEFAULT '{}' NOT NULL;
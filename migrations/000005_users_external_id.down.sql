@@ -0,0 +1,8 @@
+ALTER TABLE jobs DROP COLUMN skipped;
+ALTER TABLE jobs DROP COLUMN updated;
+
+DROP INDEX index_users_staging_on_batch_id;
+DROP TABLE users_staging;
+
+DROP INDEX index_users_on_external_id;
+ALTER TABLE users DROP COLUMN external_id;
@@ -0,0 +1,22 @@
+ALTER TABLE users ADD COLUMN external_id VARCHAR(64);
+
+CREATE UNIQUE INDEX index_users_on_external_id ON users(external_id);
+
+-- Bulk imports are copied here first and then merged into users.
+-- Every import uses its own batch_id so concurrent imports do not mix.
+CREATE UNLOGGED TABLE users_staging (
+    batch_id VARCHAR(27) NOT NULL,
+    id VARCHAR(27) NOT NULL,
+    external_id VARCHAR(64),
+    name VARCHAR(255) NOT NULL,
+    email VARCHAR(255) NOT NULL,
+    phone VARCHAR(31) NOT NULL,
+    cell VARCHAR(31),
+    picture JSONB NOT NULL,
+    registration TIMESTAMP NOT NULL
+);
+
+CREATE INDEX index_users_staging_on_batch_id ON users_staging(batch_id);
+
+ALTER TABLE jobs ADD COLUMN updated INTEGER DEFAULT 0 NOT NULL;
+ALTER TABLE jobs ADD COLUMN skipped INTEGER DEFAULT 0 NOT NULL;
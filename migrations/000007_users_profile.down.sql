@@ -0,0 +1,17 @@
+ALTER TABLE users_staging DROP COLUMN location;
+ALTER TABLE users_staging DROP COLUMN national_id;
+ALTER TABLE users_staging DROP COLUMN nat;
+ALTER TABLE users_staging DROP COLUMN date_of_birth;
+ALTER TABLE users_staging DROP COLUMN last_name;
+ALTER TABLE users_staging DROP COLUMN first_name;
+ALTER TABLE users_staging DROP COLUMN title;
+ALTER TABLE users_staging DROP COLUMN gender;
+
+ALTER TABLE users DROP COLUMN location;
+ALTER TABLE users DROP COLUMN national_id;
+ALTER TABLE users DROP COLUMN nat;
+ALTER TABLE users DROP COLUMN date_of_birth;
+ALTER TABLE users DROP COLUMN last_name;
+ALTER TABLE users DROP COLUMN first_name;
+ALTER TABLE users DROP COLUMN title;
+ALTER TABLE users DROP COLUMN gender;
@@ -0,0 +1,18 @@
+-- The profile of the populated users. Users created through the API have none.
+ALTER TABLE users ADD COLUMN gender VARCHAR(15);
+ALTER TABLE users ADD COLUMN title VARCHAR(31);
+ALTER TABLE users ADD COLUMN first_name VARCHAR(255);
+ALTER TABLE users ADD COLUMN last_name VARCHAR(255);
+ALTER TABLE users ADD COLUMN date_of_birth TIMESTAMP;
+ALTER TABLE users ADD COLUMN nat VARCHAR(2);
+ALTER TABLE users ADD COLUMN national_id JSONB;
+ALTER TABLE users ADD COLUMN location JSONB;
+
+ALTER TABLE users_staging ADD COLUMN gender VARCHAR(15);
+ALTER TABLE users_staging ADD COLUMN title VARCHAR(31);
+ALTER TABLE users_staging ADD COLUMN first_name VARCHAR(255);
+ALTER TABLE users_staging ADD COLUMN last_name VARCHAR(255);
+ALTER TABLE users_staging ADD COLUMN date_of_birth TIMESTAMP;
+ALTER TABLE users_staging ADD COLUMN nat VARCHAR(2);
+ALTER TABLE users_staging ADD COLUMN national_id JSONB;
+ALTER TABLE users_staging ADD COLUMN location JSONB;
@@ -0,0 +1,3 @@
+CREATE INDEX index_users_on_registration ON users(registration);
+
+DROP INDEX index_users_on_registration_id;
@@ -0,0 +1,4 @@
+-- The listings seek on the (registration, id) tuple of the cursor.
+CREATE INDEX index_users_on_registration_id ON users(registration, id);
+
+DROP INDEX index_users_on_registration;
@@ -0,0 +1,3 @@
+DROP INDEX index_users_on_created_at_id;
+DROP INDEX index_users_on_email_id;
+DROP INDEX index_users_on_name_id;
@@ -0,0 +1,4 @@
+-- The listings sorted by a single field seek on the (field, id) tuple of the cursor.
+CREATE INDEX index_users_on_name_id ON users(name, id);
+CREATE INDEX index_users_on_email_id ON users(email, id);
+CREATE INDEX index_users_on_created_at_id ON users(created_at, id);
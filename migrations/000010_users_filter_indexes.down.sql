@@ -0,0 +1,2 @@
+DROP INDEX index_users_on_cell;
+DROP INDEX index_users_on_phone;
@@ -0,0 +1,3 @@
+-- The phone filters match exactly.
+CREATE INDEX index_users_on_phone ON users(phone);
+CREATE INDEX index_users_on_cell ON users(cell);
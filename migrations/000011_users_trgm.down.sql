@@ -0,0 +1,4 @@
+DROP INDEX index_users_on_name_trgm;
+DROP INDEX index_users_on_email_trgm;
+
+DROP EXTENSION IF EXISTS pg_trgm;
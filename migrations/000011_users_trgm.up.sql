@@ -0,0 +1,6 @@
+-- Trigram indexes back the case-insensitive substring filters (ILIKE '%...%')
+-- and the similarity search on the name and email.
+CREATE EXTENSION IF NOT EXISTS pg_trgm;
+
+CREATE INDEX index_users_on_email_trgm ON users USING GIN (email gin_trgm_ops);
+CREATE INDEX index_users_on_name_trgm ON users USING GIN (name gin_trgm_ops);
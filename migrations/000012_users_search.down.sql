@@ -0,0 +1,5 @@
+DROP INDEX index_users_on_search;
+
+ALTER TABLE users DROP COLUMN search;
+
+DROP FUNCTION users_location_text;
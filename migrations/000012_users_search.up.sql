@@ -0,0 +1,16 @@
+-- users_location_text is the searchable text of a user location, empty when
+-- there is none. concat_ws skips the missing fields.
+CREATE FUNCTION users_location_text(location JSONB) RETURNS TEXT
+    LANGUAGE SQL IMMUTABLE PARALLEL SAFE
+    RETURN concat_ws(' ', location->>'street_number', location->>'street_name', location->>'city',
+        location->>'state', location->>'country', location->>'postcode');
+
+-- The full-text search document of a user. The 'simple' configuration does
+-- not stem, as names and addresses are not words of a single language.
+ALTER TABLE users ADD COLUMN search TSVECTOR GENERATED ALWAYS AS (
+    setweight(to_tsvector('simple', name), 'A') ||
+    setweight(to_tsvector('simple', email), 'B') ||
+    setweight(to_tsvector('simple', users_location_text(location)), 'C')
+) STORED;
+
+CREATE INDEX index_users_on_search ON users USING GIN (search);